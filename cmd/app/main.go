@@ -1,17 +1,46 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"expvar"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
 	"os"
+	"os/signal"
 	_ "safelyyou/docs"
+	grpcadapter "safelyyou/internal/adapters/grpc"
 	"safelyyou/internal/adapters/http"
+	"safelyyou/internal/adapters/mqtt"
+	"safelyyou/internal/adapters/repository/file"
 	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/adapters/telemetry"
+	"safelyyou/internal/adapters/udp"
+	"safelyyou/internal/adapters/webhook"
+	"safelyyou/internal/config"
+	"safelyyou/internal/core/domain"
+	"safelyyou/internal/core/ports"
 	"safelyyou/internal/core/services"
+	"safelyyou/pkg/logging"
+	"safelyyou/pkg/ratelimit"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
+// webhookQueuePersistDelay is how long webhook queue changes may wait to
+// be written together.
+const webhookQueuePersistDelay = 200 * time.Millisecond
+
 // Package main Fleet Management Simple Metrics Server.
 //
 // @title Fleet Management Simple Metrics Server
@@ -21,31 +50,267 @@ import (
 // @BasePath /api/v1
 func main() {
 
-	err := godotenv.Load(".env")
+	// A .env file is optional; its values feed the environment layer.
+	envErr := godotenv.Load(".env")
+
+	cfg, opts, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fatal("invalid configuration", "error", err)
+	}
+	if opts.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fatal("failed to print configuration", "error", err)
+		}
+		return
+	}
+
+	level, componentLevels, _ := logging.ParseLevels(cfg.Logging.Level)
+	logging.Setup(logging.Config{
+		Level:      level,
+		Components: componentLevels,
+		Format:     cfg.Logging.Format,
+	})
+	logger := logging.For("main")
+	if envErr != nil && !errors.Is(envErr, fs.ErrNotExist) {
+		logger.Warn("could not load .env file", "error", envErr)
+	}
+	if opts.File != "" {
+		logger.Info("configuration file loaded", "path", opts.File)
+	}
+	domain.SeriesRetention = cfg.Retention.Series
+
+	// ctx is cancelled on SIGINT/SIGTERM and stops ingestion; background
+	// workers get their own context so they outlive request draining.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	runWorker := func(run func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
+	}
+
+	shutdownTracing, err := telemetry.Setup(context.Background(), telemetry.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		ServiceName: http.TracingServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
 	if err != nil {
-		log.Println("Error loading .env file")
+		fatal("failed to set up tracing", "error", err)
 	}
-	csvPath := os.Getenv("DEVICE_CSV")
 
 	deviceRepo := memory.NewDeviceRepository()
 
+	csvPath := cfg.Storage.DeviceCSV
 	if err := deviceRepo.LoadFromCSV(csvPath); err != nil {
-		log.Fatalf("failed to load devices from %s: %v", csvPath, err)
+		fatal("failed to load devices", "path", csvPath, "error", err)
+	}
+	// Validated by config.Load, like the tenant tokens and quotas below.
+	tenantCSVs, _ := cfg.Tenants.DeviceCSVPaths()
+	for tenant, path := range tenantCSVs {
+		if err := deviceRepo.LoadTenantCSV(tenant, path); err != nil {
+			fatal("failed to load tenant devices", "tenant", tenant, "path", path, "error", err)
+		}
+	}
+	if snapshotPath := cfg.Storage.SnapshotPath; snapshotPath != "" {
+		if err := deviceRepo.UseSnapshotFile(snapshotPath); err != nil {
+			fatal("failed to restore device snapshot", "path", snapshotPath, "error", err)
+		}
 	}
 
-	log.Printf("devices loaded from %s: %d", csvPath, deviceRepo.Count())
+	logger.Info("devices loaded", "path", csvPath, "count", deviceRepo.Count(), "tenants", len(deviceRepo.Tenants()))
+
+	webhookRepo := memory.NewWebhookRepository()
+	// Queue writes are batched so that publishing an event from the
+	// ingestion path never waits for the file.
+	deliveryQueue, err := file.NewDeliveryQueue(cfg.Storage.WebhookQueuePath,
+		file.WithHistory(cfg.Webhooks.HistoryLimit, cfg.Webhooks.HistoryTTL),
+		file.WithPersistDelay(webhookQueuePersistDelay))
+	if err != nil {
+		fatal("failed to open webhook queue", "error", err)
+	}
+	webhookSvc := services.NewWebhookService(webhookRepo, deliveryQueue)
+	for _, url := range cfg.Webhooks.URLs {
+		if _, err := webhookSvc.Register(url, cfg.Webhooks.Secret, nil); err != nil {
+			fatal("invalid webhook", "url", url, "error", err)
+		}
+		logger.Info("webhook registered from config", "url", url)
+	}
+
+	quotas, _ := cfg.Tenants.DeviceQuotas()
+	deviceSvc := services.NewDeviceService(deviceRepo,
+		services.WithEventPublisher(webhookSvc),
+		services.WithUploadThreshold(cfg.Alerts.UploadThreshold),
+		services.WithTenantQuotas(quotas),
+		services.WithTimestampPolicy(domain.TimestampPolicy(cfg.Clock.TimestampPolicy), cfg.Clock.SkewThreshold),
+	)
+
+	dispatcher := services.NewWebhookDispatcher(webhookRepo, deliveryQueue,
+		webhook.NewHTTPSender(nil), services.DefaultDispatcherConfig())
+	runWorker(func(ctx context.Context) { dispatcher.Run(ctx, time.Second) })
+
+	if offlineAfter := cfg.Alerts.OfflineAfter; offlineAfter > 0 {
+		monitor := services.NewOfflineMonitor(deviceRepo, webhookSvc, offlineAfter)
+		runWorker(func(ctx context.Context) { monitor.Run(ctx, offlineAfter/4) })
+	}
 
-	deviceSvc := services.NewDeviceService(deviceRepo)
+	alertSvc := services.NewAlertService(deviceSvc, memory.NewAlertRepository(), webhookSvc)
+	runWorker(func(ctx context.Context) { alertSvc.Run(ctx, cfg.Alerts.EvalInterval) })
 
-	r := gin.Default()
+	heartbeatLimit, _ := ratelimit.ParseRule(cfg.RateLimit.Heartbeat)
+	statsLimit, _ := ratelimit.ParseRule(cfg.RateLimit.Stats)
+	deviceOverride := func(ctx context.Context, id string) (ratelimit.Limit, bool) {
+		rl, ok := deviceRepo.RateLimit(ports.TenantFrom(ctx), id)
+		if !ok || !rl.IsSet() {
+			return ratelimit.Limit{}, false
+		}
+		return ratelimit.Limit{Rate: rl.PerSecond, Burst: rl.Burst}, true
+	}
+	limiter := http.NewRateLimiter(http.RateLimitConfig{
+		Routes: map[string]ratelimit.Rule{
+			http.HeartbeatRoute: heartbeatLimit,
+			http.StatsRoute:     statsLimit,
+		},
+		DeviceOverride: deviceOverride,
+	})
+	expvar.Publish("rate_limit", expvar.Func(func() any { return limiter.Counters() }))
+
+	// gRPC, MQTT and UDP ingestion get the same per-device limits; they
+	// have no client IP to limit on.
+	limitedDeviceSvc := services.NewRateLimitedDeviceService(deviceSvc, services.IngestLimits{
+		Heartbeat:      heartbeatLimit.Device,
+		Stats:          statsLimit.Device,
+		DeviceOverride: deviceOverride,
+	})
+	expvar.Publish("rate_limit_adapters", expvar.Func(func() any { return limitedDeviceSvc.Counters() }))
+
+	tenantTokens, _ := cfg.Tenants.TokenTenants()
+	r := gin.New()
+	r.Use(
+		http.RequestID(),
+		http.AccessLog(http.AccessLogConfig{HeartbeatSampleEvery: cfg.Logging.HeartbeatSample}),
+		http.Recovery(),
+		http.BearerAuth(cfg.Auth.Token, tenantTokens),
+		http.RequestSignature(cfg.Auth.SigningSecret),
+		limiter.Handler(),
+	)
 	http.RegisterRoutes(r, deviceSvc)
+	http.RegisterWebhookRoutes(r, webhookSvc)
+	http.RegisterAlertRoutes(r, alertSvc)
+	http.RegisterExportRoutes(r, services.NewExportService(deviceRepo))
+	http.RegisterImportRoutes(r, services.NewImportService(deviceSvc))
+	http.RegisterGroupRoutes(r, services.NewGroupService(deviceRepo, memory.NewGroupRepository()))
+	r.GET("/debug/vars", gin.WrapH(expvar.Handler()))
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Server.GRPCPort))
+	if err != nil {
+		fatal("could not listen on gRPC port", "port", cfg.Server.GRPCPort, "error", err)
+	}
+	unaryAuth, streamAuth := grpcadapter.BearerAuth(cfg.Auth.Token, tenantTokens)
+	grpcOpts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(unaryAuth),
+		grpc.ChainStreamInterceptor(streamAuth),
+	}
+	if cfg.TLS.Enabled() {
+		creds, err := credentials.NewServerTLSFromFile(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			fatal("could not load TLS certificate", "error", err)
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(creds))
 	}
-	log.Printf("Starting server on port %s...", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatalf("Could not start server: %v", err)
+	grpcServer := grpc.NewServer(grpcOpts...)
+	grpcadapter.NewServer(limitedDeviceSvc).Register(grpcServer)
+	go func() {
+		logger.Info("starting gRPC server", "port", cfg.Server.GRPCPort, "tls", cfg.TLS.Enabled())
+		if err := grpcServer.Serve(lis); err != nil {
+			fatal("could not start gRPC server", "error", err)
+		}
+	}()
+
+	var sub *mqtt.Subscriber
+	if brokerURL := cfg.MQTT.BrokerURL; brokerURL != "" {
+		sub = mqtt.NewSubscriber(mqtt.Config{
+			BrokerURL:   brokerURL,
+			ClientID:    cfg.MQTT.ClientID,
+			Username:    cfg.MQTT.Username,
+			Password:    cfg.MQTT.Password,
+			TopicPrefix: cfg.MQTT.TopicPrefix,
+		}, limitedDeviceSvc)
+		if err := sub.Start(); err != nil {
+			fatal("could not connect to MQTT broker", "broker", brokerURL, "error", err)
+		}
+		logger.Info("subscribed to MQTT broker", "broker", brokerURL)
+	}
+
+	if udpAddr := cfg.UDP.Addr; udpAddr != "" {
+		listener := udp.NewListener(limitedDeviceSvc, cfg.UDP.HMACSecret, udp.WithReplayWindow(cfg.UDP.ReplayWindow))
+		expvar.Publish("udp_heartbeats", expvar.Func(func() any { return listener.Counters() }))
+		go func() {
+			logger.Info("listening for UDP heartbeats", "addr", udpAddr)
+			if err := listener.ListenAndServe(ctx, udpAddr); err != nil {
+				fatal("could not start UDP listener", "error", err)
+			}
+		}()
+	}
+
+	drainTimeout := cfg.Timeouts.Shutdown
+	server := http.NewServer(fmt.Sprintf(":%d", cfg.Server.Port), r, http.ServerConfig{
+		DrainTimeout:      drainTimeout,
+		ReadHeaderTimeout: cfg.Timeouts.ReadHeader,
+		CertFile:          cfg.TLS.CertFile,
+		KeyFile:           cfg.TLS.KeyFile,
+	})
+	logger.Info("starting HTTP server", "port", cfg.Server.Port, "tls", cfg.TLS.Enabled())
+	if err := server.ListenAndServe(ctx); err != nil {
+		logger.Error("HTTP server stopped with error", "error", err)
 	}
+
+	// Shutdown order: ingestion is already stopped and drained, so stop the
+	// remaining adapters, then the workers, and persist state last.
+	logger.Info("shutting down")
+	stopGRPC(grpcServer, drainTimeout)
+	if sub != nil {
+		sub.Stop(250 * time.Millisecond)
+	}
+	stopWorkers()
+	workers.Wait()
+	if err := deliveryQueue.Flush(); err != nil {
+		logger.Error("failed to flush webhook queue", "error", err)
+	}
+	if err := deviceRepo.Flush(); err != nil {
+		logger.Error("failed to flush device state", "error", err)
+	}
+	if err := shutdownTracing(context.Background()); err != nil {
+		logger.Error("failed to flush traces", "error", err)
+	}
+	logger.Info("shutdown complete")
+}
+
+// stopGRPC drains in-flight RPCs, forcing the server closed after timeout.
+func stopGRPC(s *grpc.Server, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		s.Stop()
+	}
+}
+
+// fatal logs msg at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is where fleetctl finds the server. Values come from the config
+// file, then the environment, then flags, each overriding the previous.
+type Config struct {
+	Server        string `yaml:"server"`
+	Token         string `yaml:"token"`
+	SigningSecret string `yaml:"signing_secret"`
+}
+
+// defaultConfigPath is $FLEETCTL_CONFIG, or fleetctl/config.yaml under the
+// user config directory.
+func defaultConfigPath() string {
+	if p := os.Getenv("FLEETCTL_CONFIG"); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "fleetctl", "config.yaml")
+}
+
+// loadConfig reads path, applies FLEET_SERVER, API_TOKEN and
+// API_SIGNING_SECRET and fills in the default server. A missing file is
+// not an error.
+func loadConfig(path string) (Config, error) {
+	cfg := Config{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return cfg, err
+		default:
+			if err := yaml.Unmarshal(data, &cfg); err != nil {
+				return cfg, fmt.Errorf("decode config %s: %w", path, err)
+			}
+		}
+	}
+	if v := os.Getenv("FLEET_SERVER"); v != "" {
+		cfg.Server = v
+	}
+	if v := os.Getenv("API_TOKEN"); v != "" {
+		cfg.Token = v
+	}
+	if v := os.Getenv("API_SIGNING_SECRET"); v != "" {
+		cfg.SigningSecret = v
+	}
+	if cfg.Server == "" {
+		cfg.Server = "http://localhost:8080"
+	}
+	return cfg, nil
+}
@@ -0,0 +1,282 @@
+// Command fleetctl manages a fleet server from the command line.
+//
+//	fleetctl devices list
+//	fleetctl devices add 60-6b-44-84-dc-64 --site north
+//	fleetctl devices remove 60-6b-44-84-dc-64
+//	fleetctl stats 60-6b-44-84-dc-64 -o yaml
+//	fleetctl outages 60-6b-44-84-dc-64 --since 24h
+//	fleetctl export --dataset series --format parquet --out series.parquet
+//
+// The server URL and token are read from ~/.config/fleetctl/config.yaml
+// (or $FLEETCTL_CONFIG, or -config):
+//
+//	server: https://fleet.example.com
+//	token: s3cret
+//	signing_secret: hmac-s3cret   # only for servers with API_SIGNING_SECRET
+//
+// FLEET_SERVER, API_TOKEN and API_SIGNING_SECRET override the file, and
+// -server and -token override both.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"safelyyou/pkg/client"
+)
+
+const usage = `usage: fleetctl <command> [flags]
+
+commands:
+  devices list                 list devices
+  devices add <id> [-site s]   add a device
+  devices remove <id>          remove a device and its history
+  stats <id>                   show a device's stats
+  outages <id> [-since 24h]    list a device's outages
+  export [-dataset stats|series] [-format csv|parquet] [-out file]
+                               download a bulk export
+
+common flags:
+  -config path   config file (default $FLEETCTL_CONFIG or ~/.config/fleetctl/config.yaml)
+  -server url    server base URL
+  -token token   bearer token
+  -o format      output: table, json or yaml (default table)
+  -timeout d     request timeout (default 30s)
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// command is one fleetctl command: it declares its own flags on fs and
+// runs with its positional arguments.
+type command struct {
+	args  string
+	nargs int
+	flags func(fs *flag.FlagSet) func(ctx context.Context, env *env, args []string) error
+}
+
+// env is what every command runs with.
+type env struct {
+	client *client.Client
+	out    *printer
+	stdout io.Writer
+}
+
+var commands = map[string]command{
+	"devices list": {nargs: 0, flags: func(*flag.FlagSet) func(context.Context, *env, []string) error {
+		return func(ctx context.Context, e *env, _ []string) error {
+			devices, err := e.client.ListDevices(ctx)
+			if err != nil {
+				return err
+			}
+			return e.out.devices(devices)
+		}
+	}},
+	"devices add": {args: "<id>", nargs: 1, flags: func(fs *flag.FlagSet) func(context.Context, *env, []string) error {
+		site := fs.String("site", "", "site of the device")
+		return func(ctx context.Context, e *env, args []string) error {
+			d, err := e.client.AddDevice(ctx, args[0], *site)
+			if err != nil {
+				return err
+			}
+			return e.out.devices([]client.Device{*d})
+		}
+	}},
+	"devices remove": {args: "<id>", nargs: 1, flags: func(*flag.FlagSet) func(context.Context, *env, []string) error {
+		return func(ctx context.Context, e *env, args []string) error {
+			if err := e.client.RemoveDevice(ctx, args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(e.stdout, "removed %s\n", args[0])
+			return nil
+		}
+	}},
+	"stats": {args: "<id>", nargs: 1, flags: func(*flag.FlagSet) func(context.Context, *env, []string) error {
+		return func(ctx context.Context, e *env, args []string) error {
+			stats, err := e.client.GetStats(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			return e.out.stats(stats)
+		}
+	}},
+	"outages": {args: "<id>", nargs: 1, flags: func(fs *flag.FlagSet) func(context.Context, *env, []string) error {
+		sinceFlag := fs.String("since", "24h", "RFC 3339 time or a duration back from now")
+		return func(ctx context.Context, e *env, args []string) error {
+			since, err := parseSince(*sinceFlag, time.Now())
+			if err != nil {
+				return err
+			}
+			outages, err := e.client.Outages(ctx, args[0], since)
+			if err != nil {
+				return err
+			}
+			return e.out.outages(outages)
+		}
+	}},
+	"export": {nargs: 0, flags: func(fs *flag.FlagSet) func(context.Context, *env, []string) error {
+		dataset := fs.String("dataset", "stats", "stats (one row per device) or series (one row per device minute)")
+		format := fs.String("format", "csv", "csv or parquet")
+		devices := fs.String("device", "", "comma-separated device IDs (default all)")
+		from := fs.String("from", "", "RFC 3339 start of the series, inclusive")
+		to := fs.String("to", "", "RFC 3339 end of the series, exclusive")
+		outPath := fs.String("out", "", "write to this file instead of stdout")
+		return func(ctx context.Context, e *env, _ []string) error {
+			req := client.ExportRequest{Dataset: *dataset, Format: *format}
+			if *devices != "" {
+				req.DeviceIDs = strings.Split(*devices, ",")
+			}
+			var err error
+			if req.From, err = parseTime("-from", *from); err != nil {
+				return err
+			}
+			if req.To, err = parseTime("-to", *to); err != nil {
+				return err
+			}
+			if *outPath == "" {
+				_, err = e.client.Export(ctx, req, e.stdout)
+				return err
+			}
+			f, err := os.Create(*outPath)
+			if err != nil {
+				return err
+			}
+			if _, err := e.client.Export(ctx, req, f); err != nil {
+				_ = f.Close()
+				_ = os.Remove(*outPath)
+				return err
+			}
+			return f.Close()
+		}
+	}},
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	name, rest := commandName(args)
+	cmd, ok := commands[name]
+	if !ok {
+		if name != "" && name != "help" && name != "-h" && name != "--help" {
+			fmt.Fprintf(stderr, "fleetctl: unknown command %q\n\n", name)
+		}
+		fmt.Fprint(stderr, usage)
+		if name == "help" || name == "-h" || name == "--help" {
+			return 0
+		}
+		return 2
+	}
+
+	fs := flag.NewFlagSet("fleetctl "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", defaultConfigPath(), "config file")
+	server := fs.String("server", "", "server base URL")
+	token := fs.String("token", "", "bearer token")
+	output := fs.String("o", "table", "output format: table, json or yaml")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	exec := cmd.flags(fs)
+
+	positional, err := parseInterspersed(fs, rest)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if len(positional) != cmd.nargs {
+		fmt.Fprintf(stderr, "usage: fleetctl %s %s\n", name, cmd.args)
+		return 2
+	}
+
+	fail := func(err error) int {
+		fmt.Fprintln(stderr, "fleetctl:", err)
+		return 1
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return fail(err)
+	}
+	if *server != "" {
+		cfg.Server = *server
+	}
+	if *token != "" {
+		cfg.Token = *token
+	}
+	out, err := newPrinter(stdout, *output)
+	if err != nil {
+		return fail(err)
+	}
+
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	c := client.New(cfg.Server, client.WithToken(cfg.Token), client.WithSigningSecret(cfg.SigningSecret))
+	e := &env{client: c, out: out, stdout: stdout}
+	if err := exec(ctx, e, positional); err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+// commandName picks the command from the leading arguments: "devices"
+// takes a subcommand, the others are a single word.
+func commandName(args []string) (string, []string) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	if args[0] == "devices" && len(args) > 1 {
+		return "devices " + args[1], args[2:]
+	}
+	return args[0], args[1:]
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// positional arguments, and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseSince reads an RFC 3339 time or a duration before now.
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid -since %q: want an RFC 3339 time or a duration such as 24h", v)
+}
+
+func parseTime(name, v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: want an RFC 3339 time", name, v)
+	}
+	return t, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+
+	httpadapter "safelyyou/internal/adapters/http"
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/services"
+)
+
+const testDevice = "60-6b-44-84-dc-64"
+
+// newConfiguredServer starts a fleet server requiring a token and points
+// a config file at it.
+func newConfiguredServer(t *testing.T) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := memory.NewDeviceRepository()
+	svc := services.NewDeviceService(repo)
+	if _, err := svc.AddDevice(context.Background(), testDevice, "north"); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Now().UTC().Add(-time.Hour)
+	for _, m := range []int{0, 1, 4} {
+		if err := svc.RecordHeartbeat(context.Background(), testDevice, base.Add(time.Duration(m)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := gin.New()
+	r.Use(httpadapter.BearerAuth("secret", nil))
+	httpadapter.RegisterRoutes(r, svc)
+	httpadapter.RegisterExportRoutes(r, services.NewExportService(repo))
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("server: "+srv.URL+"\ntoken: secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FLEETCTL_CONFIG", path)
+	t.Setenv("FLEET_SERVER", "")
+	t.Setenv("API_TOKEN", "")
+	t.Setenv("API_SIGNING_SECRET", "")
+}
+
+func fleetctl(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr)
+	return stdout.String() + stderr.String(), code
+}
+
+func TestFleetctl_Commands(t *testing.T) {
+	newConfiguredServer(t)
+
+	out, code := fleetctl(t, "devices", "add", "b4-45-52-a2-f1-3c", "-site", "south")
+	if code != 0 || !strings.Contains(out, "south") {
+		t.Fatalf("devices add: %d %s", code, out)
+	}
+
+	out, code = fleetctl(t, "devices", "list", "-o", "json")
+	var devices []map[string]any
+	if code != 0 || json.Unmarshal([]byte(out), &devices) != nil || len(devices) != 2 {
+		t.Fatalf("devices list: %d %s", code, out)
+	}
+
+	out, code = fleetctl(t, "stats", testDevice, "-o", "yaml")
+	var stats map[string]any
+	if code != 0 || yaml.Unmarshal([]byte(out), &stats) != nil || stats["site"] != "north" {
+		t.Fatalf("stats: %d %s", code, out)
+	}
+
+	out, code = fleetctl(t, "outages", testDevice, "--since", "2h")
+	if code != 0 || !strings.Contains(out, "START") || !strings.Contains(out, "2m0s") {
+		t.Fatalf("outages: %d %s", code, out)
+	}
+
+	out, code = fleetctl(t, "export", "-device", testDevice)
+	if code != 0 || !strings.Contains(out, testDevice) {
+		t.Fatalf("export: %d %s", code, out)
+	}
+
+	out, code = fleetctl(t, "devices", "remove", "b4-45-52-a2-f1-3c")
+	if code != 0 || !strings.Contains(out, "removed") {
+		t.Fatalf("devices remove: %d %s", code, out)
+	}
+	out, code = fleetctl(t, "stats", "b4-45-52-a2-f1-3c")
+	if code != 1 || !strings.Contains(out, "404") {
+		t.Fatalf("stats of removed device: %d %s", code, out)
+	}
+}
+
+func TestFleetctl_FlagsOverrideConfig(t *testing.T) {
+	newConfiguredServer(t)
+
+	out, code := fleetctl(t, "devices", "list", "-token", "wrong")
+	if code != 1 || !strings.Contains(out, "401") {
+		t.Fatalf("expected the -token flag to win over the config: %d %s", code, out)
+	}
+	t.Setenv("API_TOKEN", "wrong")
+	if out, code = fleetctl(t, "devices", "list"); code != 1 {
+		t.Fatalf("expected API_TOKEN to win over the config: %d %s", code, out)
+	}
+}
+
+func TestFleetctl_UsageErrors(t *testing.T) {
+	for _, args := range [][]string{{}, {"bogus"}, {"stats"}, {"devices", "add"}, {"stats", testDevice, "extra"}} {
+		if _, code := fleetctl(t, args...); code != 2 {
+			t.Errorf("%v: expected exit status 2, got %d", args, code)
+		}
+	}
+	if out, code := fleetctl(t, "devices", "list", "-o", "xml", "-server", "http://127.0.0.1:1"); code != 1 || !strings.Contains(out, "xml") {
+		t.Errorf("expected unknown output format to fail: %d %s", code, out)
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("t", flag.ContinueOnError)
+	since := fs.String("since", "", "")
+	args, err := parseInterspersed(fs, []string{"a", "-since", "1h", "b"})
+	if err != nil || *since != "1h" || len(args) != 2 || args[1] != "b" {
+		t.Fatalf("got %v %q %v", args, *since, err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"safelyyou/pkg/client"
+)
+
+// printer writes command results in the format chosen with -o.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "table", "json", "yaml":
+		return &printer{w: w, format: format}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q (want table, json or yaml)", format)
+}
+
+// print writes v as JSON or YAML, or calls table for the table format.
+func (p *printer) print(v any, table func(*tabwriter.Writer)) error {
+	switch p.format {
+	case "json":
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		enc := yaml.NewEncoder(p.w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+func (p *printer) devices(devices []client.Device) error {
+	return p.print(devices, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "DEVICE\tSITE\tHEARTBEATS\tUPLOADS\tLAST SEEN")
+		for _, d := range devices {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", d.ID, dash(d.Site), d.HeartbeatCount, d.UploadCount, timeOrDash(d.LastSeenAt))
+		}
+	})
+}
+
+func (p *printer) stats(s *client.Stats) error {
+	return p.print(s, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "DEVICE\t%s\n", s.DeviceID)
+		fmt.Fprintf(tw, "SITE\t%s\n", dash(s.Site))
+		fmt.Fprintf(tw, "UPTIME\t%s\n", percent(s.UptimeRatio))
+		fmt.Fprintf(tw, "UPTIME 24H\t%s\n", percent(s.Uptime24hRatio))
+		fmt.Fprintf(tw, "AVG UPLOAD\t%s\n", millis(s.AvgUploadMs))
+		fmt.Fprintf(tw, "P95 UPLOAD\t%s\n", millis(s.P95UploadMs))
+		fmt.Fprintf(tw, "HEARTBEATS\t%d\n", s.Counts.Heartbeats)
+		fmt.Fprintf(tw, "UPLOADS\t%d\n", s.Counts.Uploads)
+		fmt.Fprintf(tw, "LAST HEARTBEAT\t%s\n", timeOrDash(s.LastHeartbeatAt))
+		fmt.Fprintf(tw, "LAST SEEN\t%s\n", timeOrDash(s.LastSeenAt))
+		fmt.Fprintf(tw, "CLOCK OFFSET\t%s\n", clockOffset(s))
+	})
+}
+
+func (p *printer) outages(outages []client.Outage) error {
+	if outages == nil {
+		outages = []client.Outage{}
+	}
+	return p.print(outages, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "START\tEND\tDURATION")
+		for _, o := range outages {
+			end, duration := "ongoing", "-"
+			if !o.Ongoing {
+				end = timeOrDash(o.End)
+				duration = millis(o.DurationMs)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", o.Start.UTC().Format(time.RFC3339), end, duration)
+		}
+	})
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func timeOrDash(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func percent(ratio float64) string {
+	return strconv.FormatFloat(ratio*100, 'f', 2, 64) + "%"
+}
+
+// clockOffset renders the estimated device clock offset, flagged when
+// the server considers it skewed.
+func clockOffset(s *client.Stats) string {
+	out := millis(s.ClockOffsetMs)
+	if s.ClockSkewed {
+		out += " (skewed)"
+	}
+	return out
+}
+
+func millis(ms float64) string {
+	return time.Duration(ms * float64(time.Millisecond)).Round(time.Microsecond).String()
+}
@@ -0,0 +1,222 @@
+// Command import backfills historical heartbeats and upload stats from a
+// CSV or NDJSON file, either through a running server's admin endpoint or
+// directly into a device snapshot file while the server is stopped.
+//
+//	import -server http://localhost:8080 -token $API_TOKEN history.csv
+//	import -devices devices.csv -snapshot state.json -dry-run history.ndjson
+//
+// The exit status is 0 when every row was accepted, 2 when some were
+// rejected and 1 when the import could not run.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	httpadapter "safelyyou/internal/adapters/http"
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/ports"
+	"safelyyou/internal/core/services"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", "", "base URL of a running server; the rows are posted to its admin import endpoint")
+	token := fs.String("token", os.Getenv("API_TOKEN"), "bearer token for -server (default $API_TOKEN)")
+	devicesCSV := fs.String("devices", "devices.csv", "CSV of known devices, without -server")
+	snapshot := fs.String("snapshot", os.Getenv("DEVICE_SNAPSHOT_PATH"), "device snapshot file to update, without -server (default $DEVICE_SNAPSHOT_PATH)")
+	format := fs.String("format", "", "csv or ndjson (default from the file extension)")
+	dryRun := fs.Bool("dry-run", false, "validate rows without recording them")
+	timeout := fs.Duration("timeout", 10*time.Minute, "how long the import may take")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: import [flags] FILE (- for stdin)")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	path := fs.Arg(0)
+
+	opts := ports.ImportOptions{Format: ports.ImportFormat(*format), DryRun: *dryRun}
+	if opts.Format == "" {
+		opts.Format = formatOf(path)
+	}
+	if opts.Format != ports.ImportCSV && opts.Format != ports.ImportNDJSON {
+		fmt.Fprintf(stderr, "import: cannot tell the format of %q, use -format csv|ndjson\n", path)
+		return 1
+	}
+
+	in := stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(stderr, "import:", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	var report *ports.ImportReport
+	var err error
+	if *server != "" {
+		report, err = importRemote(ctx, *server, *token, in, opts)
+	} else {
+		report, err = importLocal(ctx, *devicesCSV, *snapshot, in, opts)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "import:", err)
+		return 1
+	}
+
+	printReport(stdout, report)
+	if report.Rejected > 0 {
+		return 2
+	}
+	return 0
+}
+
+func formatOf(path string) ports.ImportFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ports.ImportCSV
+	case ".ndjson", ".jsonl":
+		return ports.ImportNDJSON
+	}
+	return ""
+}
+
+// importLocal applies the rows to the snapshot file, the same state the
+// server restores at start. The server must not be running, or it would
+// overwrite the snapshot when it shuts down.
+func importLocal(ctx context.Context, devicesCSV, snapshot string, in io.Reader, opts ports.ImportOptions) (*ports.ImportReport, error) {
+	if snapshot == "" && !opts.DryRun {
+		return nil, errors.New("-snapshot is required without -server, or nothing would be kept")
+	}
+	repo := memory.NewDeviceRepository()
+	if err := repo.LoadFromCSV(devicesCSV); err != nil {
+		return nil, fmt.Errorf("load devices: %w", err)
+	}
+	if snapshot != "" {
+		if err := repo.UseSnapshotFile(snapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	report, err := services.NewImportService(services.NewDeviceService(repo)).Import(ctx, in, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.DryRun {
+		if err := repo.Flush(); err != nil {
+			return nil, fmt.Errorf("write snapshot: %w", err)
+		}
+	}
+	return report, nil
+}
+
+var importContentType = map[ports.ImportFormat]string{
+	ports.ImportCSV:    "text/csv",
+	ports.ImportNDJSON: "application/x-ndjson",
+}
+
+// importRemote streams the file to POST /api/v1/admin/import.
+func importRemote(ctx context.Context, server, token string, in io.Reader, opts ports.ImportOptions) (*ports.ImportReport, error) {
+	q := url.Values{"format": {string(opts.Format)}}
+	if opts.DryRun {
+		q.Set("dry_run", "true")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimRight(server, "/")+"/api/v1/admin/import?"+q.Encode(), in)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", importContentType[opts.Format])
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var problem httpadapter.Problem
+		if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil || problem.Title == "" {
+			return nil, fmt.Errorf("server answered %s", resp.Status)
+		}
+		if problem.Detail != "" {
+			return nil, fmt.Errorf("server answered %s: %s", resp.Status, problem.Detail)
+		}
+		return nil, fmt.Errorf("server answered %s: %s", resp.Status, problem.Title)
+	}
+
+	var body httpadapter.ImportReportResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode report: %w", err)
+	}
+	report := &ports.ImportReport{
+		DryRun:          body.DryRun,
+		Rows:            body.Rows,
+		Accepted:        body.Accepted,
+		Rejected:        body.Rejected,
+		Heartbeats:      body.Heartbeats,
+		Stats:           body.Stats,
+		ErrorsTruncated: body.ErrorsTruncated,
+	}
+	for _, e := range body.Errors {
+		report.Errors = append(report.Errors, ports.ImportRowError{Line: e.Line, DeviceID: e.DeviceID, Reason: e.Reason})
+	}
+	return report, nil
+}
+
+func printReport(w io.Writer, r *ports.ImportReport) {
+	mode := ""
+	if r.DryRun {
+		mode = " (dry run, nothing recorded)"
+	}
+	fmt.Fprintf(w, "%d rows: %d accepted (%d heartbeats, %d stats), %d rejected%s\n",
+		r.Rows, r.Accepted, r.Heartbeats, r.Stats, r.Rejected, mode)
+	for _, e := range r.Errors {
+		if e.DeviceID != "" {
+			fmt.Fprintf(w, "  line %d [%s]: %s\n", e.Line, e.DeviceID, e.Reason)
+		} else {
+			fmt.Fprintf(w, "  line %d: %s\n", e.Line, e.Reason)
+		}
+	}
+	if r.ErrorsTruncated {
+		fmt.Fprintf(w, "  ... and %d more\n", r.Rejected-len(r.Errors))
+	}
+}
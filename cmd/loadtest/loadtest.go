@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint is a kind of request in the traffic mix.
+type Endpoint string
+
+const (
+	Heartbeat Endpoint = "heartbeat" // POST /api/v1/devices/{id}/heartbeat
+	Stats     Endpoint = "stats"     // POST /api/v1/devices/{id}/stats
+	Read      Endpoint = "read"      // GET  /api/v1/devices/{id}/stats
+	List      Endpoint = "list"      // GET  /api/v1/devices
+)
+
+var endpoints = []Endpoint{Heartbeat, Stats, Read, List}
+
+// Mix weighs the endpoints, e.g. "heartbeat=70,stats=20,read=10".
+type Mix struct {
+	weights []int // indexed like endpoints
+	total   int
+}
+
+// ParseMix parses a comma-separated list of endpoint=weight pairs.
+func ParseMix(s string) (Mix, error) {
+	m := Mix{weights: make([]int, len(endpoints))}
+	for part := range strings.SplitSeq(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		i := slices.Index(endpoints, Endpoint(name))
+		if !ok || i < 0 {
+			return Mix{}, fmt.Errorf("invalid mix entry %q, want one of heartbeat, stats, read, list with =weight", part)
+		}
+		w, err := strconv.Atoi(value)
+		if err != nil || w < 0 {
+			return Mix{}, fmt.Errorf("invalid weight %q for %s", value, name)
+		}
+		m.weights[i] += w
+		m.total += w
+	}
+	if m.total == 0 {
+		return Mix{}, fmt.Errorf("mix %q has no traffic", s)
+	}
+	return m, nil
+}
+
+func (m Mix) pick(rng *rand.Rand) Endpoint {
+	n := rng.IntN(m.total)
+	for i, w := range m.weights {
+		if n < w {
+			return endpoints[i]
+		}
+		n -= w
+	}
+	return endpoints[len(endpoints)-1]
+}
+
+// DeviceID returns the i-th synthetic device ID. The first octet marks a
+// locally administered address, so the IDs never clash with real devices.
+func DeviceID(i int) string {
+	return fmt.Sprintf("02-%02x-%02x-%02x-%02x-%02x", byte(i>>32), byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+}
+
+// Runner drives load against a server.
+type Runner struct {
+	BaseURL string
+	Token   string
+	Client  *http.Client
+	Devices int
+	Mix     Mix
+	// Timeout bounds each request.
+	Timeout time.Duration
+}
+
+// sample is the outcome of one request.
+type sample struct {
+	endpoint Endpoint
+	status   int // 0 when the request failed before a response
+	latency  time.Duration
+}
+
+// Step runs concurrency closed-loop workers for d and summarizes what
+// they observed.
+func (r *Runner) Step(ctx context.Context, concurrency int, d time.Duration) StepResult {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	results := make([][]sample, concurrency)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(uint64(start.UnixNano()), uint64(w)))
+			for ctx.Err() == nil {
+				s := r.send(ctx, rng)
+				if ctx.Err() != nil && s.status == 0 {
+					break // cut off by the end of the step, not a failure
+				}
+				results[w] = append(results[w], s)
+			}
+		}()
+	}
+	wg.Wait()
+	return summarize(concurrency, time.Since(start), slices.Concat(results...))
+}
+
+func (r *Runner) send(ctx context.Context, rng *rand.Rand) sample {
+	e := r.Mix.pick(rng)
+	id := DeviceID(rng.IntN(max(r.Devices, 1)))
+	base := strings.TrimRight(r.BaseURL, "/") + "/api/v1/devices"
+
+	method, url := http.MethodGet, base+"/"+id+"/stats"
+	var body []byte
+	now := time.Now().UTC()
+	switch e {
+	case Heartbeat:
+		method, url = http.MethodPost, base+"/"+id+"/heartbeat"
+		body, _ = json.Marshal(map[string]any{"sent_at": now})
+	case Stats:
+		method = http.MethodPost
+		body, _ = json.Marshal(map[string]any{"sent_at": now, "upload_time": time.Second.Nanoseconds() + rng.Int64N(int64(5*time.Minute))})
+	case List:
+		url = base
+	}
+
+	reqCtx := ctx
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(reqCtx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return sample{endpoint: e}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+
+	sent := time.Now()
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return sample{endpoint: e, latency: time.Since(sent)}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return sample{endpoint: e, status: resp.StatusCode, latency: time.Since(sent)}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"safelyyou/pkg/utils"
+)
+
+func TestParseMix(t *testing.T) {
+	m, err := ParseMix("heartbeat=3, read=1,heartbeat=1")
+	if err != nil {
+		t.Fatalf("ParseMix: %v", err)
+	}
+	if m.total != 5 || m.weights[0] != 4 || m.weights[2] != 1 {
+		t.Errorf("unexpected mix %+v", m)
+	}
+	for _, bad := range []string{"", "upload=1", "heartbeat=-1", "heartbeat=0,read=0"} {
+		if _, err := ParseMix(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestDeviceID_IsValidAndDistinct(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, i := range []int{0, 1, 255, 256, 49999, 1 << 30} {
+		id := DeviceID(i)
+		if !utils.IsId(id) || seen[id] {
+			t.Errorf("DeviceID(%d) = %q is invalid or duplicated", i, id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestRunnerStep_ReportsPerEndpoint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/heartbeat"):
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+
+	mix, _ := ParseMix("heartbeat=1,stats=1,read=1")
+	r := &Runner{BaseURL: srv.URL, Client: srv.Client(), Devices: 10, Mix: mix}
+	step := r.Step(context.Background(), 4, 200*time.Millisecond)
+
+	if step.Concurrency != 4 || step.Total.Requests == 0 || len(step.Endpoints) != 3 {
+		t.Fatalf("unexpected step %+v", step)
+	}
+	for _, e := range step.Endpoints {
+		wantErrors := e.Endpoint == Stats
+		if (e.Errors == e.Requests) != wantErrors || (e.Errors == 0) == wantErrors {
+			t.Errorf("%s: unexpected errors %d of %d (%v)", e.Endpoint, e.Errors, e.Requests, e.Statuses)
+		}
+		if e.Throughput <= 0 || e.P99 < e.P50 || e.Max < e.P99 {
+			t.Errorf("%s: inconsistent figures %+v", e.Endpoint, e)
+		}
+	}
+
+	report := &Report{Server: srv.URL, Devices: 10, Mix: "m", Steps: []StepResult{step}}
+	var out bytes.Buffer
+	if err := report.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON report: %v", err)
+	}
+	out.Reset()
+	if err := report.WriteText(&out); err != nil || !strings.Contains(out.String(), "concurrency 4") {
+		t.Errorf("unexpected text report %q (%v)", out.String(), err)
+	}
+}
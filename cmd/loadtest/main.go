@@ -0,0 +1,148 @@
+// Command loadtest drives a mix of ingestion and read traffic across
+// synthetic devices at increasing concurrency and reports throughput, error
+// rates and latency percentiles per endpoint.
+//
+// The server only accepts known devices, so generate a matching device
+// list first and start the server with it:
+//
+//	loadtest -devices 50000 -write-devices /tmp/devices.csv
+//	DEVICE_CSV=/tmp/devices.csv RATE_LIMIT_HEARTBEAT=device=0 RATE_LIMIT_STATS=device=0 go run ./cmd/app
+//	loadtest -devices 50000 -ramp 10,50,100,200 -step 30s -format json
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", "http://localhost:8080", "base URL of the server")
+	token := fs.String("token", os.Getenv("API_TOKEN"), "bearer token (default $API_TOKEN)")
+	devices := fs.Int("devices", 1000, "number of synthetic devices")
+	writeDevices := fs.String("write-devices", "", "write the synthetic devices as a devices CSV to this path and exit")
+	mixFlag := fs.String("mix", "heartbeat=70,stats=20,read=9,list=1", "traffic weights per endpoint (heartbeat, stats, read, list)")
+	rampFlag := fs.String("ramp", "10,50,100", "comma-separated concurrency levels, run in order")
+	stepDuration := fs.Duration("step", 30*time.Second, "how long each concurrency level runs")
+	maxErrorRate := fs.Float64("max-error-rate", 0.05, "stop the ramp after a step whose error rate exceeds this (0 never stops)")
+	timeout := fs.Duration("timeout", 10*time.Second, "per-request timeout")
+	format := fs.String("format", "text", "report format: text or json")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	fail := func(err error) int {
+		fmt.Fprintln(stderr, "loadtest:", err)
+		return 1
+	}
+	if *devices < 1 {
+		return fail(errors.New("-devices must be >= 1"))
+	}
+
+	if *writeDevices != "" {
+		if err := writeDeviceCSV(*writeDevices, *devices); err != nil {
+			return fail(err)
+		}
+		return 0
+	}
+
+	mix, err := ParseMix(*mixFlag)
+	if err != nil {
+		return fail(err)
+	}
+	ramp, err := parseRamp(*rampFlag)
+	if err != nil {
+		return fail(err)
+	}
+	if *format != "text" && *format != "json" {
+		return fail(fmt.Errorf("unknown -format %q", *format))
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = slices.Max(ramp)
+	runner := &Runner{
+		BaseURL: *server,
+		Token:   *token,
+		Client:  &http.Client{Transport: transport},
+		Devices: *devices,
+		Mix:     mix,
+		Timeout: *timeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report := &Report{Server: *server, Devices: *devices, Mix: *mixFlag}
+	for _, c := range ramp {
+		if ctx.Err() != nil {
+			break
+		}
+		fmt.Fprintf(stderr, "running %d workers for %s\n", c, *stepDuration)
+		step := runner.Step(ctx, c, *stepDuration)
+		report.Steps = append(report.Steps, step)
+		if *maxErrorRate > 0 && step.Total.ErrorRate > *maxErrorRate {
+			report.BreakingPoint = c
+			break
+		}
+	}
+
+	if *format == "json" {
+		err = report.WriteJSON(stdout)
+	} else {
+		err = report.WriteText(stdout)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+func parseRamp(s string) ([]int, error) {
+	var ramp []int
+	for part := range strings.SplitSeq(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid concurrency %q in -ramp", part)
+		}
+		ramp = append(ramp, n)
+	}
+	return ramp, nil
+}
+
+// writeDeviceCSV writes a devices.csv listing the first n synthetic devices.
+func writeDeviceCSV(path string, n int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"device_id"})
+	for i := range n {
+		_ = w.Write([]string{DeviceID(i)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
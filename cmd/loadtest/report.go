@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// Millis is a duration reported in milliseconds.
+type Millis time.Duration
+
+func (m Millis) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatFloat(float64(m)/float64(time.Millisecond), 'f', 3, 64)), nil
+}
+
+func (m Millis) String() string {
+	return strconv.FormatFloat(float64(m)/float64(time.Millisecond), 'f', 2, 64) + "ms"
+}
+
+// EndpointResult summarizes one endpoint during one step. Errors counts
+// failed requests and responses with status >= 400 (429s included).
+type EndpointResult struct {
+	Endpoint   Endpoint       `json:"endpoint"`
+	Requests   int            `json:"requests"`
+	Throughput float64        `json:"throughput_rps"`
+	Errors     int            `json:"errors"`
+	ErrorRate  float64        `json:"error_rate"`
+	Statuses   map[string]int `json:"statuses"`
+	P50        Millis         `json:"p50_ms"`
+	P90        Millis         `json:"p90_ms"`
+	P99        Millis         `json:"p99_ms"`
+	Max        Millis         `json:"max_ms"`
+}
+
+// StepResult summarizes one concurrency level; Total aggregates all
+// endpoints.
+type StepResult struct {
+	Concurrency int              `json:"concurrency"`
+	Duration    Millis           `json:"duration_ms"`
+	Total       EndpointResult   `json:"total"`
+	Endpoints   []EndpointResult `json:"endpoints"`
+}
+
+// Report is the outcome of a ramp. BreakingPoint is the concurrency of the
+// first step whose error rate exceeded the threshold, or 0.
+type Report struct {
+	Server        string       `json:"server"`
+	Devices       int          `json:"devices"`
+	Mix           string       `json:"mix"`
+	Steps         []StepResult `json:"steps"`
+	BreakingPoint int          `json:"breaking_point,omitempty"`
+}
+
+func summarize(concurrency int, elapsed time.Duration, samples []sample) StepResult {
+	step := StepResult{Concurrency: concurrency, Duration: Millis(elapsed)}
+	step.Total = endpointResult("total", elapsed, samples)
+	for _, e := range endpoints {
+		var own []sample
+		for _, s := range samples {
+			if s.endpoint == e {
+				own = append(own, s)
+			}
+		}
+		if len(own) > 0 {
+			step.Endpoints = append(step.Endpoints, endpointResult(e, elapsed, own))
+		}
+	}
+	return step
+}
+
+func endpointResult(e Endpoint, elapsed time.Duration, samples []sample) EndpointResult {
+	r := EndpointResult{Endpoint: e, Requests: len(samples), Statuses: make(map[string]int)}
+	if len(samples) == 0 {
+		return r
+	}
+	latencies := make([]time.Duration, 0, len(samples))
+	for _, s := range samples {
+		status := "error"
+		if s.status != 0 {
+			status = strconv.Itoa(s.status)
+		}
+		r.Statuses[status]++
+		if s.status == 0 || s.status >= 400 {
+			r.Errors++
+		}
+		latencies = append(latencies, s.latency)
+	}
+	slices.Sort(latencies)
+	at := func(p float64) Millis { return Millis(latencies[int(p*float64(len(latencies)-1))]) }
+	r.P50, r.P90, r.P99, r.Max = at(0.50), at(0.90), at(0.99), Millis(latencies[len(latencies)-1])
+	r.Throughput = float64(len(samples)) / elapsed.Seconds()
+	r.ErrorRate = float64(r.Errors) / float64(len(samples))
+	return r
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes one table per step.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "load test of %s: %d devices, mix %s\n", r.Server, r.Devices, r.Mix)
+	for _, step := range r.Steps {
+		fmt.Fprintf(tw, "\nconcurrency %d for %s\n", step.Concurrency, time.Duration(step.Duration).Round(time.Millisecond))
+		fmt.Fprintln(tw, "endpoint\trequests\treq/s\terrors\terror %\tp50\tp90\tp99\tmax\t")
+		for _, e := range append(step.Endpoints, step.Total) {
+			fmt.Fprintf(tw, "%s\t%d\t%.1f\t%d\t%.2f\t%s\t%s\t%s\t%s\t\n",
+				e.Endpoint, e.Requests, e.Throughput, e.Errors, e.ErrorRate*100, e.P50, e.P90, e.P99, e.Max)
+		}
+	}
+	if r.BreakingPoint > 0 {
+		fmt.Fprintf(tw, "\nerror rate threshold exceeded at concurrency %d\n", r.BreakingPoint)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxCaptureLine bounds a single capture line.
+const maxCaptureLine = 4 << 20
+
+// Request is one captured request:
+//
+//	{"time":"2025-11-09T10:00:00.123Z","method":"POST",
+//	 "path":"/api/v1/devices/60-6b-44-84-dc-64/heartbeat",
+//	 "headers":{"Content-Type":"application/json"},
+//	 "body":{"sent_at":"2025-11-09T10:00:00Z"}}
+//
+// body is either the JSON payload itself or a string holding the raw body.
+// time is when the request was received; it drives the replay timing.
+type Request struct {
+	Time    time.Time         `json:"time"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+
+	// Line is the 1-based line of the request in the capture.
+	Line int `json:"-"`
+}
+
+// Payload returns the request body as sent on the wire.
+func (r Request) Payload() []byte {
+	body := bytes.TrimSpace(r.Body)
+	if len(body) > 0 && body[0] == '"' {
+		var s string
+		if err := json.Unmarshal(body, &s); err == nil {
+			return []byte(s)
+		}
+	}
+	if bytes.Equal(body, []byte("null")) {
+		return nil
+	}
+	return body
+}
+
+// DeviceID returns the device a /api/vN/devices/{id}/... request targets,
+// or "" for any other path.
+func (r Request) DeviceID() string {
+	path, _, _ := strings.Cut(r.Path, "?")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 4 && parts[0] == "api" && parts[2] == "devices" {
+		return parts[3]
+	}
+	return ""
+}
+
+// captureReader reads a capture line by line. Lines that are not requests
+// (blank, not JSON, or without method and path) are counted and skipped,
+// so a capture can be interleaved with other records.
+type captureReader struct {
+	sc      *bufio.Scanner
+	line    int
+	Skipped int
+}
+
+func newCaptureReader(r io.Reader) *captureReader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxCaptureLine)
+	return &captureReader{sc: sc}
+}
+
+// Next returns the next request, or io.EOF at the end of the capture.
+func (c *captureReader) Next() (Request, error) {
+	for c.sc.Scan() {
+		c.line++
+		text := bytes.TrimSpace(c.sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var req Request
+		if err := json.Unmarshal(text, &req); err != nil || req.Method == "" || !strings.HasPrefix(req.Path, "/") {
+			c.Skipped++
+			continue
+		}
+		req.Method = strings.ToUpper(req.Method)
+		req.Line = c.line
+		return req, nil
+	}
+	if err := c.sc.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return Request{}, errors.New("capture line too long")
+		}
+		return Request{}, err
+	}
+	return Request{}, io.EOF
+}
@@ -0,0 +1,132 @@
+// Command replay re-sends captured API traffic, one JSON request per line
+// (see Request), to reproduce production issues locally.
+//
+//	replay -target http://localhost:8080 -speed 10 capture.jsonl
+//	replay -in-process -devices devices.csv -speed 0 capture.jsonl
+//
+// With -in-process the device API is replayed straight into a fresh
+// DeviceService and the resulting stats are printed.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/ports"
+	"safelyyou/internal/core/services"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	target := fs.String("target", "", "base URL of the server to replay against")
+	token := fs.String("token", os.Getenv("API_TOKEN"), "bearer token for -target (default $API_TOKEN)")
+	inProcess := fs.Bool("in-process", false, "replay into an in-process DeviceService instead of a server")
+	devicesCSV := fs.String("devices", "devices.csv", "CSV of known devices, with -in-process")
+	speed := fs.Float64("speed", 1, "timing factor: 1 keeps the captured pace, 10 is ten times faster, 0 is as fast as possible")
+	concurrency := fs.Int("concurrency", 8, "requests in flight; requests for one device stay in order")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: replay (-target URL | -in-process) [flags] CAPTURE (- for stdin)")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() != 1 || (*target == "") == !*inProcess || *speed < 0 || *concurrency < 1 {
+		fs.Usage()
+		return 1
+	}
+
+	in := stdin
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(stderr, "replay:", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	replayer := &Replayer{Speed: *speed, Concurrency: *concurrency}
+	var svc *services.DeviceServiceImpl
+	if *inProcess {
+		repo := memory.NewDeviceRepository()
+		if err := repo.LoadFromCSV(*devicesCSV); err != nil {
+			fmt.Fprintln(stderr, "replay: load devices:", err)
+			return 1
+		}
+		svc = services.NewDeviceService(repo)
+		replayer.Target = &serviceTarget{svc: svc}
+	} else {
+		replayer.Target = newHTTPTarget(*target, *token, *concurrency)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	capture := newCaptureReader(in)
+	report, err := replayer.Run(ctx, capture.Next)
+	report.Skipped = capture.Skipped
+	printReport(stdout, report)
+	if svc != nil {
+		printStats(ctx, stdout, svc)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "replay:", err)
+		return 1
+	}
+	return 0
+}
+
+func printReport(w io.Writer, r *Report) {
+	fmt.Fprintf(w, "replayed %d requests in %s (capture spans %s), %d lines skipped\n",
+		r.Requests, r.Elapsed.Round(time.Millisecond), r.CaptureSpan, r.Skipped)
+	for _, outcome := range slices.Sorted(maps.Keys(r.Outcomes)) {
+		fmt.Fprintf(w, "  %-12s %d\n", outcome, r.Outcomes[outcome])
+	}
+	if r.Failed > 0 {
+		fmt.Fprintf(w, "  %-12s %d\n", "failed", r.Failed)
+		for _, e := range r.Errors {
+			fmt.Fprintf(w, "    %s\n", e)
+		}
+	}
+	fmt.Fprintf(w, "latency p50=%s p99=%s max=%s, max schedule lag %s\n", r.P50, r.P99, r.MaxLatency, r.MaxLag.Round(time.Millisecond))
+}
+
+// printStats lists the devices that received traffic during the replay.
+func printStats(ctx context.Context, w io.Writer, svc *services.DeviceServiceImpl) {
+	all, err := svc.ListStats(ctx)
+	if err != nil {
+		return
+	}
+	all = slices.DeleteFunc(all, func(s ports.Stats) bool { return s.HeartbeatCount == 0 && s.UploadCount == 0 })
+	if len(all) == 0 {
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w)
+	fmt.Fprintln(tw, "DEVICE\tUPTIME\tAVG UPLOAD\tHEARTBEATS\tUPLOADS")
+	for _, s := range all {
+		fmt.Fprintf(tw, "%s\t%.2f\t%s\t%d\t%d\n", s.ID, s.Uptime, s.AvgUploadTime, s.HeartbeatCount, s.UploadCount)
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxReportedErrors caps the transport errors kept in a Report.
+const maxReportedErrors = 10
+
+// Replayer sends captured requests to a Target.
+//
+// Requests for the same device always go to the same worker, so their
+// relative order is preserved at any concurrency.
+type Replayer struct {
+	Target Target
+	// Speed scales the capture's timing: 1 replays at the original pace,
+	// 10 ten times faster. Zero sends every request as soon as a worker is
+	// free.
+	Speed       float64
+	Concurrency int
+
+	now func() time.Time
+}
+
+// Report summarizes a replay.
+type Report struct {
+	Requests int
+	Skipped  int
+	// Outcomes counts requests by status code (or core error kind
+	// in-process); Failed counts those that could not be sent.
+	Outcomes map[string]int
+	Failed   int
+	Errors   []string
+
+	Elapsed     time.Duration
+	CaptureSpan time.Duration
+	// MaxLag is how late the most delayed request was sent relative to its
+	// schedule; a large value means the replay could not keep the pace.
+	MaxLag     time.Duration
+	P50, P99   time.Duration
+	MaxLatency time.Duration
+
+	latencies []time.Duration
+}
+
+type job struct {
+	req Request
+	due time.Time
+}
+
+// Run replays the requests returned by next until it returns io.EOF. It
+// returns the report so far alongside any error that stopped it.
+func (r *Replayer) Run(ctx context.Context, next func() (Request, error)) (*Report, error) {
+	now := r.now
+	if now == nil {
+		now = time.Now
+	}
+	workers := max(r.Concurrency, 1)
+
+	report := &Report{Outcomes: make(map[string]int)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queues := make([]chan job, workers)
+	for i := range queues {
+		queues[i] = make(chan job, 64)
+		wg.Add(1)
+		go func(jobs <-chan job) {
+			defer wg.Done()
+			for j := range jobs {
+				if ctx.Err() != nil {
+					continue // drain
+				}
+				sent := now()
+				outcome, err := r.Target.Do(ctx, j.req)
+				latency := now().Sub(sent)
+
+				mu.Lock()
+				if !j.due.IsZero() {
+					report.MaxLag = max(report.MaxLag, sent.Sub(j.due))
+				}
+				if err != nil {
+					if ctx.Err() == nil {
+						report.Failed++
+						if len(report.Errors) < maxReportedErrors {
+							report.Errors = append(report.Errors, err.Error())
+						}
+					}
+				} else {
+					report.Outcomes[outcome]++
+					report.latencies = append(report.latencies, latency)
+				}
+				mu.Unlock()
+			}
+		}(queues[i])
+	}
+
+	start := now()
+	err := r.dispatch(ctx, next, queues, start, now, report)
+	for _, q := range queues {
+		close(q)
+	}
+	wg.Wait()
+
+	report.Elapsed = now().Sub(start)
+	report.summarize()
+	return report, err
+}
+
+// dispatch reads requests and hands each to its device's worker once it
+// is due. It owns report.Requests and report.CaptureSpan.
+func (r *Replayer) dispatch(ctx context.Context, next func() (Request, error), queues []chan job,
+	start time.Time, now func() time.Time, report *Report) error {
+	var first, last time.Time
+	for {
+		req, err := next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		j := job{req: req}
+		if !req.Time.IsZero() {
+			if first.IsZero() {
+				first = req.Time
+			}
+			last = req.Time
+			report.CaptureSpan = max(report.CaptureSpan, last.Sub(first))
+		}
+		if r.Speed > 0 && !first.IsZero() {
+			// Untimed requests follow the previous timed one.
+			j.due = start.Add(time.Duration(float64(last.Sub(first)) / r.Speed))
+			if wait := j.due.Sub(now()); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case queues[shard(req, len(queues))] <- j:
+			report.Requests++
+		}
+	}
+}
+
+func shard(req Request, n int) int {
+	key := req.DeviceID()
+	if key == "" {
+		key = req.Path
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+func (r *Report) summarize() {
+	if len(r.latencies) == 0 {
+		return
+	}
+	slices.Sort(r.latencies)
+	at := func(p float64) time.Duration {
+		return r.latencies[int(p*float64(len(r.latencies)-1))]
+	}
+	r.P50, r.P99 = at(0.50), at(0.99)
+	r.MaxLatency = r.latencies[len(r.latencies)-1]
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/domain"
+	"safelyyou/internal/core/services"
+)
+
+const replayDeviceID = "60-6b-44-84-dc-64"
+
+// recordingTarget remembers the order in which each device's requests
+// arrived.
+type recordingTarget struct {
+	mu   sync.Mutex
+	seen map[string][]int
+}
+
+func (t *recordingTarget) Do(_ context.Context, req Request) (string, error) {
+	time.Sleep(time.Millisecond)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seen[req.DeviceID()] = append(t.seen[req.DeviceID()], req.Line)
+	return "204", nil
+}
+
+func heartbeatLine(id string, at time.Time) string {
+	return fmt.Sprintf(`{"time":%q,"method":"POST","path":"/api/v1/devices/%s/heartbeat","body":{"sent_at":%q}}`,
+		at.Format(time.RFC3339Nano), id, at.Format(time.RFC3339))
+}
+
+func TestCaptureReader_SkipsNonRequests(t *testing.T) {
+	in := strings.Join([]string{
+		`{"request_id":"user-001","title":"not a request"}`,
+		``,
+		`{"method":"post","path":"/api/v1/devices/` + replayDeviceID + `/stats","body":"{\"upload_time\":5}"}`,
+		`garbage`,
+	}, "\n")
+	c := newCaptureReader(strings.NewReader(in))
+
+	req, err := c.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if req.Line != 3 || req.Method != http.MethodPost || req.DeviceID() != replayDeviceID || string(req.Payload()) != `{"upload_time":5}` {
+		t.Errorf("unexpected request %+v payload=%s", req, req.Payload())
+	}
+	if _, err := c.Next(); err == nil || c.Skipped != 2 {
+		t.Errorf("expected EOF after skipping 2 lines, got %v and %d skipped", err, c.Skipped)
+	}
+}
+
+func TestReplayer_KeepsPerDeviceOrder(t *testing.T) {
+	base := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	var lines []string
+	for i := range 40 {
+		lines = append(lines, heartbeatLine(fmt.Sprintf("60-6b-44-84-dc-%02d", i%4), base.Add(time.Duration(i)*time.Second)))
+	}
+	target := &recordingTarget{seen: make(map[string][]int)}
+	r := &Replayer{Target: target, Concurrency: 4}
+
+	report, err := r.Run(context.Background(), newCaptureReader(strings.NewReader(strings.Join(lines, "\n"))).Next)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Requests != 40 || report.Outcomes["204"] != 40 || report.CaptureSpan != 39*time.Second {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	for id, order := range target.seen {
+		for i := 1; i < len(order); i++ {
+			if order[i] < order[i-1] {
+				t.Fatalf("%s: requests reordered: %v", id, order)
+			}
+		}
+	}
+}
+
+func TestReplayer_ScalesCapturedTiming(t *testing.T) {
+	base := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	in := heartbeatLine(replayDeviceID, base) + "\n" + heartbeatLine(replayDeviceID, base.Add(2*time.Second))
+	target := &recordingTarget{seen: make(map[string][]int)}
+
+	report, err := (&Replayer{Target: target, Speed: 20}).Run(context.Background(), newCaptureReader(strings.NewReader(in)).Next)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Elapsed < 100*time.Millisecond || report.Elapsed > 2*time.Second {
+		t.Errorf("expected about 100ms at 20x, took %s", report.Elapsed)
+	}
+}
+
+func TestTargets_HTTPAndInProcessAgree(t *testing.T) {
+	base := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	in := strings.Join([]string{
+		heartbeatLine(replayDeviceID, base),
+		heartbeatLine("60-6b-44-84-dc-99", base),
+		`{"method":"POST","path":"/api/v1/devices/` + replayDeviceID + `/stats","body":{"sent_at":"2025-11-09T10:00:00Z","upload_time":-1}}`,
+		`{"method":"POST","path":"/api/v1/devices/` + replayDeviceID + `/stats","body":{"sent_at":"2025-11-09T10:00:00Z"}}`,
+	}, "\n")
+
+	repo := memory.NewDeviceRepository()
+	if err := repo.Add(context.Background(), domain.NewDeviceStats(replayDeviceID)); err != nil {
+		t.Fatal(err)
+	}
+	report, err := (&Replayer{Target: &serviceTarget{svc: services.NewDeviceService(repo)}}).Run(
+		context.Background(), newCaptureReader(strings.NewReader(in)).Next)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Outcomes["ok"] != 1 || report.Outcomes["not-found"] != 1 || report.Outcomes["validation"] != 2 {
+		t.Errorf("unexpected in-process outcomes %v", report.Outcomes)
+	}
+
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	report, err = (&Replayer{Target: newHTTPTarget(srv.URL, "secret", 1)}).Run(
+		context.Background(), newCaptureReader(strings.NewReader(in)).Next)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Outcomes["204"] != 4 || len(paths) != 4 || paths[0] != "/api/v1/devices/"+replayDeviceID+"/heartbeat" {
+		t.Errorf("unexpected HTTP replay %v to %v", report.Outcomes, paths)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	httpadapter "safelyyou/internal/adapters/http"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/utils"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Target executes replayed requests.
+type Target interface {
+	// Do sends req and returns its outcome: the HTTP status code, or the
+	// core error kind ("ok" on success) for an in-process replay. err is
+	// set only when the request could not be sent at all.
+	Do(ctx context.Context, req Request) (outcome string, err error)
+}
+
+// skippedHeaders are not replayed: they describe the original connection,
+// or a credential that -token replaces.
+var skippedHeaders = map[string]bool{
+	"Authorization":     true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Host":              true,
+	"Transfer-Encoding": true,
+}
+
+// httpTarget replays requests against a running server.
+type httpTarget struct {
+	base   string
+	token  string
+	client *http.Client
+}
+
+func newHTTPTarget(base, token string, conns int) *httpTarget {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = conns
+	return &httpTarget{
+		base:   strings.TrimRight(base, "/"),
+		token:  token,
+		client: &http.Client{Transport: transport},
+	}
+}
+
+func (t *httpTarget) Do(ctx context.Context, req Request) (string, error) {
+	var body io.Reader
+	if payload := req.Payload(); len(payload) > 0 {
+		body = bytes.NewReader(payload)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, t.base+req.Path, body)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range req.Headers {
+		if !skippedHeaders[http.CanonicalHeaderKey(k)] {
+			httpReq.Header.Set(k, v)
+		}
+	}
+	if body != nil && httpReq.Header.Get("Content-Type") == "" {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if t.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return strconv.Itoa(resp.StatusCode), nil
+}
+
+// serviceTarget replays the device API directly against a DeviceService,
+// bypassing HTTP, so a capture can be reproduced under a debugger.
+type serviceTarget struct {
+	svc ports.DeviceService
+}
+
+func (t *serviceTarget) Do(ctx context.Context, req Request) (string, error) {
+	err := t.dispatch(ctx, req)
+	if err == nil {
+		return "ok", nil
+	}
+	if ctx.Err() != nil {
+		return "", err
+	}
+	return coreerrors.KindOf(err).String(), nil
+}
+
+func (t *serviceTarget) dispatch(ctx context.Context, req Request) error {
+	path, _, _ := strings.Cut(req.Path, "?")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 3 || parts[0] != "api" || parts[2] != "devices" {
+		return coreerrors.New(coreerrors.KindNotFound, "route not replayable in-process: "+path)
+	}
+	if len(parts) == 3 && req.Method == http.MethodGet {
+		_, err := t.svc.ListStats(ctx)
+		return err
+	}
+	if len(parts) != 5 {
+		return coreerrors.New(coreerrors.KindNotFound, "route not replayable in-process: "+path)
+	}
+
+	id := parts[3]
+	if !utils.IsId(id) {
+		return coreerrors.Invalid("device_id", "is invalid")
+	}
+	switch req.Method + " " + parts[4] {
+	case "POST heartbeat":
+		var body httpadapter.HeartbeatRequest
+		if err := bind(req.Payload(), &body); err != nil {
+			return err
+		}
+		return t.svc.RecordHeartbeat(ctx, id, body.SentAt)
+	case "POST stats":
+		var body httpadapter.StatsRequest
+		if err := bind(req.Payload(), &body); err != nil {
+			return err
+		}
+		return t.svc.RecordStats(ctx, id, body.SentAt, body.UploadTime)
+	case "GET stats":
+		_, err := t.svc.GetStats(ctx, id)
+		return err
+	}
+	return coreerrors.New(coreerrors.KindNotFound, "route not replayable in-process: "+req.Method+" "+path)
+}
+
+// bind decodes a JSON body and checks it against the same binding tags as
+// the HTTP adapter, so that both targets reject the same payloads.
+func bind(payload []byte, body any) error {
+	if err := json.Unmarshal(payload, body); err != nil {
+		return coreerrors.New(coreerrors.KindValidation, "invalid payload: "+err.Error())
+	}
+	if err := binding.Validator.ValidateStruct(body); err != nil {
+		return coreerrors.New(coreerrors.KindValidation, "invalid payload: "+err.Error())
+	}
+	return nil
+}
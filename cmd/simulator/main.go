@@ -0,0 +1,85 @@
+// Command simulator sends generated heartbeats and upload stats for the
+// devices in devices.csv to a running server, then checks the uptime and
+// average upload time it reports against independently computed values.
+//
+//	simulator -server http://localhost:8080
+//
+// It exits with status 1 if any device fails.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/domain"
+	"safelyyou/pkg/simulator"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	cfg := simulator.DefaultConfig()
+	defaultServer := "http://localhost:8080"
+	if port := os.Getenv("PORT"); port != "" {
+		defaultServer = "http://localhost:" + port
+	}
+
+	fs := flag.NewFlagSet("simulator", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", defaultServer, "base URL of the server (default from $PORT)")
+	token := fs.String("token", os.Getenv("API_TOKEN"), "bearer token (default $API_TOKEN)")
+	devicesCSV := fs.String("devices", "devices.csv", "CSV of the devices to simulate")
+	fs.IntVar(&cfg.Minutes, "minutes", cfg.Minutes, "minutes of heartbeats per device")
+	fs.IntVar(&cfg.Uploads, "uploads", cfg.Uploads, "upload stats per device")
+	fs.Float64Var(&cfg.OutageChance, "outage-chance", cfg.OutageChance, "probability that an outage starts at any minute")
+	fs.IntVar(&cfg.MaxOutage, "max-outage", cfg.MaxOutage, "longest outage, in minutes")
+	fs.DurationVar(&cfg.MeanUpload, "mean-upload", cfg.MeanUpload, "mean upload duration")
+	fs.Uint64Var(&cfg.Seed, "seed", cfg.Seed, "random seed; the same seed replays the same traffic")
+	concurrency := fs.Int("concurrency", 8, "devices simulated at once")
+	retries := fs.Int("retries", 30, "retries of a throttled (429) request")
+	timeout := fs.Duration("timeout", 10*time.Minute, "how long the simulation may take")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if cfg.Minutes < 1 || cfg.Uploads < 0 || cfg.MeanUpload <= time.Second {
+		fmt.Fprintln(stderr, "simulator: -minutes must be >= 1, -uploads >= 0 and -mean-upload > 1s")
+		return 1
+	}
+
+	repo := memory.NewDeviceRepository()
+	if err := repo.LoadFromCSV(*devicesCSV); err != nil {
+		fmt.Fprintln(stderr, "simulator: load devices:", err)
+		return 1
+	}
+	devices := repo.IDs(domain.DefaultTenant)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	fmt.Fprintf(stderr, "simulating %d devices against %s\n", len(devices), *server)
+	client := &simulator.Client{BaseURL: *server, Token: *token, Concurrency: *concurrency, MaxRetries: *retries}
+	report := client.Run(ctx, simulator.NewPlan(cfg, devices))
+	if err := report.WriteText(stdout); err != nil {
+		fmt.Fprintln(stderr, "simulator:", err)
+		return 1
+	}
+	if !report.Passed() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/logging"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// BearerAuth returns interceptors requiring "authorization: Bearer <token>"
+// metadata on every call and scoping it to the tenant the token belongs
+// to, with the same tokens as the HTTP API: token acts as the default
+// tenant and tenantTokens maps further tokens to their tenant. No tokens
+// at all disables the check.
+func BearerAuth(token string, tenantTokens map[string]string) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	tokens := ports.NewTenantTokens(token, tenantTokens)
+	unary := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, tokens)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), tokens)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &tenantStream{ServerStream: ss, ctx: ctx})
+	}
+	return unary, stream
+}
+
+// authenticate returns ctx scoped to the caller's tenant, or an
+// Unauthenticated status.
+func authenticate(ctx context.Context, tokens ports.TenantTokens) (context.Context, error) {
+	if !tokens.Enabled() {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		got, ok := strings.CutPrefix(v, "Bearer ")
+		if !ok {
+			continue
+		}
+		if tenant, ok := tokens.Tenant(got); ok {
+			ctx = ports.WithTenant(ctx, tenant)
+			return logging.WithAttrs(ctx, slog.String("tenant", tenant)), nil
+		}
+	}
+	return nil, toStatus(coreerrors.ErrUnauthorized)
+}
+
+// tenantStream carries the authenticated context into a stream handler.
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tenantStream) Context() context.Context { return s.ctx }
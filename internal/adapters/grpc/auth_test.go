@@ -0,0 +1,97 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	fleetv1 "safelyyou/api/fleet/v1"
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/domain"
+)
+
+const acmeDeviceID = "b4-45-52-a2-f1-3c"
+
+func newAuthClient(t *testing.T) fleetv1.DeviceServiceClient {
+	t.Helper()
+	repo := memory.NewDeviceRepository()
+	acme := domain.NewDeviceStats(acmeDeviceID)
+	acme.Tenant = "acme"
+	for _, d := range []*domain.DeviceStats{domain.NewDeviceStats(knownDeviceID), acme} {
+		if err := repo.Add(context.Background(), d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unary, stream := BearerAuth("operator", map[string]string{"acme-token": "acme"})
+	return startServer(t, repo, grpc.ChainUnaryInterceptor(unary), grpc.ChainStreamInterceptor(stream))
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestBearerAuth_RejectsMissingAndWrongTokens(t *testing.T) {
+	client := newAuthClient(t)
+	req := &fleetv1.GetStatsRequest{DeviceId: knownDeviceID}
+
+	for name, ctx := range map[string]context.Context{
+		"missing": context.Background(),
+		"wrong":   withToken("guess"),
+		"scheme":  metadata.AppendToOutgoingContext(context.Background(), "authorization", "operator"),
+	} {
+		if _, err := client.GetStats(ctx, req); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s token: expected Unauthenticated, got %v", name, err)
+		}
+		stream, err := client.Ingest(ctx)
+		if err == nil {
+			_, err = stream.CloseAndRecv()
+		}
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s token: expected Unauthenticated on Ingest, got %v", name, err)
+		}
+	}
+
+	if _, err := client.GetStats(withToken("operator"), req); err != nil {
+		t.Fatalf("expected the operator token to be accepted, got %v", err)
+	}
+}
+
+func TestBearerAuth_ScopesCallsToTheTokenTenant(t *testing.T) {
+	client := newAuthClient(t)
+	acme := withToken("acme-token")
+
+	if _, err := client.GetStats(acme, &fleetv1.GetStatsRequest{DeviceId: knownDeviceID}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected the default tenant's device to be unknown to acme, got %v", err)
+	}
+	if _, err := client.RecordHeartbeat(acme, &fleetv1.RecordHeartbeatRequest{DeviceId: acmeDeviceID, SentAt: timestamppb.Now()}); err != nil {
+		t.Fatalf("expected acme to reach its own device, got %v", err)
+	}
+	if _, err := client.GetStats(withToken("operator"), &fleetv1.GetStatsRequest{DeviceId: acmeDeviceID}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected acme's device to be unknown to the default tenant, got %v", err)
+	}
+
+	stream, err := client.Ingest(acme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{knownDeviceID, acmeDeviceID} {
+		rec := &fleetv1.IngestRequest{Record: &fleetv1.IngestRequest_Heartbeat{Heartbeat: &fleetv1.RecordHeartbeatRequest{
+			DeviceId: id, SentAt: timestamppb.Now(),
+		}}}
+		if err := stream.Send(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetAccepted() != 1 || len(resp.GetErrors()) != 1 || resp.GetErrors()[0].GetCode() != codes.NotFound.String() {
+		t.Fatalf("expected only acme's own device accepted on Ingest, got %+v", resp)
+	}
+}
@@ -0,0 +1,152 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	fleetv1 "safelyyou/api/fleet/v1"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/utils"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxIngestErrors caps the per-record errors returned by Ingest.
+const maxIngestErrors = 100
+
+// Server exposes ports.DeviceService over gRPC.
+type Server struct {
+	fleetv1.UnimplementedDeviceServiceServer
+	deviceSvc ports.DeviceService
+}
+
+// NewServer constructs a gRPC server that depends on the DeviceService interface.
+func NewServer(svc ports.DeviceService) *Server {
+	return &Server{deviceSvc: svc}
+}
+
+// Register attaches the device service to a gRPC server.
+func (s *Server) Register(gs *grpc.Server) {
+	fleetv1.RegisterDeviceServiceServer(gs, s)
+}
+
+func (s *Server) RecordHeartbeat(ctx context.Context, req *fleetv1.RecordHeartbeatRequest) (*fleetv1.RecordHeartbeatResponse, error) {
+	if err := s.recordHeartbeat(ctx, req); err != nil {
+		return nil, err
+	}
+	return &fleetv1.RecordHeartbeatResponse{}, nil
+}
+
+func (s *Server) RecordStats(ctx context.Context, req *fleetv1.RecordStatsRequest) (*fleetv1.RecordStatsResponse, error) {
+	if err := s.recordStats(ctx, req); err != nil {
+		return nil, err
+	}
+	return &fleetv1.RecordStatsResponse{}, nil
+}
+
+func (s *Server) GetStats(ctx context.Context, req *fleetv1.GetStatsRequest) (*fleetv1.GetStatsResponse, error) {
+	if !utils.IsId(req.GetDeviceId()) {
+		return nil, status.Error(codes.InvalidArgument, "invalid device ID")
+	}
+
+	stats, err := s.deviceSvc.GetStats(ctx, req.GetDeviceId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &fleetv1.GetStatsResponse{
+		Uptime:        stats.Uptime,
+		AvgUploadTime: stats.AvgUploadTime,
+	}, nil
+}
+
+// Ingest applies each streamed record in order. Rejected records do not
+// abort the stream; they are counted and reported in the final response.
+func (s *Server) Ingest(stream grpc.ClientStreamingServer[fleetv1.IngestRequest, fleetv1.IngestResponse]) error {
+	resp := &fleetv1.IngestResponse{}
+	for index := int64(0); ; index++ {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			return err
+		}
+
+		var deviceID string
+		switch rec := req.GetRecord().(type) {
+		case *fleetv1.IngestRequest_Heartbeat:
+			deviceID = rec.Heartbeat.GetDeviceId()
+			err = s.recordHeartbeat(stream.Context(), rec.Heartbeat)
+		case *fleetv1.IngestRequest_Stats:
+			deviceID = rec.Stats.GetDeviceId()
+			err = s.recordStats(stream.Context(), rec.Stats)
+		default:
+			err = status.Error(codes.InvalidArgument, "empty record")
+		}
+
+		if err == nil {
+			resp.Accepted++
+			continue
+		}
+		resp.Rejected++
+		if len(resp.Errors) < maxIngestErrors {
+			st := status.Convert(err)
+			resp.Errors = append(resp.Errors, &fleetv1.IngestError{
+				Index:    index,
+				DeviceId: deviceID,
+				Code:     st.Code().String(),
+				Message:  st.Message(),
+			})
+		}
+	}
+}
+
+func (s *Server) recordHeartbeat(ctx context.Context, req *fleetv1.RecordHeartbeatRequest) error {
+	if !utils.IsId(req.GetDeviceId()) {
+		return status.Error(codes.InvalidArgument, "invalid device ID")
+	}
+	if req.GetSentAt() == nil {
+		return status.Error(codes.InvalidArgument, "sent_at is required")
+	}
+	if err := s.deviceSvc.RecordHeartbeat(ctx, req.GetDeviceId(), req.GetSentAt().AsTime()); err != nil {
+		return toStatus(err)
+	}
+	return nil
+}
+
+func (s *Server) recordStats(ctx context.Context, req *fleetv1.RecordStatsRequest) error {
+	if !utils.IsId(req.GetDeviceId()) {
+		return status.Error(codes.InvalidArgument, "invalid device ID")
+	}
+	var sentAt time.Time
+	if req.GetSentAt() != nil {
+		sentAt = req.GetSentAt().AsTime()
+	}
+	if err := s.deviceSvc.RecordStats(ctx, req.GetDeviceId(), sentAt, req.GetUploadTime()); err != nil {
+		return toStatus(err)
+	}
+	return nil
+}
+
+// kindCode maps core error kinds to gRPC codes; anything else is Internal.
+var kindCode = map[coreerrors.Kind]codes.Code{
+	coreerrors.KindValidation:   codes.InvalidArgument,
+	coreerrors.KindNotFound:     codes.NotFound,
+	coreerrors.KindConflict:     codes.AlreadyExists,
+	coreerrors.KindRateLimited:  codes.ResourceExhausted,
+	coreerrors.KindUnauthorized: codes.Unauthenticated,
+	coreerrors.KindUnavailable:  codes.Unavailable,
+	coreerrors.KindForbidden:    codes.PermissionDenied,
+}
+
+// toStatus maps core errors to gRPC status errors.
+func toStatus(err error) error {
+	if code, ok := kindCode[coreerrors.KindOf(err)]; ok {
+		return status.Error(code, err.Error())
+	}
+	return status.Error(codes.Internal, "internal error")
+}
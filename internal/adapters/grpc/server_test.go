@@ -0,0 +1,156 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	fleetv1 "safelyyou/api/fleet/v1"
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/domain"
+	"safelyyou/internal/core/services"
+)
+
+const knownDeviceID = "60-6b-44-84-dc-64"
+
+// newTestClient starts the adapter on an in-memory listener backed by the
+// real service and memory repository.
+func newTestClient(t *testing.T) fleetv1.DeviceServiceClient {
+	t.Helper()
+
+	repo := memory.NewDeviceRepository()
+	if err := repo.Add(context.Background(), domain.NewDeviceStats(knownDeviceID)); err != nil {
+		t.Fatalf("failed to seed device: %v", err)
+	}
+	return startServer(t, repo)
+}
+
+// startServer serves repo's devices on an in-memory listener with opts.
+func startServer(t *testing.T, repo *memory.DeviceRepository, opts ...grpc.ServerOption) fleetv1.DeviceServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer(opts...)
+	NewServer(services.NewDeviceService(repo)).Register(gs)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return fleetv1.NewDeviceServiceClient(conn)
+}
+
+func TestRecordAndGetStats(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	t0 := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+
+	for _, ts := range []time.Time{t0, t0.Add(30 * time.Minute), t0.Add(60 * time.Minute)} {
+		_, err := client.RecordHeartbeat(ctx, &fleetv1.RecordHeartbeatRequest{
+			DeviceId: knownDeviceID,
+			SentAt:   timestamppb.New(ts),
+		})
+		if err != nil {
+			t.Fatalf("RecordHeartbeat returned error: %v", err)
+		}
+	}
+	_, err := client.RecordStats(ctx, &fleetv1.RecordStatsRequest{
+		DeviceId:   knownDeviceID,
+		SentAt:     timestamppb.New(t0),
+		UploadTime: int64(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("RecordStats returned error: %v", err)
+	}
+
+	resp, err := client.GetStats(ctx, &fleetv1.GetStatsRequest{DeviceId: knownDeviceID})
+	if err != nil {
+		t.Fatalf("GetStats returned error: %v", err)
+	}
+	if resp.GetUptime() != 5 || resp.GetAvgUploadTime() != "1m0s" {
+		t.Fatalf("unexpected stats: uptime=%f avg=%s", resp.GetUptime(), resp.GetAvgUploadTime())
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.GetStats(ctx, &fleetv1.GetStatsRequest{DeviceId: "aa-bb-cc-11-22-33"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound for unknown device, got %v", err)
+	}
+
+	_, err = client.RecordHeartbeat(ctx, &fleetv1.RecordHeartbeatRequest{DeviceId: "bad-id", SentAt: timestamppb.Now()})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for bad id, got %v", err)
+	}
+
+	_, err = client.RecordHeartbeat(ctx, &fleetv1.RecordHeartbeatRequest{DeviceId: knownDeviceID})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for missing sent_at, got %v", err)
+	}
+
+	_, err = client.RecordStats(ctx, &fleetv1.RecordStatsRequest{DeviceId: knownDeviceID, UploadTime: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for negative upload_time, got %v", err)
+	}
+}
+
+func TestIngest_CountsAcceptedAndRejected(t *testing.T) {
+	client := newTestClient(t)
+
+	stream, err := client.Ingest(context.Background())
+	if err != nil {
+		t.Fatalf("Ingest returned error: %v", err)
+	}
+
+	records := []*fleetv1.IngestRequest{
+		{Record: &fleetv1.IngestRequest_Heartbeat{Heartbeat: &fleetv1.RecordHeartbeatRequest{
+			DeviceId: knownDeviceID, SentAt: timestamppb.Now(),
+		}}},
+		{Record: &fleetv1.IngestRequest_Stats{Stats: &fleetv1.RecordStatsRequest{
+			DeviceId: knownDeviceID, UploadTime: 1000,
+		}}},
+		{Record: &fleetv1.IngestRequest_Heartbeat{Heartbeat: &fleetv1.RecordHeartbeatRequest{
+			DeviceId: "aa-bb-cc-11-22-33", SentAt: timestamppb.Now(),
+		}}},
+		{},
+	}
+	for _, rec := range records {
+		if err := stream.Send(rec); err != nil {
+			t.Fatalf("Send returned error: %v", err)
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv returned error: %v", err)
+	}
+	if resp.GetAccepted() != 2 || resp.GetRejected() != 2 {
+		t.Fatalf("expected 2 accepted / 2 rejected, got %d / %d", resp.GetAccepted(), resp.GetRejected())
+	}
+	if len(resp.GetErrors()) != 2 {
+		t.Fatalf("expected 2 errors, got %d", len(resp.GetErrors()))
+	}
+	if e := resp.GetErrors()[0]; e.GetIndex() != 2 || e.GetCode() != codes.NotFound.String() {
+		t.Fatalf("unexpected first error: %+v", e)
+	}
+}
@@ -0,0 +1,244 @@
+package http
+
+import (
+	"net/http"
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AlertHandler struct {
+	alertSvc ports.AlertService
+}
+
+// NewAlertHandler constructs a handler that depends on the AlertService interface.
+func NewAlertHandler(svc ports.AlertService) *AlertHandler {
+	return &AlertHandler{alertSvc: svc}
+}
+
+// PostAlertRule godoc
+// @Summary Create an alert rule
+// @Description Create a rule such as "uptime_24h < 95", "p95_upload > 5m" or "no_heartbeat_for > 10m".
+// @Description Scope is fleet (default), site or device; site and device scopes require a target.
+// @Description A tenant limits the rule to its devices; site and device scopes without one refer to the default tenant.
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param request body AlertRuleRequest true "Alert rule"
+// @Success 201 {object} AlertRuleResponse
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /api/v1/alerts/rules [post]
+func (h *AlertHandler) PostAlertRule(c *gin.Context) {
+	var req AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+
+	var forDur time.Duration
+	if req.For != "" {
+		d, err := time.ParseDuration(req.For)
+		if err != nil {
+			respondError(c, coreerrors.Invalid("for", "must be a duration"))
+			return
+		}
+		forDur = d
+	}
+
+	rule, err := h.alertSvc.CreateRule(ports.AlertRuleInput{
+		Name:     req.Name,
+		Expr:     req.Expr,
+		Scope:    domain.AlertScope(req.Scope),
+		Tenant:   req.Tenant,
+		Target:   req.Target,
+		For:      forDur,
+		Severity: req.Severity,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toAlertRuleResponse(*rule))
+}
+
+// ListAlertRules godoc
+// @Summary List alert rules
+// @Tags alerts
+// @Produce json
+// @Success 200 {array} AlertRuleResponse
+// @Router /api/v1/alerts/rules [get]
+func (h *AlertHandler) ListAlertRules(c *gin.Context) {
+	rules := h.alertSvc.ListRules()
+	resp := make([]AlertRuleResponse, 0, len(rules))
+	for _, r := range rules {
+		resp = append(resp, toAlertRuleResponse(r))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteAlertRule godoc
+// @Summary Remove an alert rule and its alerts
+// @Tags alerts
+// @Param rule_id path string true "Rule ID"
+// @Success 204 "no content"
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /api/v1/alerts/rules/{rule_id} [delete]
+func (h *AlertHandler) DeleteAlertRule(c *gin.Context) {
+	if err := h.alertSvc.DeleteRule(c.Param("rule_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListAlerts godoc
+// @Summary List alerts
+// @Description Return alert instances, one per rule and device.
+// @Tags alerts
+// @Produce json
+// @Param state query string false "pending, firing or resolved"
+// @Success 200 {array} AlertResponse
+// @Failure 400 {object} Problem
+// @Router /api/v1/alerts [get]
+func (h *AlertHandler) ListAlerts(c *gin.Context) {
+	state := domain.AlertState(c.Query("state"))
+	switch state {
+	case "", domain.AlertPending, domain.AlertFiring, domain.AlertResolved:
+	default:
+		respondError(c, coreerrors.Invalid("state", "must be pending, firing or resolved"))
+		return
+	}
+
+	alerts := h.alertSvc.ListAlerts(state)
+	resp := make([]AlertResponse, 0, len(alerts))
+	for _, a := range alerts {
+		resp = append(resp, toAlertResponse(a))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// PostSilence godoc
+// @Summary Create a silence
+// @Description Mute notifications for alerts matching tenant, rule_id and/or device_id until ends_at.
+// @Description A device_id without a tenant refers to the default tenant's device.
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param request body SilenceRequest true "Silence"
+// @Success 201 {object} SilenceResponse
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /api/v1/alerts/silences [post]
+func (h *AlertHandler) PostSilence(c *gin.Context) {
+	var req SilenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+
+	s, err := h.alertSvc.CreateSilence(domain.Silence{
+		Tenant:   req.Tenant,
+		RuleID:   req.RuleID,
+		DeviceID: req.DeviceID,
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+		Comment:  req.Comment,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, toSilenceResponse(*s))
+}
+
+// ListSilences godoc
+// @Summary List silences
+// @Tags alerts
+// @Produce json
+// @Success 200 {array} SilenceResponse
+// @Router /api/v1/alerts/silences [get]
+func (h *AlertHandler) ListSilences(c *gin.Context) {
+	silences := h.alertSvc.ListSilences()
+	resp := make([]SilenceResponse, 0, len(silences))
+	for _, s := range silences {
+		resp = append(resp, toSilenceResponse(s))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteSilence godoc
+// @Summary Remove a silence
+// @Tags alerts
+// @Param silence_id path string true "Silence ID"
+// @Success 204 "no content"
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /api/v1/alerts/silences/{silence_id} [delete]
+func (h *AlertHandler) DeleteSilence(c *gin.Context) {
+	if err := h.alertSvc.DeleteSilence(c.Param("silence_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func toAlertRuleResponse(r domain.AlertRule) AlertRuleResponse {
+	return AlertRuleResponse{
+		ID:        r.ID,
+		Name:      r.Name,
+		Expr:      r.Expr,
+		Metric:    string(r.Metric),
+		Op:        r.Op,
+		Threshold: r.Threshold,
+		Scope:     string(r.Scope),
+		Tenant:    r.Tenant,
+		Target:    r.Target,
+		For:       r.For.String(),
+		Severity:  r.Severity,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func toAlertResponse(a domain.Alert) AlertResponse {
+	resp := AlertResponse{
+		ID:              a.ID,
+		RuleID:          a.RuleID,
+		RuleName:        a.RuleName,
+		Tenant:          a.Tenant,
+		DeviceID:        a.DeviceID,
+		Severity:        a.Severity,
+		State:           string(a.State),
+		Value:           a.Value,
+		Silenced:        a.Silenced,
+		StartsAt:        a.StartsAt,
+		LastEvaluatedAt: a.LastEvaluatedAt,
+	}
+	if !a.FiredAt.IsZero() {
+		fired := a.FiredAt
+		resp.FiredAt = &fired
+	}
+	if !a.ResolvedAt.IsZero() {
+		resolved := a.ResolvedAt
+		resp.ResolvedAt = &resolved
+	}
+	return resp
+}
+
+func toSilenceResponse(s domain.Silence) SilenceResponse {
+	return SilenceResponse{
+		ID:        s.ID,
+		Tenant:    s.Tenant,
+		RuleID:    s.RuleID,
+		DeviceID:  s.DeviceID,
+		StartsAt:  s.StartsAt,
+		EndsAt:    s.EndsAt,
+		Comment:   s.Comment,
+		CreatedAt: s.CreatedAt,
+	}
+}
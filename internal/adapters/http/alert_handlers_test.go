@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/ports"
+	"safelyyou/internal/core/services"
+)
+
+func newAlertServer(t *testing.T, devices *testDeviceService) (*gin.Engine, *services.AlertServiceImpl) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	svc := services.NewAlertService(devices, memory.NewAlertRepository(), nil)
+	r := gin.New()
+	RegisterAlertRoutes(r, svc)
+	return r, svc
+}
+
+func TestPostAlertRule_CreatesRule(t *testing.T) {
+	r, _ := newAlertServer(t, &testDeviceService{})
+
+	body := []byte(`{"name":"slow uploads","expr":"p95_upload > 5m","for":"10m"}`)
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/alerts/rules", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+	var resp AlertRuleResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Metric != "p95_upload" || resp.Threshold != 300 || resp.For != "10m0s" || resp.Scope != "fleet" {
+		t.Fatalf("unexpected rule: %+v", resp)
+	}
+}
+
+func TestPostAlertRule_InvalidExprReturns400(t *testing.T) {
+	r, _ := newAlertServer(t, &testDeviceService{})
+
+	body := []byte(`{"expr":"temperature > 80"}`)
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/alerts/rules", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestListAlerts_ReturnsFiringAlerts(t *testing.T) {
+	devices := &testDeviceService{
+		devices:        []string{validDeviceID},
+		getStatsResult: &ports.Stats{HeartbeatCount: 5, LastSeenAt: time.Now().Add(-time.Hour)},
+	}
+	r, svc := newAlertServer(t, devices)
+
+	if _, err := svc.CreateRule(ports.AlertRuleInput{Expr: "no_heartbeat_for > 10m"}); err != nil {
+		t.Fatalf("CreateRule returned error: %v", err)
+	}
+	svc.Evaluate(context.Background())
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/alerts?state=firing", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp []AlertResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp) != 1 || resp[0].DeviceID != validDeviceID || resp[0].FiredAt == nil {
+		t.Fatalf("unexpected alerts: %+v", resp)
+	}
+}
+
+func TestListAlerts_InvalidStateReturns400(t *testing.T) {
+	r, _ := newAlertServer(t, &testDeviceService{})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/alerts?state=bogus", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"safelyyou/internal/core/domain"
+	"safelyyou/internal/core/ports"
+)
+
+func TestBearerAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(BearerAuth("s3cret", nil))
+	r.GET("/api/v1/ping", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/docs/index.html", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		name, path, auth string
+		want             int
+	}{
+		{"missing token", "/api/v1/ping", "", http.StatusUnauthorized},
+		{"wrong token", "/api/v1/ping", "Bearer nope", http.StatusUnauthorized},
+		{"wrong scheme", "/api/v1/ping", "Basic s3cret", http.StatusUnauthorized},
+		{"valid token", "/api/v1/ping", "Bearer s3cret", http.StatusNoContent},
+		{"docs stay open", "/docs/index.html", "", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, w.Code)
+		}
+		if tc.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expected WWW-Authenticate header", tc.name)
+		}
+	}
+}
+
+func TestBearerAuth_TenantTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(BearerAuth("s3cret", map[string]string{"acme-token": "acme"}))
+	r.GET("/api/v1/whoami", func(c *gin.Context) { c.String(http.StatusOK, ports.TenantFrom(c.Request.Context())) })
+	r.GET("/api/v1/operator", DefaultTenantOnly(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	cases := []struct {
+		path, token string
+		want        int
+		body        string
+	}{
+		{"/api/v1/whoami", "s3cret", http.StatusOK, domain.DefaultTenant},
+		{"/api/v1/whoami", "acme-token", http.StatusOK, "acme"},
+		{"/api/v1/whoami", "globex-token", http.StatusUnauthorized, ""},
+		{"/api/v1/operator", "s3cret", http.StatusNoContent, ""},
+		{"/api/v1/operator", "acme-token", http.StatusForbidden, ""},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want || (tc.body != "" && w.Body.String() != tc.body) {
+			t.Errorf("%s as %s: expected %d %q, got %d %q", tc.path, tc.token, tc.want, tc.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestBearerAuth_EmptyTokenDisablesCheck(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(BearerAuth("", nil))
+	r.GET("/api/v1/ping", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/ping", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 without configured token, got %d", w.Code)
+	}
+}
+
+func TestRequestSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestSignature("s3cret"))
+	r.POST("/api/v1/ping", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+	r.GET("/docs/index.html", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	body := `{"sent_at":"2025-11-09T10:00:00Z"}`
+	cases := []struct {
+		name, path, ts, sig string
+		want                int
+	}{
+		{"valid", "/api/v1/ping?x=1", now, SignRequest("s3cret", now, "POST", "/api/v1/ping?x=1", []byte(body)), http.StatusOK},
+		{"missing", "/api/v1/ping", "", "", http.StatusUnauthorized},
+		{"other path", "/api/v1/ping", now, SignRequest("s3cret", now, "POST", "/api/v1/other", []byte(body)), http.StatusUnauthorized},
+		{"wrong secret", "/api/v1/ping", now, SignRequest("nope", now, "POST", "/api/v1/ping", []byte(body)), http.StatusUnauthorized},
+		{"stale", "/api/v1/ping", stale, SignRequest("s3cret", stale, "POST", "/api/v1/ping", []byte(body)), http.StatusUnauthorized},
+		{"docs stay open", "/docs/index.html", "", "", http.StatusOK},
+	}
+	for _, tc := range cases {
+		method := http.MethodPost
+		if tc.path == "/docs/index.html" {
+			method = http.MethodGet
+		}
+		req, _ := http.NewRequest(method, tc.path, strings.NewReader(body))
+		req.Header.Set(HeaderSignatureTimestamp, tc.ts)
+		req.Header.Set(HeaderSignature, tc.sig)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, w.Code)
+		}
+		if tc.want == http.StatusOK && method == http.MethodPost && w.Body.String() != body {
+			t.Errorf("%s: body was not passed on, got %q", tc.name, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+)
+
+// Alternative wire encodings for the device endpoints, negotiated with
+// Content-Type (requests) and Accept (responses). JSON stays the default.
+const (
+	mimeProtobuf  = binding.MIMEPROTOBUF // application/x-protobuf
+	mimeProtobuf2 = "application/protobuf"
+	mimeMsgPack   = binding.MIMEMSGPACK  // application/x-msgpack
+	mimeMsgPack2  = binding.MIMEMSGPACK2 // application/msgpack
+)
+
+// msgpackHandle writes time.Time as the standard msgpack timestamp
+// extension (-1) so non-Go clients can decode it.
+var msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+
+// protoResponse is implemented by response DTOs with a protobuf
+// counterpart in api/fleet/v1.
+type protoResponse interface {
+	toProto() proto.Message
+}
+
+// protoRequest is implemented by request DTOs with a protobuf counterpart
+// in api/fleet/v1.
+type protoRequest interface {
+	emptyProto() proto.Message
+	fromProto(m proto.Message)
+}
+
+// decodeBody decodes the request body according to its Content-Type and runs
+// the DTO's binding validation, whatever the encoding. Bodies larger than
+// DefaultMaxDecompressedBytes fail with *http.MaxBytesError (413).
+func decodeBody(c *gin.Context, obj any) error {
+	if c.Request.Body != nil {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, DefaultMaxDecompressedBytes)
+	}
+	switch c.ContentType() {
+	case mimeProtobuf, mimeProtobuf2:
+		req, ok := obj.(protoRequest)
+		if !ok {
+			return errors.New("protobuf is not supported for this endpoint")
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+		m := req.emptyProto()
+		if err := proto.Unmarshal(body, m); err != nil {
+			return err
+		}
+		req.fromProto(m)
+		return binding.Validator.ValidateStruct(obj)
+	case mimeMsgPack, mimeMsgPack2:
+		return c.ShouldBindWith(obj, binding.MsgPack)
+	default:
+		return c.ShouldBindJSON(obj)
+	}
+}
+
+// respond writes obj in the format negotiated from the Accept header,
+// falling back to JSON.
+func respond(c *gin.Context, code int, obj any) {
+	switch format := c.NegotiateFormat(binding.MIMEJSON, mimeProtobuf, mimeProtobuf2, mimeMsgPack, mimeMsgPack2); format {
+	case mimeProtobuf, mimeProtobuf2:
+		if m, ok := obj.(protoResponse); ok {
+			c.Render(code, protoRender{contentType: format, msg: m.toProto()})
+			return
+		}
+	case mimeMsgPack, mimeMsgPack2:
+		c.Render(code, msgpackRender{contentType: format, data: obj})
+		return
+	}
+	c.JSON(code, obj)
+}
+
+type protoRender struct {
+	contentType string
+	msg         proto.Message
+}
+
+func (r protoRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	b, err := proto.Marshal(r.msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (r protoRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", r.contentType)
+}
+
+type msgpackRender struct {
+	contentType string
+	data        any
+}
+
+func (r msgpackRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return codec.NewEncoder(w, msgpackHandle).Encode(r.data)
+}
+
+func (r msgpackRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", r.contentType)
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	fleetv1 "safelyyou/api/fleet/v1"
+	"safelyyou/internal/core/ports"
+)
+
+func newCodecRouter(svc *testDeviceService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(svc)
+	r := gin.New()
+	r.POST("/api/v1/devices/:device_id/heartbeat", h.PostHeartbeat)
+	r.POST("/api/v1/devices/:device_id/stats", h.PostStats)
+	r.GET("/api/v1/devices/:device_id/stats", h.GetStats)
+	return r
+}
+
+func TestPostHeartbeat_Protobuf(t *testing.T) {
+	svc := &testDeviceService{}
+	r := newCodecRouter(svc)
+
+	sentAt := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	body, _ := proto.Marshal(&fleetv1.RecordHeartbeatRequest{SentAt: timestamppb.New(sentAt)})
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices/"+validDeviceID+"/heartbeat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if !svc.lastHeartbeatSentAt.Equal(sentAt) {
+		t.Fatalf("expected sent_at=%v, got %v", sentAt, svc.lastHeartbeatSentAt)
+	}
+}
+
+func TestPostHeartbeat_ProtobufMissingSentAtReturns400(t *testing.T) {
+	r := newCodecRouter(&testDeviceService{})
+
+	body, _ := proto.Marshal(&fleetv1.RecordHeartbeatRequest{})
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices/"+validDeviceID+"/heartbeat", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Accept", "application/x-protobuf")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	var resp fleetv1.ErrorResponse
+	if err := proto.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.GetMsg() == "" {
+		t.Fatalf("expected protobuf error body, got %q (err=%v)", w.Body.String(), err)
+	}
+}
+
+func TestPostStats_MessagePack(t *testing.T) {
+	svc := &testDeviceService{}
+	r := newCodecRouter(svc)
+
+	sentAt := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	var body []byte
+	if err := codec.NewEncoderBytes(&body, msgpackHandle).Encode(StatsRequest{SentAt: sentAt, UploadTime: 30000000000}); err != nil {
+		t.Fatalf("failed to encode msgpack: %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices/"+validDeviceID+"/stats", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/msgpack")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if svc.lastUploadTimeNs != 30000000000 || !svc.lastStatsSentAt.Equal(sentAt) {
+		t.Fatalf("unexpected stats recorded: %d at %v", svc.lastUploadTimeNs, svc.lastStatsSentAt)
+	}
+}
+
+func TestGetStats_NegotiatesResponseEncoding(t *testing.T) {
+	svc := &testDeviceService{
+		getStatsResult: &ports.Stats{Uptime: 98.75, AvgUploadTime: "3m17s"},
+	}
+	r := newCodecRouter(svc)
+
+	get := func(accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/devices/"+validDeviceID+"/stats", nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("application/x-protobuf")
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-protobuf" {
+		t.Fatalf("expected protobuf content type, got %q", ct)
+	}
+	var pb fleetv1.GetStatsResponse
+	if err := proto.Unmarshal(w.Body.Bytes(), &pb); err != nil {
+		t.Fatalf("failed to unmarshal protobuf: %v", err)
+	}
+	if pb.GetUptime() != 98.75 || pb.GetAvgUploadTime() != "3m17s" {
+		t.Fatalf("unexpected protobuf stats: %+v", &pb)
+	}
+
+	w = get("application/x-msgpack")
+	var mp StatsResponse
+	if err := codec.NewDecoderBytes(w.Body.Bytes(), msgpackHandle).Decode(&mp); err != nil {
+		t.Fatalf("failed to decode msgpack: %v", err)
+	}
+	if mp.Uptime != 98.75 || mp.AvgUploadTime != "3m17s" {
+		t.Fatalf("unexpected msgpack stats: %+v", mp)
+	}
+
+	w = get("")
+	if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Fatalf("expected JSON by default, got %q", ct)
+	}
+}
+
+func TestPostStats_OversizedBodyReturns413(t *testing.T) {
+	r := newCodecRouter(&testDeviceService{})
+
+	padding := bytes.Repeat([]byte("a"), DefaultMaxDecompressedBytes)
+	bodies := map[string][]byte{
+		"application/x-protobuf": append(padding, 0),
+		"application/json":       append(append([]byte(`{"pad":"`), padding...), `"}`...),
+	}
+	for contentType, body := range bodies {
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices/"+validDeviceID+"/stats", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("expected status 413 for %s, got %d, body=%s", contentType, w.Code, w.Body.String())
+		}
+	}
+}
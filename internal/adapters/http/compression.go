@@ -0,0 +1,213 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	coreerrors "safelyyou/internal/core/errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultMaxDecompressedBytes caps the size of a decompressed request body.
+const DefaultMaxDecompressedBytes = 10 << 20
+
+var (
+	gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+	zstdWriters = sync.Pool{New: func() any {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return w
+	}}
+)
+
+// Compression transparently decompresses gzip or zstd request bodies and
+// compresses responses for clients that accept it.
+//
+// Request bodies are decompressed up front and rejected with 413 once they
+// exceed maxDecompressed bytes, so a small compressed payload cannot expand
+// into an unbounded allocation (zip bomb). Unknown encodings get 415.
+func Compression(maxDecompressed int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !decompressRequest(c, maxDecompressed) {
+			return
+		}
+
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		c.Header("Vary", "Accept-Encoding")
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: c.Writer, encoding: encoding}
+		c.Writer = cw
+		defer cw.close()
+		c.Next()
+	}
+}
+
+// decompressRequest replaces a compressed body with its decoded bytes. It
+// returns false after aborting the request.
+func decompressRequest(c *gin.Context, limit int64) bool {
+	encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+	if encoding == "" || encoding == "identity" || c.Request.Body == nil {
+		return true
+	}
+
+	var (
+		dec io.Reader
+		err error
+	)
+	switch encoding {
+	case "gzip":
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(c.Request.Body); err == nil {
+			defer zr.Close()
+			dec = zr
+		}
+	case "zstd":
+		var zr *zstd.Decoder
+		if zr, err = zstd.NewReader(c.Request.Body, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(limit))); err == nil {
+			defer zr.Close()
+			dec = zr
+		}
+	default:
+		writeProblem(c, newProblem(c, http.StatusUnsupportedMediaType, "unsupported content encoding: "+encoding))
+		return false
+	}
+	if err != nil {
+		respondError(c, coreerrors.New(coreerrors.KindValidation, "invalid compressed body: "+err.Error()))
+		return false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(dec, limit+1))
+	if err != nil {
+		respondError(c, coreerrors.New(coreerrors.KindValidation, "invalid compressed body: "+err.Error()))
+		return false
+	}
+	if int64(len(body)) > limit {
+		writeProblem(c, newProblem(c, http.StatusRequestEntityTooLarge,
+			"decompressed body exceeds "+strconv.FormatInt(limit, 10)+" bytes"))
+		return false
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	c.Request.ContentLength = int64(len(body))
+	c.Request.Header.Del("Content-Encoding")
+	c.Request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return true
+}
+
+// negotiateEncoding picks zstd or gzip from an Accept-Encoding header,
+// preferring the higher q-value and zstd on ties. q=0 marks an encoding as
+// not acceptable.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "zstd" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == "zstd") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressWriter compresses the body on first write or flush. Responses
+// without a body (204, 304), and those whose headers went out before, are
+// left untouched.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	w        io.WriteCloser
+	// passthrough is set once the response is known to stay uncompressed.
+	passthrough bool
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.w == nil && !cw.passthrough {
+		cw.begin()
+	}
+	if cw.w == nil {
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.w.Write(b)
+}
+
+func (cw *compressWriter) WriteString(s string) (int, error) {
+	return cw.Write([]byte(s))
+}
+
+// begin starts compressing if the headers can still announce it, and
+// otherwise leaves the response uncompressed.
+func (cw *compressWriter) begin() {
+	cw.passthrough = true
+	switch status := cw.Status(); {
+	case cw.Written(), status < http.StatusOK, status == http.StatusNoContent, status == http.StatusNotModified:
+		return
+	}
+	cw.start()
+}
+
+func (cw *compressWriter) start() {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" {
+		return
+	}
+	h.Set("Content-Encoding", cw.encoding)
+	h.Del("Content-Length")
+
+	switch cw.encoding {
+	case "gzip":
+		gz := gzipWriters.Get().(*gzip.Writer)
+		gz.Reset(cw.ResponseWriter)
+		cw.w = gz
+	case "zstd":
+		zw := zstdWriters.Get().(*zstd.Encoder)
+		zw.Reset(cw.ResponseWriter)
+		cw.w = zw
+	}
+}
+
+// Flush pushes buffered compressed data to the client. Flushing before the
+// first write sends the headers, so compression starts there.
+func (cw *compressWriter) Flush() {
+	if cw.w == nil && !cw.passthrough {
+		cw.begin()
+	}
+	if f, ok := cw.w.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	cw.ResponseWriter.Flush()
+}
+
+func (cw *compressWriter) close() {
+	if cw.w == nil {
+		return
+	}
+	_ = cw.w.Close()
+	switch w := cw.w.(type) {
+	case *gzip.Writer:
+		gzipWriters.Put(w)
+	case *zstd.Encoder:
+		zstdWriters.Put(w)
+	}
+	cw.w = nil
+}
@@ -0,0 +1,225 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+
+	"safelyyou/internal/core/ports"
+)
+
+func newCompressionRouter(svc *testDeviceService, limit int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(svc)
+	r := gin.New()
+	r.Use(Compression(limit))
+	r.POST("/api/v1/devices/:device_id/stats", h.PostStats)
+	r.GET("/api/v1/devices/:device_id/stats", h.GetStats)
+	return r
+}
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		t.Fatalf("gzip write failed: %v", err)
+	}
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+func zstdBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+	zw, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("zstd writer failed: %v", err)
+	}
+	defer zw.Close()
+	return zw.EncodeAll(b, nil)
+}
+
+func TestCompression_DecompressesGzipAndZstdBodies(t *testing.T) {
+	body := []byte(`{"sent_at":"2025-11-09T10:00:00Z","upload_time":30000000000}`)
+
+	for _, tc := range []struct {
+		encoding string
+		payload  []byte
+	}{
+		{"gzip", gzipBytes(t, body)},
+		{"zstd", zstdBytes(t, body)},
+	} {
+		svc := &testDeviceService{}
+		r := newCompressionRouter(svc, DefaultMaxDecompressedBytes)
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices/"+validDeviceID+"/stats", bytes.NewReader(tc.payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", tc.encoding)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("%s: expected status 204, got %d, body=%s", tc.encoding, w.Code, w.Body.String())
+		}
+		if svc.lastUploadTimeNs != 30000000000 {
+			t.Fatalf("%s: expected upload_time to be decoded, got %d", tc.encoding, svc.lastUploadTimeNs)
+		}
+		if w.Header().Get("Content-Encoding") != "" {
+			t.Fatalf("%s: 204 response must not be encoded", tc.encoding)
+		}
+	}
+}
+
+func TestCompression_RejectsZipBomb(t *testing.T) {
+	r := newCompressionRouter(&testDeviceService{}, 1024)
+
+	// 1 MiB of zeros compresses to about a kilobyte.
+	bomb := gzipBytes(t, make([]byte, 1<<20))
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices/"+validDeviceID+"/stats", bytes.NewReader(bomb))
+	req.Header.Set("Content-Encoding", "gzip")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status 413, got %d", w.Code)
+	}
+}
+
+func TestCompression_RejectsUnknownAndCorruptEncodings(t *testing.T) {
+	r := newCompressionRouter(&testDeviceService{}, DefaultMaxDecompressedBytes)
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices/"+validDeviceID+"/stats", bytes.NewReader([]byte("x")))
+	req.Header.Set("Content-Encoding", "br")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected status 415 for br, got %d", w.Code)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/devices/"+validDeviceID+"/stats", bytes.NewReader([]byte("not gzip")))
+	req.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for corrupt gzip, got %d", w.Code)
+	}
+}
+
+func TestCompression_CompressesResponsesWhenAccepted(t *testing.T) {
+	svc := &testDeviceService{
+		getStatsResult: &ports.Stats{Uptime: 98.75, AvgUploadTime: "3m17s"},
+	}
+	r := newCompressionRouter(svc, DefaultMaxDecompressedBytes)
+
+	get := func(acceptEncoding string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/devices/"+validDeviceID+"/stats", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	decode := func(t *testing.T, rd io.Reader) StatsResponse {
+		t.Helper()
+		var resp StatsResponse
+		if err := json.NewDecoder(rd).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode body: %v", err)
+		}
+		return resp
+	}
+
+	w := get("gzip, deflate")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip response, got %q", w.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip response: %v", err)
+	}
+	if resp := decode(t, zr); resp.Uptime != 98.75 {
+		t.Fatalf("unexpected gzip body: %+v", resp)
+	}
+
+	w = get("gzip;q=0.5, zstd")
+	if w.Header().Get("Content-Encoding") != "zstd" {
+		t.Fatalf("expected zstd response, got %q", w.Header().Get("Content-Encoding"))
+	}
+	zd, _ := zstd.NewReader(w.Body)
+	defer zd.Close()
+	if resp := decode(t, zd); resp.AvgUploadTime != "3m17s" {
+		t.Fatalf("unexpected zstd body: %+v", resp)
+	}
+
+	w = get("")
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("expected identity response without Accept-Encoding")
+	}
+	if resp := decode(t, w.Body); resp.Uptime != 98.75 {
+		t.Fatalf("unexpected identity body: %+v", resp)
+	}
+}
+
+func TestCompression_FlushBeforeWriteStillCompresses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Compression(DefaultMaxDecompressedBytes))
+	r.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/csv")
+		c.Writer.Flush()
+		_, _ = c.Writer.WriteString("device_id,uptime\n")
+	})
+	r.GET("/committed", func(c *gin.Context) {
+		c.Writer.WriteHeaderNow()
+		_, _ = c.Writer.WriteString("plain")
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Result has the headers as sent, not as changed after the fact.
+	w := get("/stream")
+	if got := w.Result().Header.Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected gzip response after an early flush, got %q", got)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip response: %v", err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != "device_id,uptime\n" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	w = get("/committed")
+	if got := w.Result().Header.Get("Content-Encoding"); got != "" || w.Body.String() != "plain" {
+		t.Fatalf("expected headers sent before the first write to keep the body plain, got %q %q", got, w.Body.String())
+	}
+}
+
+func TestNegotiateEncoding_SkipsUnacceptable(t *testing.T) {
+	cases := map[string]string{
+		"zstd;q=0":             "",
+		"gzip;q=0, zstd;q=0":   "",
+		"gzip, zstd;q=0":       "gzip",
+		"gzip;q=0.5, zstd;q=1": "zstd",
+		"gzip;q=1, zstd;q=1":   "zstd",
+	}
+	for header, want := range cases {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"safelyyou/internal/core/ports"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statsETag identifies one state of a device. The update time is part of
+// the tag so versions restarting from zero (no snapshot) cannot collide.
+// It is weak because the body depends on the negotiated encoding.
+func statsETag(s *ports.Stats) string {
+	return fmt.Sprintf(`W/"%d.%s"`, s.Version, strconv.FormatInt(s.UpdatedAt.UnixMicro(), 36))
+}
+
+// listETag identifies the state of a set of devices.
+func listETag(stats []ports.Stats) (string, time.Time) {
+	h := fnv.New64a()
+	var modified time.Time
+	for _, s := range stats {
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00", s.ID, s.Version, s.UpdatedAt.UnixMicro())
+		if s.UpdatedAt.After(modified) {
+			modified = s.UpdatedAt
+		}
+	}
+	return fmt.Sprintf(`W/"%d.%x"`, len(stats), h.Sum64()), modified
+}
+
+// notModified sets the ETag and Last-Modified validators and answers 304
+// when the request's If-None-Match (or, without it, If-Modified-Since)
+// shows the client already has this state. It reports whether it did.
+func notModified(c *gin.Context, etag string, modified time.Time) bool {
+	c.Header("ETag", etag)
+	c.Writer.Header().Add("Vary", "Accept")
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	match := false
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		match = etagMatches(inm, etag)
+	} else if ims := c.GetHeader("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil {
+			match = !modified.Truncate(time.Second).After(t)
+		}
+	}
+	if match {
+		c.AbortWithStatus(http.StatusNotModified)
+	}
+	return match
+}
+
+// etagMatches applies the weak comparison of RFC 9110 section 13.1.2.
+func etagMatches(header, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
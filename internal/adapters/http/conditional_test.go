@@ -0,0 +1,85 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func conditionalGet(r *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func postHeartbeatAt(t *testing.T, r *gin.Engine, sentAt string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices/"+integrationDeviceID+"/heartbeat",
+		bytes.NewBufferString(`{"sent_at":"`+sentAt+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("heartbeat: expected 204, got %d", w.Code)
+	}
+}
+
+func TestIntegration_ConditionalGet(t *testing.T) {
+	for _, path := range []string{
+		"/api/v1/devices/" + integrationDeviceID + "/stats",
+		"/api/v2/devices/" + integrationDeviceID + "/stats",
+		"/api/v1/devices",
+	} {
+		r, repo := newIntegrationServer(t)
+		seedDevice(t, repo, integrationDeviceID)
+		postHeartbeatAt(t, r, "2025-11-09T10:00:00Z")
+
+		first := conditionalGet(r, path, nil)
+		etag := first.Header().Get("ETag")
+		if first.Code != http.StatusOK || etag == "" || first.Header().Get("Last-Modified") == "" {
+			t.Fatalf("%s: expected 200 with validators, got %d %v", path, first.Code, first.Header())
+		}
+
+		w := conditionalGet(r, path, map[string]string{"If-None-Match": `"other", ` + etag})
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+			t.Fatalf("%s: expected empty 304 with the same ETag, got %d %q", path, w.Code, w.Body.String())
+		}
+
+		w = conditionalGet(r, path, map[string]string{"If-Modified-Since": time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)})
+		if w.Code != http.StatusNotModified {
+			t.Errorf("%s: expected 304 for If-Modified-Since in the future, got %d", path, w.Code)
+		}
+
+		postHeartbeatAt(t, r, "2025-11-09T10:01:00Z")
+		w = conditionalGet(r, path, map[string]string{"If-None-Match": etag})
+		if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+			t.Errorf("%s: expected 200 with a new ETag after a heartbeat, got %d %q", path, w.Code, w.Header().Get("ETag"))
+		}
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	cases := []struct {
+		header string
+		want   bool
+	}{
+		{`W/"1.a"`, true},
+		{`"1.a"`, true},
+		{`"2.a", W/"1.a"`, true},
+		{`*`, true},
+		{`W/"1.b"`, false},
+	}
+	for _, tc := range cases {
+		if got := etagMatches(tc.header, `W/"1.a"`); got != tc.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+}
@@ -2,6 +2,12 @@ package http
 
 import "time"
 
+// The device DTOs below share their schema with api/fleet/v1/fleet.proto:
+// JSON and MessagePack use the json tags, which must match the proto field
+// names and kinds. They are not generated from the proto, because they
+// carry the binding and swagger annotations; dto_proto_test.go fails when
+// a field or message added to the proto is missing here or in dto_proto.go.
+
 type HeartbeatRequest struct {
 	SentAt time.Time `json:"sent_at" binding:"required"`
 }
@@ -19,3 +25,184 @@ type StatsResponse struct {
 	Uptime        float64 `json:"uptime"`
 	AvgUploadTime string  `json:"avg_upload_time"`
 }
+
+// DeviceResponse summarizes a device in the device list.
+type DeviceResponse struct {
+	DeviceID       string            `json:"device_id"`
+	Site           string            `json:"site,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	HeartbeatCount int64             `json:"heartbeat_count"`
+	UploadCount    int64             `json:"upload_count"`
+	LastSeenAt     *time.Time        `json:"last_seen_at"`
+	ClockSkewed    bool              `json:"clock_skewed,omitempty"`
+	Version        uint64            `json:"version"`
+}
+
+type DeviceRequest struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	Site     string `json:"site"`
+}
+
+// OutagesResponse lists a device's outages since Since. duration_ms is
+// set once an outage has ended.
+type OutagesResponse struct {
+	DeviceID string           `json:"device_id"`
+	Since    time.Time        `json:"since"`
+	Outages  []OutageResponse `json:"outages"`
+}
+
+type OutageResponse struct {
+	Start      time.Time  `json:"start"`
+	End        *time.Time `json:"end"`
+	DurationMs float64    `json:"duration_ms,omitempty"`
+	Ongoing    bool       `json:"ongoing"`
+}
+
+// LabelsRequest replaces a device's labels; a "site" label sets its site.
+type LabelsRequest struct {
+	Labels map[string]string `json:"labels"`
+}
+
+type GroupRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Selector string `json:"selector" binding:"required"`
+}
+
+type GroupResponse struct {
+	Name      string    `json:"name"`
+	Selector  string    `json:"selector"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GroupStatsResponse aggregates a group's devices. Uptimes are means over
+// reporting devices (those with at least one heartbeat); upload figures
+// pool every upload of the group.
+type GroupStatsResponse struct {
+	Group    string `json:"group"`
+	Selector string `json:"selector"`
+
+	Devices          int `json:"devices"`
+	ReportingDevices int `json:"reporting_devices"`
+	OfflineDevices   int `json:"offline_devices"`
+
+	UptimePercent       float64 `json:"uptime_percent"`
+	Uptime24hPercent    float64 `json:"uptime_24h_percent"`
+	MinUptime24hPercent float64 `json:"min_uptime_24h_percent"`
+
+	UploadCount int64   `json:"upload_count"`
+	AvgUploadMs float64 `json:"avg_upload_ms"`
+	P50UploadMs float64 `json:"p50_upload_ms"`
+	P95UploadMs float64 `json:"p95_upload_ms"`
+	P99UploadMs float64 `json:"p99_upload_ms"`
+
+	DeviceIDs []string `json:"device_ids"`
+}
+
+type WebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+type WebhookResponse struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Signed    bool      `json:"signed"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DeliveryResponse struct {
+	ID             string     `json:"id"`
+	WebhookID      string     `json:"webhook_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	DeviceID       string     `json:"device_id"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	CreatedAt      time.Time  `json:"created_at"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+type AlertRuleRequest struct {
+	Name     string `json:"name"`
+	Expr     string `json:"expr" binding:"required"`
+	Scope    string `json:"scope"`
+	Tenant   string `json:"tenant"`
+	Target   string `json:"target"`
+	For      string `json:"for"`
+	Severity string `json:"severity"`
+}
+
+type AlertRuleResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Expr      string    `json:"expr"`
+	Metric    string    `json:"metric"`
+	Op        string    `json:"op"`
+	Threshold float64   `json:"threshold"`
+	Scope     string    `json:"scope"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Target    string    `json:"target,omitempty"`
+	For       string    `json:"for"`
+	Severity  string    `json:"severity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AlertResponse struct {
+	ID              string     `json:"id"`
+	RuleID          string     `json:"rule_id"`
+	RuleName        string     `json:"rule_name"`
+	Tenant          string     `json:"tenant"`
+	DeviceID        string     `json:"device_id"`
+	Severity        string     `json:"severity"`
+	State           string     `json:"state"`
+	Value           float64    `json:"value"`
+	Silenced        bool       `json:"silenced"`
+	StartsAt        time.Time  `json:"starts_at"`
+	FiredAt         *time.Time `json:"fired_at,omitempty"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+	LastEvaluatedAt time.Time  `json:"last_evaluated_at"`
+}
+
+type SilenceRequest struct {
+	Tenant   string    `json:"tenant"`
+	RuleID   string    `json:"rule_id"`
+	DeviceID string    `json:"device_id"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+	Comment  string    `json:"comment"`
+}
+
+type SilenceResponse struct {
+	ID        string    `json:"id"`
+	Tenant    string    `json:"tenant,omitempty"`
+	RuleID    string    `json:"rule_id,omitempty"`
+	DeviceID  string    `json:"device_id,omitempty"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ImportReportResponse summarizes an import; errors lists at most the
+// first 100 rejected rows.
+type ImportReportResponse struct {
+	DryRun          bool                `json:"dry_run"`
+	Rows            int                 `json:"rows"`
+	Accepted        int                 `json:"accepted"`
+	Rejected        int                 `json:"rejected"`
+	Heartbeats      int                 `json:"heartbeats"`
+	Stats           int                 `json:"stats"`
+	Errors          []ImportRowResponse `json:"errors"`
+	ErrorsTruncated bool                `json:"errors_truncated,omitempty"`
+}
+
+type ImportRowResponse struct {
+	Line     int    `json:"line"`
+	DeviceID string `json:"device_id,omitempty"`
+	Reason   string `json:"reason"`
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	fleetv1 "safelyyou/api/fleet/v1"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (r *HeartbeatRequest) emptyProto() proto.Message {
+	return &fleetv1.RecordHeartbeatRequest{}
+}
+
+func (r *HeartbeatRequest) fromProto(m proto.Message) {
+	msg := m.(*fleetv1.RecordHeartbeatRequest)
+	r.SentAt = timeFromProto(msg.GetSentAt())
+}
+
+func (r *StatsRequest) emptyProto() proto.Message {
+	return &fleetv1.RecordStatsRequest{}
+}
+
+func (r *StatsRequest) fromProto(m proto.Message) {
+	msg := m.(*fleetv1.RecordStatsRequest)
+	r.SentAt = timeFromProto(msg.GetSentAt())
+	r.UploadTime = msg.GetUploadTime()
+}
+
+func (r StatsResponse) toProto() proto.Message {
+	return &fleetv1.GetStatsResponse{
+		Uptime:        r.Uptime,
+		AvgUploadTime: r.AvgUploadTime,
+	}
+}
+
+func (r ErrorResponse) toProto() proto.Message {
+	return &fleetv1.ErrorResponse{Msg: r.Msg}
+}
+
+// timeFromProto maps an unset timestamp to the zero time, so that
+// binding:"required" rejects it like a missing JSON field.
+func timeFromProto(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	fleetv1 "safelyyou/api/fleet/v1"
+)
+
+// grpcOnlyMessages are the fleet.proto messages no HTTP endpoint encodes.
+var grpcOnlyMessages = map[protoreflect.FullName]bool{
+	"fleet.v1.RecordHeartbeatResponse": true,
+	"fleet.v1.RecordStatsResponse":     true,
+	"fleet.v1.GetStatsRequest":         true,
+	"fleet.v1.IngestRequest":           true,
+	"fleet.v1.IngestError":             true,
+	"fleet.v1.IngestResponse":          true,
+}
+
+// jsonKinds returns the proto kind each json field of a DTO struct must
+// have, keyed by json tag name.
+func jsonKinds(t *testing.T, v any) map[string]string {
+	t.Helper()
+	kinds := make(map[string]string)
+	typ := reflect.TypeOf(v)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft == reflect.TypeOf(time.Time{}):
+			kinds[name] = "google.protobuf.Timestamp"
+		case ft.Kind() == reflect.String:
+			kinds[name] = protoreflect.StringKind.String()
+		case ft.Kind() == reflect.Int64:
+			kinds[name] = protoreflect.Int64Kind.String()
+		case ft.Kind() == reflect.Float64:
+			kinds[name] = protoreflect.DoubleKind.String()
+		case ft.Kind() == reflect.Bool:
+			kinds[name] = protoreflect.BoolKind.String()
+		default:
+			t.Errorf("%T.%s: no proto counterpart for %s", v, f.Name, ft)
+		}
+	}
+	return kinds
+}
+
+// protoKinds returns the kind of each field of a message, keyed by field
+// name, minus the excluded ones. Message fields are named by their type.
+func protoKinds(m proto.Message, exclude ...string) map[string]string {
+	kinds := make(map[string]string)
+	fields := m.ProtoReflect().Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.Get(i)
+		name := string(f.Name())
+		skip := false
+		for _, ex := range exclude {
+			skip = skip || ex == name
+		}
+		if skip {
+			continue
+		}
+		kind := f.Kind().String()
+		if f.Message() != nil {
+			kind = string(f.Message().FullName())
+		}
+		if f.IsList() {
+			kind = "repeated " + kind
+		}
+		kinds[name] = kind
+	}
+	return kinds
+}
+
+func TestDTOs_MatchProtoSchema(t *testing.T) {
+	cases := []struct {
+		dto    any
+		msg    proto.Message
+		pathed []string // fields carried in the URL path instead of the body
+	}{
+		{HeartbeatRequest{}, &fleetv1.RecordHeartbeatRequest{}, []string{"device_id"}},
+		{StatsRequest{}, &fleetv1.RecordStatsRequest{}, []string{"device_id"}},
+		{StatsResponse{}, &fleetv1.GetStatsResponse{}, nil},
+		{ErrorResponse{}, &fleetv1.ErrorResponse{}, nil},
+	}
+	covered := make(map[protoreflect.FullName]bool)
+	for _, tc := range cases {
+		name := tc.msg.ProtoReflect().Descriptor().FullName()
+		covered[name] = true
+
+		var converted proto.Message
+		switch dto := reflect.New(reflect.TypeOf(tc.dto)).Interface().(type) {
+		case protoRequest:
+			converted = dto.emptyProto()
+		case protoResponse:
+			converted = dto.toProto()
+		}
+		if converted == nil || converted.ProtoReflect().Descriptor().FullName() != name {
+			t.Errorf("%T does not convert to %s", tc.dto, name)
+		}
+
+		got, want := jsonKinds(t, tc.dto), protoKinds(tc.msg, tc.pathed...)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%T fields %v do not match %s fields %v", tc.dto, got, name, want)
+		}
+	}
+
+	// A message added to the proto must be mapped above, or declared
+	// gRPC only.
+	messages := fleetv1.File_fleet_v1_fleet_proto.Messages()
+	for i := 0; i < messages.Len(); i++ {
+		name := messages.Get(i).FullName()
+		if !covered[name] && !grpcOnlyMessages[name] {
+			t.Errorf("%s has no DTO and is not listed as gRPC only", name)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"safelyyou/internal/core/ports"
+	"time"
+)
+
+// The /api/v2 DTOs use numeric fields with the unit in their name. They are
+// kept separate from the v1 DTOs, whose wire format must not change.
+
+// StatsV2Response is the /api/v2 device stats payload.
+type StatsV2Response struct {
+	DeviceID string            `json:"device_id"`
+	Site     string            `json:"site,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+
+	// UptimeRatio is heartbeats received per expected heartbeat (one a
+	// minute) over Window; 1 means no gaps.
+	UptimeRatio    float64 `json:"uptime_ratio"`
+	Uptime24hRatio float64 `json:"uptime_24h_ratio"`
+	AvgUploadMs    float64 `json:"avg_upload_ms"`
+	P95UploadMs    float64 `json:"p95_upload_ms"`
+
+	Window StatsWindowV2 `json:"window"`
+	Counts StatsCountsV2 `json:"counts"`
+
+	// LastHeartbeatAt is the latest heartbeat time, as recorded under the
+	// timestamp policy; LastSentAt is the sent_at of the heartbeat last
+	// received and LastSeenAt is when the server received it.
+	LastHeartbeatAt *time.Time `json:"last_heartbeat_at"`
+	LastSentAt      *time.Time `json:"last_sent_at"`
+	LastSeenAt      *time.Time `json:"last_seen_at"`
+
+	// ClockOffsetMs estimates how far the device clock is ahead of the
+	// server's, negative when behind; ClockSkewed flags offsets beyond the
+	// configured threshold.
+	ClockOffsetMs float64 `json:"clock_offset_ms"`
+	ClockSkewed   bool    `json:"clock_skewed"`
+}
+
+// StatsWindowV2 is the heartbeat span UptimeRatio is computed over.
+type StatsWindowV2 struct {
+	Start   *time.Time `json:"start"`
+	End     *time.Time `json:"end"`
+	Minutes float64    `json:"minutes"`
+}
+
+type StatsCountsV2 struct {
+	Heartbeats int64 `json:"heartbeats"`
+	Uploads    int64 `json:"uploads"`
+}
+
+func toStatsV2Response(id string, s *ports.Stats) StatsV2Response {
+	return StatsV2Response{
+		DeviceID:       id,
+		Site:           s.Site,
+		Labels:         s.Labels,
+		UptimeRatio:    s.Uptime / 100,
+		Uptime24hRatio: s.Uptime24h / 100,
+		AvgUploadMs:    durationMs(s.AvgUpload),
+		P95UploadMs:    durationMs(s.P95Upload),
+		Window: StatsWindowV2{
+			Start:   optionalTime(s.FirstHeartbeat),
+			End:     optionalTime(s.LastHeartbeat),
+			Minutes: windowMinutes(s),
+		},
+		Counts:          StatsCountsV2{Heartbeats: s.HeartbeatCount, Uploads: s.UploadCount},
+		LastHeartbeatAt: optionalTime(s.LastHeartbeat),
+		LastSentAt:      optionalTime(s.LastSentAt),
+		LastSeenAt:      optionalTime(s.LastSeenAt),
+		ClockOffsetMs:   durationMs(s.ClockOffset),
+		ClockSkewed:     s.ClockSkewed,
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// windowMinutes mirrors the window used by the uptime computation: at
+// least one minute once a heartbeat has been received.
+func windowMinutes(s *ports.Stats) float64 {
+	if s.HeartbeatCount == 0 {
+		return 0
+	}
+	if m := s.LastHeartbeat.Sub(s.FirstHeartbeat).Minutes(); m > 0 {
+		return m
+	}
+	return 1
+}
+
+// optionalTime renders the zero time as null.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
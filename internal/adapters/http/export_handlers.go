@@ -0,0 +1,370 @@
+package http
+
+import (
+	"encoding/csv"
+	"net/http"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/logging"
+	"safelyyou/pkg/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Export media types.
+const (
+	mimeCSV     = "text/csv; charset=utf-8"
+	mimeParquet = "application/vnd.apache.parquet"
+)
+
+// exportBatch is how many rows are buffered before being written out.
+const exportBatch = 512
+
+type ExportHandler struct {
+	exportSvc ports.ExportService
+}
+
+// NewExportHandler constructs a handler that depends on the ExportService interface.
+func NewExportHandler(svc ports.ExportService) *ExportHandler {
+	return &ExportHandler{exportSvc: svc}
+}
+
+// statsRow is the stats export schema, shared by CSV and Parquet.
+type statsRow struct {
+	DeviceID         string     `parquet:"device_id"`
+	Site             string     `parquet:"site"`
+	UptimePercent    float64    `parquet:"uptime_percent"`
+	Uptime24hPercent float64    `parquet:"uptime_24h_percent"`
+	AvgUploadMs      float64    `parquet:"avg_upload_ms"`
+	P95UploadMs      float64    `parquet:"p95_upload_ms"`
+	HeartbeatCount   int64      `parquet:"heartbeat_count"`
+	UploadCount      int64      `parquet:"upload_count"`
+	FirstHeartbeat   *time.Time `parquet:"first_heartbeat,optional,timestamp(millisecond)"`
+	LastHeartbeat    *time.Time `parquet:"last_heartbeat,optional,timestamp(millisecond)"`
+	LastSeenAt       *time.Time `parquet:"last_seen_at,optional,timestamp(millisecond)"`
+}
+
+var statsCSVHeader = []string{"device_id", "site", "uptime_percent", "uptime_24h_percent", "avg_upload_ms",
+	"p95_upload_ms", "heartbeat_count", "upload_count", "first_heartbeat", "last_heartbeat", "last_seen_at"}
+
+func toStatsRow(s ports.Stats) statsRow {
+	return statsRow{
+		DeviceID:         s.ID,
+		Site:             s.Site,
+		UptimePercent:    s.Uptime,
+		Uptime24hPercent: s.Uptime24h,
+		AvgUploadMs:      durationMs(s.AvgUpload),
+		P95UploadMs:      durationMs(s.P95Upload),
+		HeartbeatCount:   s.HeartbeatCount,
+		UploadCount:      s.UploadCount,
+		FirstHeartbeat:   optionalTime(s.FirstHeartbeat),
+		LastHeartbeat:    optionalTime(s.LastHeartbeat),
+		LastSeenAt:       optionalTime(s.LastSeenAt),
+	}
+}
+
+func (r statsRow) record() []string {
+	return []string{r.DeviceID, r.Site, formatFloat(r.UptimePercent), formatFloat(r.Uptime24hPercent),
+		formatFloat(r.AvgUploadMs), formatFloat(r.P95UploadMs), strconv.FormatInt(r.HeartbeatCount, 10),
+		strconv.FormatInt(r.UploadCount, 10), formatTime(r.FirstHeartbeat), formatTime(r.LastHeartbeat),
+		formatTime(r.LastSeenAt)}
+}
+
+// seriesRow is the time-series export schema: one device minute.
+type seriesRow struct {
+	DeviceID    string    `parquet:"device_id"`
+	Minute      time.Time `parquet:"minute,timestamp(millisecond)"`
+	Heartbeats  int64     `parquet:"heartbeats"`
+	Uploads     int64     `parquet:"uploads"`
+	AvgUploadMs float64   `parquet:"avg_upload_ms"`
+}
+
+var seriesCSVHeader = []string{"device_id", "minute", "heartbeats", "uploads", "avg_upload_ms"}
+
+func toSeriesRow(p ports.SeriesPoint) seriesRow {
+	return seriesRow{
+		DeviceID:    p.DeviceID,
+		Minute:      p.Minute.UTC(),
+		Heartbeats:  p.Heartbeats,
+		Uploads:     p.Uploads,
+		AvgUploadMs: durationMs(p.AvgUpload),
+	}
+}
+
+func (r seriesRow) record() []string {
+	return []string{r.DeviceID, formatTime(&r.Minute), strconv.FormatInt(r.Heartbeats, 10),
+		strconv.FormatInt(r.Uploads, 10), formatFloat(r.AvgUploadMs)}
+}
+
+// ExportStatsCSV godoc
+// @Summary Export device stats as CSV
+// @Description Stream one row of stats per device. With from/to, only devices with heartbeats in that range are included.
+// @Tags export
+// @Produce text/csv
+// @Param device_id query []string false "Device IDs (repeat or comma-separate)" collectionFormat(multi)
+// @Param from query string false "RFC 3339 start, inclusive"
+// @Param to query string false "RFC 3339 end, exclusive"
+// @Success 200 {file} file
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Router /api/v1/export/stats.csv [get]
+func (h *ExportHandler) ExportStatsCSV(c *gin.Context) {
+	h.exportStats(c, newCSVRows[statsRow](c, "stats.csv", statsCSVHeader))
+}
+
+// ExportStatsParquet godoc
+// @Summary Export device stats as Parquet
+// @Description Same rows as stats.csv, as a Parquet file.
+// @Tags export
+// @Produce application/vnd.apache.parquet
+// @Param device_id query []string false "Device IDs (repeat or comma-separate)" collectionFormat(multi)
+// @Param from query string false "RFC 3339 start, inclusive"
+// @Param to query string false "RFC 3339 end, exclusive"
+// @Success 200 {file} file
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Router /api/v1/export/stats.parquet [get]
+func (h *ExportHandler) ExportStatsParquet(c *gin.Context) {
+	h.exportStats(c, newParquetRows[statsRow](c, "stats.parquet"))
+}
+
+// ExportSeriesCSV godoc
+// @Summary Export per-minute device activity as CSV
+// @Description Stream one row per device and minute with heartbeats and uploads, within the series retention.
+// @Tags export
+// @Produce text/csv
+// @Param device_id query []string false "Device IDs (repeat or comma-separate)" collectionFormat(multi)
+// @Param from query string false "RFC 3339 start, inclusive"
+// @Param to query string false "RFC 3339 end, exclusive"
+// @Success 200 {file} file
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Router /api/v1/export/series.csv [get]
+func (h *ExportHandler) ExportSeriesCSV(c *gin.Context) {
+	h.exportSeries(c, newCSVRows[seriesRow](c, "series.csv", seriesCSVHeader))
+}
+
+// ExportSeriesParquet godoc
+// @Summary Export per-minute device activity as Parquet
+// @Description Same rows as series.csv, as a Parquet file.
+// @Tags export
+// @Produce application/vnd.apache.parquet
+// @Param device_id query []string false "Device IDs (repeat or comma-separate)" collectionFormat(multi)
+// @Param from query string false "RFC 3339 start, inclusive"
+// @Param to query string false "RFC 3339 end, exclusive"
+// @Success 200 {file} file
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Router /api/v1/export/series.parquet [get]
+func (h *ExportHandler) ExportSeriesParquet(c *gin.Context) {
+	h.exportSeries(c, newParquetRows[seriesRow](c, "series.parquet"))
+}
+
+func (h *ExportHandler) exportStats(c *gin.Context, rows rowWriter[statsRow]) {
+	f, err := parseExportFilter(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	err = h.exportSvc.ExportStats(c.Request.Context(), f, func(s ports.Stats) error {
+		return rows.Write(toStatsRow(s))
+	})
+	finishExport(c, rows, err)
+}
+
+func (h *ExportHandler) exportSeries(c *gin.Context, rows rowWriter[seriesRow]) {
+	f, err := parseExportFilter(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	err = h.exportSvc.ExportSeries(c.Request.Context(), f, func(p ports.SeriesPoint) error {
+		return rows.Write(toSeriesRow(p))
+	})
+	finishExport(c, rows, err)
+}
+
+// finishExport completes the file, or reports err. Before the first row an
+// error still gets a problem response; afterwards the body is cut short.
+func finishExport[T any](c *gin.Context, rows rowWriter[T], err error) {
+	if err != nil && !rows.Started() {
+		respondError(c, err)
+		return
+	}
+	if err == nil {
+		err = rows.Close()
+	}
+	if err != nil {
+		logging.For("http").WarnContext(c.Request.Context(), "export aborted", "error", err)
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// parseExportFilter reads device_id, from and to.
+func parseExportFilter(c *gin.Context) (ports.ExportFilter, error) {
+	var f ports.ExportFilter
+	for _, v := range c.QueryArray("device_id") {
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id == "" {
+				continue
+			}
+			if !utils.IsId(id) {
+				return f, errInvalidDeviceID
+			}
+			f.DeviceIDs = append(f.DeviceIDs, id)
+		}
+	}
+	var err error
+	if f.From, err = parseQueryTime(c, "from"); err != nil {
+		return f, err
+	}
+	if f.To, err = parseQueryTime(c, "to"); err != nil {
+		return f, err
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.To.After(f.From) {
+		return f, coreerrors.Invalid("to", "must be after from")
+	}
+	return f, nil
+}
+
+func parseQueryTime(c *gin.Context, name string) (time.Time, error) {
+	v := c.Query(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, coreerrors.Invalid(name, "must be an RFC 3339 timestamp")
+	}
+	return t, nil
+}
+
+// rowWriter streams export rows in one file format. Nothing is written to
+// the response until the first row (or Close), so errors found before
+// that can still become a problem response.
+type rowWriter[T any] interface {
+	Write(row T) error
+	Close() error
+	Started() bool
+}
+
+type csvRecord interface{ record() []string }
+
+type csvRows[T csvRecord] struct {
+	c        *gin.Context
+	name     string
+	header   []string
+	w        *csv.Writer
+	buffered int
+}
+
+func newCSVRows[T csvRecord](c *gin.Context, name string, header []string) *csvRows[T] {
+	return &csvRows[T]{c: c, name: name, header: header}
+}
+
+func (r *csvRows[T]) Started() bool { return r.w != nil }
+
+func (r *csvRows[T]) start() error {
+	if r.w != nil {
+		return nil
+	}
+	startDownload(r.c, mimeCSV, r.name)
+	r.w = csv.NewWriter(r.c.Writer)
+	return r.w.Write(r.header)
+}
+
+func (r *csvRows[T]) Write(row T) error {
+	if err := r.start(); err != nil {
+		return err
+	}
+	if err := r.w.Write(row.record()); err != nil {
+		return err
+	}
+	if r.buffered++; r.buffered >= exportBatch {
+		r.buffered = 0
+		return r.flush()
+	}
+	return nil
+}
+
+func (r *csvRows[T]) Close() error {
+	if err := r.start(); err != nil {
+		return err
+	}
+	return r.flush()
+}
+
+func (r *csvRows[T]) flush() error {
+	r.w.Flush()
+	r.c.Writer.Flush()
+	return r.w.Error()
+}
+
+type parquetRows[T any] struct {
+	c    *gin.Context
+	name string
+	w    *parquet.GenericWriter[T]
+	buf  []T
+}
+
+func newParquetRows[T any](c *gin.Context, name string) *parquetRows[T] {
+	return &parquetRows[T]{c: c, name: name}
+}
+
+func (r *parquetRows[T]) Started() bool { return r.w != nil }
+
+func (r *parquetRows[T]) start() {
+	if r.w != nil {
+		return
+	}
+	startDownload(r.c, mimeParquet, r.name)
+	r.w = parquet.NewGenericWriter[T](r.c.Writer,
+		parquet.Compression(&parquet.Snappy),
+		parquet.MaxRowsPerRowGroup(16*exportBatch))
+	r.buf = make([]T, 0, exportBatch)
+}
+
+func (r *parquetRows[T]) Write(row T) error {
+	r.start()
+	if r.buf = append(r.buf, row); len(r.buf) < exportBatch {
+		return nil
+	}
+	return r.flush()
+}
+
+func (r *parquetRows[T]) Close() error {
+	r.start()
+	if err := r.flush(); err != nil {
+		return err
+	}
+	return r.w.Close()
+}
+
+func (r *parquetRows[T]) flush() error {
+	if len(r.buf) == 0 {
+		return nil
+	}
+	_, err := r.w.Write(r.buf)
+	r.buf = r.buf[:0]
+	return err
+}
+
+func startDownload(c *gin.Context, contentType, name string) {
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Status(http.StatusOK)
+}
+
+func formatFloat(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/parquet-go/parquet-go"
+
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/services"
+)
+
+const exportOtherDeviceID = "60-6b-44-84-dc-65"
+
+// newExportServer ingests three heartbeats and one upload for
+// integrationDeviceID and nothing for exportOtherDeviceID.
+func newExportServer(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := memory.NewDeviceRepository()
+	r := gin.New()
+	RegisterRoutes(r, services.NewDeviceService(repo))
+	RegisterExportRoutes(r, services.NewExportService(repo))
+
+	seedDevice(t, repo, integrationDeviceID)
+	seedDevice(t, repo, exportOtherDeviceID)
+	for _, ts := range []string{"10:00", "10:01", "10:02"} {
+		postHeartbeatAt(t, r, "2025-11-09T"+ts+":00Z")
+	}
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices/"+integrationDeviceID+"/stats",
+		bytes.NewBufferString(`{"sent_at":"2025-11-09T10:01:30Z","upload_time":2500000000}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	return r
+}
+
+func exportGet(t *testing.T, r *gin.Engine, path string, wantStatus int) *httptest.ResponseRecorder {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != wantStatus {
+		t.Fatalf("GET %s: expected %d, got %d, body=%s", path, wantStatus, w.Code, w.Body.String())
+	}
+	return w
+}
+
+func readCSV(t *testing.T, w *httptest.ResponseRecorder) [][]string {
+	t.Helper()
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	return records
+}
+
+func TestExportStatsCSV(t *testing.T) {
+	r := newExportServer(t)
+
+	w := exportGet(t, r, "/api/v1/export/stats.csv", http.StatusOK)
+	if ct := w.Header().Get("Content-Type"); ct != mimeCSV {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	records := readCSV(t, w)
+	if len(records) != 3 || records[0][0] != "device_id" {
+		t.Fatalf("expected header and two devices, got %v", records)
+	}
+	got := records[1]
+	if got[0] != integrationDeviceID || got[2] != "150" || got[4] != "2500" || got[6] != "3" ||
+		got[8] != "2025-11-09T10:00:00Z" || got[9] != "2025-11-09T10:02:00Z" {
+		t.Errorf("unexpected stats row %v", got)
+	}
+	if records[2][0] != exportOtherDeviceID || records[2][8] != "" {
+		t.Errorf("expected an idle device with empty timestamps, got %v", records[2])
+	}
+
+	records = readCSV(t, exportGet(t, r, "/api/v1/export/stats.csv?from=2025-11-09T10:01:00Z", http.StatusOK))
+	if len(records) != 2 || records[1][0] != integrationDeviceID {
+		t.Errorf("expected only the active device in range, got %v", records)
+	}
+}
+
+func TestExportSeriesCSV_Filters(t *testing.T) {
+	r := newExportServer(t)
+
+	path := "/api/v1/export/series.csv?device_id=" + integrationDeviceID + "&from=2025-11-09T10:01:00Z&to=2025-11-09T10:02:00Z"
+	records := readCSV(t, exportGet(t, r, path, http.StatusOK))
+	want := []string{integrationDeviceID, "2025-11-09T10:01:00Z", "1", "1", "2500"}
+	if len(records) != 2 || len(records[1]) != len(want) {
+		t.Fatalf("expected one minute, got %v", records)
+	}
+	for i := range want {
+		if records[1][i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, records[1])
+		}
+	}
+
+	records = readCSV(t, exportGet(t, r, "/api/v1/export/series.csv?device_id="+exportOtherDeviceID, http.StatusOK))
+	if len(records) != 1 {
+		t.Errorf("expected only the header for an idle device, got %v", records)
+	}
+}
+
+func TestExportParquet(t *testing.T) {
+	r := newExportServer(t)
+
+	w := exportGet(t, r, "/api/v1/export/series.parquet", http.StatusOK)
+	if ct := w.Header().Get("Content-Type"); ct != mimeParquet {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	series, err := parquet.Read[seriesRow](bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("invalid parquet: %v", err)
+	}
+	if len(series) != 3 || series[0].DeviceID != integrationDeviceID ||
+		!series[0].Minute.Equal(time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)) || series[1].AvgUploadMs != 2500 {
+		t.Errorf("unexpected series %+v", series)
+	}
+
+	w = exportGet(t, r, "/api/v1/export/stats.parquet", http.StatusOK)
+	stats, err := parquet.Read[statsRow](bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("invalid parquet: %v", err)
+	}
+	if len(stats) != 2 || stats[0].HeartbeatCount != 3 || stats[0].LastSeenAt == nil || stats[1].LastSeenAt != nil {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestExport_InvalidFiltersAreProblems(t *testing.T) {
+	r := newExportServer(t)
+
+	for _, path := range []string{
+		"/api/v1/export/stats.csv?from=yesterday",
+		"/api/v1/export/series.parquet?from=2025-11-09T11:00:00Z&to=2025-11-09T10:00:00Z",
+		"/api/v1/export/stats.csv?device_id=not-an-id",
+	} {
+		w := exportGet(t, r, path, http.StatusBadRequest)
+		if ct := w.Header().Get("Content-Type"); ct != mimeProblem {
+			t.Errorf("%s: expected a problem response, got %q", path, ct)
+		}
+	}
+	exportGet(t, r, "/api/v1/export/stats.csv?device_id=60-6b-44-84-dc-99", http.StatusNotFound)
+
+	// A known device listed before the unknown one must not start the file.
+	for _, path := range []string{
+		"/api/v1/export/stats.csv?device_id=" + integrationDeviceID + ",60-6b-44-84-dc-99",
+		"/api/v1/export/series.parquet?device_id=" + integrationDeviceID + "&device_id=60-6b-44-84-dc-99",
+	} {
+		w := exportGet(t, r, path, http.StatusNotFound)
+		if ct := w.Header().Get("Content-Type"); ct != mimeProblem {
+			t.Errorf("%s: expected a problem response, got %q", path, ct)
+		}
+	}
+}
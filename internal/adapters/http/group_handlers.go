@@ -0,0 +1,114 @@
+package http
+
+import (
+	"net/http"
+	"safelyyou/internal/core/domain"
+	"safelyyou/internal/core/ports"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupHandler struct {
+	groupSvc ports.GroupService
+}
+
+// NewGroupHandler constructs a handler that depends on the GroupService interface.
+func NewGroupHandler(svc ports.GroupService) *GroupHandler {
+	return &GroupHandler{groupSvc: svc}
+}
+
+// PostGroup godoc
+// @Summary Create a device group
+// @Description Define a group by a label selector of comma-separated requirements:
+// @Description "key=value", "key!=value", "key" (label present) or "!key" (label absent), e.g. "site=north,floor=2".
+// @Description Membership is evaluated on every read, so relabelled devices move between groups.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param request body GroupRequest true "Group"
+// @Success 201 {object} GroupResponse
+// @Failure 400 {object} Problem
+// @Failure 409 {object} Problem
+// @Router /api/v1/groups [post]
+func (h *GroupHandler) PostGroup(c *gin.Context) {
+	var req GroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+
+	g, err := h.groupSvc.CreateGroup(c.Request.Context(), req.Name, req.Selector)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, toGroupResponse(*g))
+}
+
+// ListGroups godoc
+// @Summary List device groups
+// @Tags groups
+// @Produce json
+// @Success 200 {array} GroupResponse
+// @Router /api/v1/groups [get]
+func (h *GroupHandler) ListGroups(c *gin.Context) {
+	groups := h.groupSvc.ListGroups(c.Request.Context())
+	resp := make([]GroupResponse, 0, len(groups))
+	for _, g := range groups {
+		resp = append(resp, toGroupResponse(g))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteGroup godoc
+// @Summary Delete a device group
+// @Tags groups
+// @Param group path string true "Group name"
+// @Success 204 "deleted"
+// @Failure 404 {object} Problem
+// @Router /api/v1/groups/{group} [delete]
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	if err := h.groupSvc.DeleteGroup(c.Request.Context(), c.Param("group")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetGroupStats godoc
+// @Summary Get group stats
+// @Description Aggregate the devices the group selects: mean and minimum uptime over reporting devices,
+// @Description and average and p50/p95/p99 upload time over the group's pooled uploads.
+// @Tags groups
+// @Produce json
+// @Param group path string true "Group name"
+// @Success 200 {object} GroupStatsResponse
+// @Failure 404 {object} Problem
+// @Router /api/v1/groups/{group}/stats [get]
+func (h *GroupHandler) GetGroupStats(c *gin.Context) {
+	s, err := h.groupSvc.GroupStats(c.Request.Context(), c.Param("group"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GroupStatsResponse{
+		Group:               s.Group.Name,
+		Selector:            s.Group.Selector.String(),
+		Devices:             s.Devices,
+		ReportingDevices:    s.ReportingDevices,
+		OfflineDevices:      s.OfflineDevices,
+		UptimePercent:       s.Uptime,
+		Uptime24hPercent:    s.Uptime24h,
+		MinUptime24hPercent: s.MinUptime24h,
+		UploadCount:         s.UploadCount,
+		AvgUploadMs:         durationMs(s.AvgUpload),
+		P50UploadMs:         durationMs(s.P50Upload),
+		P95UploadMs:         durationMs(s.P95Upload),
+		P99UploadMs:         durationMs(s.P99Upload),
+		DeviceIDs:           s.DeviceIDs,
+	})
+}
+
+func toGroupResponse(g domain.Group) GroupResponse {
+	return GroupResponse{Name: g.Name, Selector: g.Selector.String(), CreatedAt: g.CreatedAt}
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/services"
+)
+
+// newGroupServer seeds integrationDeviceID and exportOtherDeviceID and
+// serves the device and group routes over them.
+func newGroupServer(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := memory.NewDeviceRepository()
+	r := gin.New()
+	RegisterRoutes(r, services.NewDeviceService(repo))
+	RegisterGroupRoutes(r, services.NewGroupService(repo, memory.NewGroupRepository()))
+
+	seedDevice(t, repo, integrationDeviceID)
+	seedDevice(t, repo, exportOtherDeviceID)
+	return r
+}
+
+func groupRequest(t *testing.T, r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGroupStats_FollowsLabels(t *testing.T) {
+	r := newGroupServer(t)
+
+	if w := groupRequest(t, r, http.MethodPost, "/api/v1/groups", `{"name":"north","selector":"site=north, floor"}`); w.Code != http.StatusCreated {
+		t.Fatalf("create group: expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+	w := groupRequest(t, r, http.MethodPut, "/api/v1/devices/"+integrationDeviceID+"/labels", `{"labels":{"site":"north","floor":"2"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("put labels: expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	_ = groupRequest(t, r, http.MethodPut, "/api/v1/devices/"+exportOtherDeviceID+"/labels", `{"labels":{"site":"north"}}`)
+	postHeartbeatAt(t, r, "2025-11-09T10:00:00Z")
+	postHeartbeatAt(t, r, "2025-11-09T10:01:00Z")
+
+	w = groupRequest(t, r, http.MethodGet, "/api/v1/groups/north/stats", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("group stats: expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	var resp GroupStatsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if resp.Selector != "floor,site=north" || resp.Devices != 1 || resp.ReportingDevices != 1 ||
+		!slices.Equal(resp.DeviceIDs, []string{integrationDeviceID}) || resp.UptimePercent != 200 {
+		t.Fatalf("unexpected group stats %+v", resp)
+	}
+
+	// Moving the device to another site takes it out of the group.
+	_ = groupRequest(t, r, http.MethodPut, "/api/v1/devices/"+integrationDeviceID+"/labels", `{"labels":{"site":"south","floor":"2"}}`)
+	w = groupRequest(t, r, http.MethodGet, "/api/v1/groups/north/stats", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Devices != 0 || len(resp.DeviceIDs) != 0 {
+		t.Fatalf("expected an empty group, got %s (%v)", w.Body.String(), err)
+	}
+}
+
+func TestGroupRoutes_Errors(t *testing.T) {
+	r := newGroupServer(t)
+	_ = groupRequest(t, r, http.MethodPost, "/api/v1/groups", `{"name":"north","selector":"site=north"}`)
+
+	cases := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/api/v1/groups", `{"name":"north","selector":"site=north"}`, http.StatusConflict},
+		{http.MethodPost, "/api/v1/groups", `{"name":"North","selector":"site=north"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/groups", `{"name":"south","selector":"Site=south"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/groups", `{"name":"south"}`, http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/groups/missing/stats", "", http.StatusNotFound},
+		{http.MethodDelete, "/api/v1/groups/missing", "", http.StatusNotFound},
+		{http.MethodPut, "/api/v1/devices/" + integrationDeviceID + "/labels", `{"labels":{"Bad Key":"x"}}`, http.StatusBadRequest},
+		{http.MethodDelete, "/api/v1/groups/north", "", http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		if w := groupRequest(t, r, tc.method, tc.path, tc.body); w.Code != tc.want {
+			t.Errorf("%s %s %s: expected %d, got %d, body=%s", tc.method, tc.path, tc.body, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	w := groupRequest(t, r, http.MethodGet, "/api/v1/groups", "")
+	if w.Code != http.StatusOK || w.Body.String() != "[]" {
+		t.Fatalf("expected no groups left, got %d %s", w.Code, w.Body.String())
+	}
+}
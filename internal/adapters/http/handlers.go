@@ -1,16 +1,19 @@
 package http
 
 import (
-	"errors"
-	"fmt"
 	"net/http"
 	coreerrors "safelyyou/internal/core/errors"
 	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/logging"
 	"safelyyou/pkg/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// errInvalidDeviceID rejects a device_id path parameter that is not an ID.
+var errInvalidDeviceID = coreerrors.Invalid("device_id", "is not a valid device ID")
+
 type Handler struct {
 	deviceSvc ports.DeviceService
 }
@@ -24,36 +27,31 @@ func NewHandler(svc ports.DeviceService) *Handler {
 // @Summary Register a heartbeat from a device
 // @Description Register a heartbeat from a device at the given timestamp.
 // @Tags devices
-// @Accept json
-// @Produce json
+// @Accept json,application/x-protobuf,application/x-msgpack
+// @Produce json,application/x-protobuf,application/x-msgpack
 // @Param device_id path string true "Device ID"
 // @Param request body HeartbeatRequest true "Heartbeat payload"
 // @Success 204 "no content"
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /api/v1/devices/{device_id}/heartbeat [post]
 func (h *Handler) PostHeartbeat(c *gin.Context) {
 	deviceID := c.Param("device_id")
 
 	if !utils.IsId(deviceID) {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Msg: "Invalid device ID"})
+		respondError(c, errInvalidDeviceID)
 		return
 	}
 
 	var req HeartbeatRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Msg: "invalid payload: " + err.Error(),
-		})
+	if err := bindBody(c, &req); err != nil {
+		respondError(c, invalidPayload(err))
 		return
 	}
 
-	if err := h.deviceSvc.RecordHeartbeat(deviceID, req.SentAt); err != nil {
-		if errors.Is(err, coreerrors.ErrDeviceNotFound) {
-			c.JSON(http.StatusNotFound, ErrorResponse{Msg: "device not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Msg: "internal error"})
+	if err := h.deviceSvc.RecordHeartbeat(c.Request.Context(), deviceID, req.SentAt); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -64,68 +62,252 @@ func (h *Handler) PostHeartbeat(c *gin.Context) {
 // @Summary Register a stats from a device
 // @Description Add per device statistics.
 // @Tags devices
-// @Accept json
-// @Produce json
+// @Accept json,application/x-protobuf,application/x-msgpack
+// @Produce json,application/x-protobuf,application/x-msgpack
 // @Param device_id path string true "Device ID"
 // @Param request body StatsRequest true "stats payload"
 // @Success 204 "no content"
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /api/v1/devices/{device_id}/stats [post]
 func (h *Handler) PostStats(c *gin.Context) {
 	deviceID := c.Param("device_id")
 
 	if !utils.IsId(deviceID) {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Msg: "Invalid device ID"})
+		respondError(c, errInvalidDeviceID)
 		return
 	}
 
 	var req StatsRequest
+	if err := bindBody(c, &req); err != nil {
+		logging.For("http").WarnContext(c.Request.Context(), "invalid stats payload", "error", err)
+		respondError(c, invalidPayload(err))
+		return
+	}
+
+	if err := h.deviceSvc.RecordStats(c.Request.Context(), deviceID, req.SentAt, req.UploadTime); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDevices godoc
+// @Summary List devices
+// @Description Return every known device, ordered by ID.
+// @Tags devices
+// @Produce json,application/x-msgpack
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {array} DeviceResponse
+// @Success 304 "not modified"
+// @Failure 500 {object} Problem
+// @Router /api/v1/devices [get]
+func (h *Handler) ListDevices(c *gin.Context) {
+	stats, err := h.deviceSvc.ListStats(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	etag, modified := listETag(stats)
+	if notModified(c, etag, modified) {
+		return
+	}
+
+	resp := make([]DeviceResponse, 0, len(stats))
+	for _, s := range stats {
+		resp = append(resp, toDeviceResponse(s))
+	}
+	respond(c, http.StatusOK, resp)
+}
+
+// PostDevice godoc
+// @Summary Add a device
+// @Description Register a device at runtime, in addition to those loaded from the device CSV.
+// @Description Added devices are kept in the device snapshot, when one is configured.
+// @Tags devices
+// @Accept json
+// @Produce json,application/x-msgpack
+// @Param request body DeviceRequest true "Device"
+// @Success 201 {object} DeviceResponse
+// @Failure 400 {object} Problem
+// @Failure 409 {object} Problem
+// @Router /api/v1/devices [post]
+func (h *Handler) PostDevice(c *gin.Context) {
+	var req DeviceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Println("invalid payload STATSREQUEST: " + err.Error())
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Msg: "invalid payload: " + err.Error(),
-		})
+		respondError(c, invalidPayload(err))
 		return
 	}
 
-	if err := h.deviceSvc.RecordStats(deviceID, req.SentAt, req.UploadTime); err != nil {
-		if errors.Is(err, coreerrors.ErrDeviceNotFound) {
-			c.JSON(http.StatusNotFound, ErrorResponse{Msg: "device not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Msg: "internal error"})
+	stats, err := h.deviceSvc.AddDevice(c.Request.Context(), req.DeviceID, req.Site)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Location", "/api/v1/devices/"+stats.ID+"/stats")
+	respond(c, http.StatusCreated, toDeviceResponse(*stats))
+}
+
+// DeleteDevice godoc
+// @Summary Remove a device
+// @Description Forget a device and its history. A device listed in the device CSV comes back, empty, at the next start.
+// @Tags devices
+// @Param device_id path string true "Device ID"
+// @Success 204 "removed"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Router /api/v1/devices/{device_id} [delete]
+func (h *Handler) DeleteDevice(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	if !utils.IsId(deviceID) {
+		respondError(c, errInvalidDeviceID)
+		return
+	}
+
+	if err := h.deviceSvc.RemoveDevice(c.Request.Context(), deviceID); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+// PutLabels godoc
+// @Summary Set device labels
+// @Description Replace the device's labels, e.g. {"labels": {"site": "north", "floor": "2"}}.
+// @Description The "site" label sets the device's site; omitting it clears the site.
+// @Tags devices
+// @Accept json
+// @Produce json,application/x-msgpack
+// @Param device_id path string true "Device ID"
+// @Param request body LabelsRequest true "Labels"
+// @Success 200 {object} DeviceResponse
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Router /api/v1/devices/{device_id}/labels [put]
+func (h *Handler) PutLabels(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	if !utils.IsId(deviceID) {
+		respondError(c, errInvalidDeviceID)
+		return
+	}
+	var req LabelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+
+	stats, err := h.deviceSvc.SetLabels(c.Request.Context(), deviceID, req.Labels)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	respond(c, http.StatusOK, toDeviceResponse(*stats))
+}
+
+// GetOutages godoc
+// @Summary List device outages
+// @Description Return the runs of minutes without a heartbeat, in device time, oldest first.
+// @Description An outage is ongoing while the device is flagged offline. History is limited by SERIES_RETENTION.
+// @Tags devices
+// @Produce json,application/x-msgpack
+// @Param device_id path string true "Device ID"
+// @Param since query string false "RFC 3339 time or a duration back from now, e.g. 24h (default)"
+// @Success 200 {object} OutagesResponse
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Router /api/v1/devices/{device_id}/outages [get]
+func (h *Handler) GetOutages(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	if !utils.IsId(deviceID) {
+		respondError(c, errInvalidDeviceID)
+		return
+	}
+	since, err := parseSince(c.Query("since"), time.Now())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	outages, err := h.deviceSvc.GetOutages(c.Request.Context(), deviceID, since)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	resp := OutagesResponse{DeviceID: deviceID, Since: since.UTC(), Outages: make([]OutageResponse, 0, len(outages))}
+	for _, o := range outages {
+		out := OutageResponse{Start: o.Start, Ongoing: o.Ongoing}
+		if !o.Ongoing {
+			out.End = optionalTime(o.End)
+			out.DurationMs = durationMs(o.End.Sub(o.Start))
+		}
+		resp.Outages = append(resp.Outages, out)
+	}
+	respond(c, http.StatusOK, resp)
+}
+
+// parseSince reads a "since" parameter: an RFC 3339 time or a duration
+// before now. Empty means 24 hours ago.
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if v == "" {
+		return now.Add(-24 * time.Hour), nil
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, coreerrors.Invalid("since", "must be an RFC 3339 time or a positive duration such as 24h")
+}
+
+func toDeviceResponse(s ports.Stats) DeviceResponse {
+	return DeviceResponse{
+		DeviceID:       s.ID,
+		Site:           s.Site,
+		Labels:         s.Labels,
+		HeartbeatCount: s.HeartbeatCount,
+		UploadCount:    s.UploadCount,
+		LastSeenAt:     optionalTime(s.LastSeenAt),
+		ClockSkewed:    s.ClockSkewed,
+		Version:        s.Version,
+	}
+}
+
 // GetStats godoc
 // @Description Return device stats.
 // @Tags devices
-// @Accept json
-// @Produce json
+// @Accept json,application/x-protobuf,application/x-msgpack
+// @Produce json,application/x-protobuf,application/x-msgpack
 // @Param device_id path string true "Device ID"
-// @Success 204 {object} StatsResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {object} StatsResponse
+// @Success 304 "not modified"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /api/v1/devices/{device_id}/stats [get]
 func (h *Handler) GetStats(c *gin.Context) {
 	deviceID := c.Param("device_id")
 
 	if !utils.IsId(deviceID) {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Msg: "Invalid device ID"})
+		respondError(c, errInvalidDeviceID)
 		return
 	}
 
-	stats, err := h.deviceSvc.GetStats(deviceID)
+	stats, err := h.deviceSvc.GetStats(c.Request.Context(), deviceID)
 	if err != nil {
-		if errors.Is(err, coreerrors.ErrDeviceNotFound) {
-			c.JSON(http.StatusNotFound, ErrorResponse{Msg: "device not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Msg: "internal error"})
+		respondError(c, err)
+		return
+	}
+	if notModified(c, statsETag(stats), stats.UpdatedAt) {
 		return
 	}
 
@@ -134,5 +316,5 @@ func (h *Handler) GetStats(c *gin.Context) {
 		AvgUploadTime: stats.AvgUploadTime,
 	}
 
-	c.JSON(http.StatusOK, resp)
+	respond(c, http.StatusOK, resp)
 }
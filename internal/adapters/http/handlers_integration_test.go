@@ -2,6 +2,7 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"math"
 	"net/http"
@@ -37,9 +38,7 @@ const integrationDeviceID = "60-6b-44-84-dc-64"
 // seedDevice simulates that the device was loaded from devices.csv.
 func seedDevice(t *testing.T, repo *memory.DeviceRepository, id string) {
 	t.Helper()
-	if err := repo.WithDevice(id, func(d *domain.DeviceStats) error {
-		return nil
-	}); err != nil {
+	if err := repo.Add(context.Background(), domain.NewDeviceStats(id)); err != nil {
 		t.Fatalf("failed to seed device %q in repo: %v", id, err)
 	}
 }
@@ -2,6 +2,7 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"safelyyou/internal/core/domain"
 	coreerrors "safelyyou/internal/core/errors"
 	"safelyyou/internal/core/ports"
 )
@@ -27,26 +29,72 @@ type testDeviceService struct {
 	statsErr            error
 	getStatsResult      *ports.Stats
 	getStatsErr         error
+	listStatsResult     []ports.Stats
+	listStatsErr        error
+	devices             []string
+	addErr              error
+	removeErr           error
+	outages             []domain.Outage
+	outagesErr          error
+	labelsErr           error
+	lastLabels          map[string]string
+	lastOutagesSince    time.Time
 }
 
-func (s *testDeviceService) RecordHeartbeat(id string, sentAt time.Time) error {
+func (s *testDeviceService) RecordHeartbeat(_ context.Context, id string, sentAt time.Time) error {
 	s.lastHeartbeatID = id
 	s.lastHeartbeatSentAt = sentAt
 	return s.heartbeatErr
 }
 
-func (s *testDeviceService) RecordStats(id string, sentAt time.Time, uploadNs int64) error {
+func (s *testDeviceService) RecordStats(_ context.Context, id string, sentAt time.Time, uploadNs int64) error {
 	s.lastStatsID = id
 	s.lastStatsSentAt = sentAt
 	s.lastUploadTimeNs = uploadNs
 	return s.statsErr
 }
 
-func (s *testDeviceService) GetStats(id string) (*ports.Stats, error) {
+func (s *testDeviceService) GetStats(_ context.Context, id string) (*ports.Stats, error) {
 	s.lastGetStatsID = id
 	return s.getStatsResult, s.getStatsErr
 }
 
+func (s *testDeviceService) ListStats(context.Context) ([]ports.Stats, error) {
+	return s.listStatsResult, s.listStatsErr
+}
+
+func (s *testDeviceService) ListDevices(context.Context) []string {
+	return s.devices
+}
+
+func (s *testDeviceService) Tenants(context.Context) []string {
+	return []string{domain.DefaultTenant}
+}
+
+func (s *testDeviceService) AddDevice(_ context.Context, id, site string) (*ports.Stats, error) {
+	if s.addErr != nil {
+		return nil, s.addErr
+	}
+	return &ports.Stats{ID: id, Site: site}, nil
+}
+
+func (s *testDeviceService) RemoveDevice(context.Context, string) error {
+	return s.removeErr
+}
+
+func (s *testDeviceService) SetLabels(_ context.Context, id string, labels map[string]string) (*ports.Stats, error) {
+	if s.labelsErr != nil {
+		return nil, s.labelsErr
+	}
+	s.lastLabels = labels
+	return &ports.Stats{ID: id, Site: labels["site"], Labels: labels}, nil
+}
+
+func (s *testDeviceService) GetOutages(_ context.Context, _ string, since time.Time) ([]domain.Outage, error) {
+	s.lastOutagesSince = since
+	return s.outages, s.outagesErr
+}
+
 // Use a valid device ID
 const validDeviceID = "60-6b-44-84-dc-64"
 
@@ -484,3 +532,185 @@ func TestGetStats_InternalError_Returns500(t *testing.T) {
 		t.Fatalf("expected status 500, got %d", w.Code)
 	}
 }
+
+//
+// Device management tests
+//
+
+func TestPostDevice_Created(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := NewHandler(&testDeviceService{})
+	r := gin.New()
+	r.POST("/api/v1/devices", h.PostDevice)
+
+	body := []byte(`{"device_id":"` + validDeviceID + `","site":"north"}`)
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if loc := w.Header().Get("Location"); loc != "/api/v1/devices/"+validDeviceID+"/stats" {
+		t.Fatalf("unexpected Location %q", loc)
+	}
+	var resp DeviceResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.DeviceID != validDeviceID || resp.Site != "north" {
+		t.Fatalf("unexpected body %s (%v)", w.Body.String(), err)
+	}
+}
+
+func TestPostDevice_Errors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"missing id", `{"site":"north"}`, nil, http.StatusBadRequest},
+		{"exists", `{"device_id":"` + validDeviceID + `"}`, coreerrors.ErrDeviceExists, http.StatusConflict},
+	}
+	for _, tc := range cases {
+		h := NewHandler(&testDeviceService{addErr: tc.err})
+		r := gin.New()
+		r.POST("/api/v1/devices", h.PostDevice)
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices", bytes.NewReader([]byte(tc.body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tc.want {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.want, w.Code)
+		}
+	}
+}
+
+func TestDeleteDevice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		id   string
+		err  error
+		want int
+	}{
+		{validDeviceID, nil, http.StatusNoContent},
+		{validDeviceID, coreerrors.ErrDeviceNotFound, http.StatusNotFound},
+		{"not-an-id", nil, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		h := NewHandler(&testDeviceService{removeErr: tc.err})
+		r := gin.New()
+		r.DELETE("/api/v1/devices/:device_id", h.DeleteDevice)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/api/v1/devices/"+tc.id, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tc.want {
+			t.Errorf("%s (%v): expected status %d, got %d", tc.id, tc.err, tc.want, w.Code)
+		}
+	}
+}
+
+func TestPutLabels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := &testDeviceService{}
+	h := NewHandler(svc)
+	r := gin.New()
+	r.PUT("/api/v1/devices/:device_id/labels", h.PutLabels)
+
+	body := []byte(`{"labels":{"site":"north","floor":"2"}}`)
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/devices/"+validDeviceID+"/labels", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	var resp DeviceResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Site != "north" || resp.Labels["floor"] != "2" {
+		t.Fatalf("unexpected body %s (%v)", w.Body.String(), err)
+	}
+	if svc.lastLabels["floor"] != "2" {
+		t.Fatalf("labels not passed to service: %v", svc.lastLabels)
+	}
+
+	svc.labelsErr = coreerrors.ErrDeviceNotFound
+	req, _ = http.NewRequest(http.MethodPut, "/api/v1/devices/"+validDeviceID+"/labels", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestGetOutages_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	start := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	svc := &testDeviceService{outages: []domain.Outage{
+		{Start: start, End: start.Add(5 * time.Minute)},
+		{Start: start.Add(time.Hour), Ongoing: true},
+	}}
+	h := NewHandler(svc)
+	r := gin.New()
+	r.GET("/api/v1/devices/:device_id/outages", h.GetOutages)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/devices/"+validDeviceID+"/outages?since=2025-11-09T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if !svc.lastOutagesSince.Equal(time.Date(2025, 11, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected since %v", svc.lastOutagesSince)
+	}
+	var resp OutagesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if len(resp.Outages) != 2 || resp.Outages[0].DurationMs != 300000 || resp.Outages[0].End == nil {
+		t.Fatalf("unexpected first outage %s", w.Body.String())
+	}
+	if !resp.Outages[1].Ongoing || resp.Outages[1].End != nil {
+		t.Fatalf("unexpected ongoing outage %s", w.Body.String())
+	}
+}
+
+func TestGetOutages_BadSince_Returns400(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := NewHandler(&testDeviceService{})
+	r := gin.New()
+	r.GET("/api/v1/devices/:device_id/outages", h.GetOutages)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/devices/"+validDeviceID+"/outages?since=yesterday", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2025, 11, 9, 12, 0, 0, 0, time.UTC)
+	if got, _ := parseSince("", now); !got.Equal(now.Add(-24 * time.Hour)) {
+		t.Errorf("default since = %v", got)
+	}
+	if got, _ := parseSince("90m", now); !got.Equal(now.Add(-90 * time.Minute)) {
+		t.Errorf("90m since = %v", got)
+	}
+	if _, err := parseSince("-1h", now); err == nil {
+		t.Error("expected a negative duration to be rejected")
+	}
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"net/http"
+	"safelyyou/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetStatsV2 godoc
+// @Summary Device stats with explicit units
+// @Description Return device stats with numeric fields in explicit units, the uptime window, counts and last-seen timestamps.
+// @Tags devices
+// @Produce json,application/x-msgpack
+// @Param device_id path string true "Device ID"
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {object} StatsV2Response
+// @Success 304 "not modified"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /api/v2/devices/{device_id}/stats [get]
+func (h *Handler) GetStatsV2(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	if !utils.IsId(deviceID) {
+		respondError(c, errInvalidDeviceID)
+		return
+	}
+
+	stats, err := h.deviceSvc.GetStats(c.Request.Context(), deviceID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if notModified(c, statsETag(stats), stats.UpdatedAt) {
+		return
+	}
+
+	respond(c, http.StatusOK, toStatsV2Response(deviceID, stats))
+}
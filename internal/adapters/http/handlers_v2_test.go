@@ -0,0 +1,97 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// seedTraffic sends three heartbeats one minute apart and two uploads of
+// 30s and 90s through the v1 ingestion routes.
+func seedTraffic(t *testing.T, r *gin.Engine) {
+	t.Helper()
+	post := func(path, body string) {
+		req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("POST %s: expected 204, got %d, body=%s", path, w.Code, w.Body.String())
+		}
+	}
+	base := "/api/v1/devices/" + integrationDeviceID
+	for _, ts := range []string{"10:00", "10:01", "10:02"} {
+		post(base+"/heartbeat", `{"sent_at":"2025-11-09T`+ts+`:00Z"}`)
+	}
+	post(base+"/stats", `{"sent_at":"2025-11-09T10:02:00Z","upload_time":30000000000}`)
+	post(base+"/stats", `{"sent_at":"2025-11-09T10:02:00Z","upload_time":90000000000}`)
+}
+
+func getBody(t *testing.T, r *gin.Engine, path string) []byte {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: expected 200, got %d, body=%s", path, w.Code, w.Body.String())
+	}
+	return w.Body.Bytes()
+}
+
+// The simulator parses v1 responses, so their bytes must not change.
+func TestIntegration_GetStatsV1_WireFormatUnchanged(t *testing.T) {
+	r, repo := newIntegrationServer(t)
+	seedDevice(t, repo, integrationDeviceID)
+	seedTraffic(t, r)
+
+	got := getBody(t, r, "/api/v1/devices/"+integrationDeviceID+"/stats")
+	if want := `{"uptime":150,"avg_upload_time":"1m0s"}`; string(got) != want {
+		t.Fatalf("v1 body changed:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestIntegration_GetStatsV2(t *testing.T) {
+	r, repo := newIntegrationServer(t)
+	seedDevice(t, repo, integrationDeviceID)
+	seedTraffic(t, r)
+
+	var resp StatsV2Response
+	if err := json.Unmarshal(getBody(t, r, "/api/v2/devices/"+integrationDeviceID+"/stats"), &resp); err != nil {
+		t.Fatalf("failed to decode v2 response: %v", err)
+	}
+
+	start := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Minute)
+	if resp.DeviceID != integrationDeviceID || resp.UptimeRatio != 1.5 || resp.AvgUploadMs != 60000 {
+		t.Errorf("unexpected stats %+v", resp)
+	}
+	if resp.Window.Start == nil || !resp.Window.Start.Equal(start) ||
+		resp.Window.End == nil || !resp.Window.End.Equal(end) || resp.Window.Minutes != 2 {
+		t.Errorf("unexpected window %+v", resp.Window)
+	}
+	if resp.Counts != (StatsCountsV2{Heartbeats: 3, Uploads: 2}) {
+		t.Errorf("unexpected counts %+v", resp.Counts)
+	}
+	if resp.LastHeartbeatAt == nil || !resp.LastHeartbeatAt.Equal(end) || resp.LastSeenAt == nil {
+		t.Errorf("unexpected last-seen fields %v %v", resp.LastHeartbeatAt, resp.LastSeenAt)
+	}
+}
+
+func TestIntegration_GetStatsV2_NoTrafficHasNullTimes(t *testing.T) {
+	r, repo := newIntegrationServer(t)
+	seedDevice(t, repo, integrationDeviceID)
+
+	body := getBody(t, r, "/api/v2/devices/"+integrationDeviceID+"/stats")
+	var raw map[string]any
+	if err := json.Unmarshal(body, &raw); err != nil {
+		t.Fatalf("failed to decode v2 response: %v", err)
+	}
+	if raw["last_seen_at"] != nil || raw["window"].(map[string]any)["start"] != nil {
+		t.Errorf("expected null timestamps before any heartbeat, got %s", body)
+	}
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"mime"
+	"net/http"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importFormats maps the accepted request media types to import formats.
+var importFormats = map[string]ports.ImportFormat{
+	"text/csv":             ports.ImportCSV,
+	"application/x-ndjson": ports.ImportNDJSON,
+	"application/ndjson":   ports.ImportNDJSON,
+	"application/jsonl":    ports.ImportNDJSON,
+}
+
+type ImportHandler struct {
+	importSvc ports.ImportService
+}
+
+// NewImportHandler constructs a handler that depends on the ImportService interface.
+func NewImportHandler(svc ports.ImportService) *ImportHandler {
+	return &ImportHandler{importSvc: svc}
+}
+
+// PostImport godoc
+// @Summary Import historical heartbeats and upload stats
+// @Description Apply a CSV or NDJSON file of rows in order, as if each had been posted to the ingestion endpoints.
+// @Description Columns/keys: type (heartbeat or stats, inferred from upload_time when omitted), device_id, sent_at (RFC 3339), upload_time (ns, stats only).
+// @Description Invalid rows are skipped and listed in the report. With dry_run=true nothing is recorded.
+// @Description Imported rows only add to the device history: they do not update last_seen_at, bring devices back online or publish webhook events.
+// @Description Plain bodies are streamed; compressed or signed bodies are read whole and limited to 10 MiB once decoded (413 beyond).
+// @Tags admin
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param format query string false "csv or ndjson; defaults to the Content-Type"
+// @Param dry_run query bool false "Validate only"
+// @Success 200 {object} ImportReportResponse
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /api/v1/admin/import [post]
+func (h *ImportHandler) PostImport(c *gin.Context) {
+	opts, err := parseImportOptions(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	report, err := h.importSvc.Import(c.Request.Context(), c.Request.Body, opts)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toImportReportResponse(*report))
+}
+
+func parseImportOptions(c *gin.Context) (ports.ImportOptions, error) {
+	var opts ports.ImportOptions
+	if v := c.Query("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, coreerrors.Invalid("dry_run", "must be a boolean")
+		}
+		opts.DryRun = dryRun
+	}
+
+	switch f := ports.ImportFormat(c.Query("format")); f {
+	case ports.ImportCSV, ports.ImportNDJSON:
+		opts.Format = f
+	case "":
+		mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		var ok bool
+		if opts.Format, ok = importFormats[mediaType]; !ok {
+			return opts, coreerrors.Invalid("format", "must be given, or the Content-Type must be text/csv or application/x-ndjson")
+		}
+	default:
+		return opts, coreerrors.Invalid("format", "must be csv or ndjson")
+	}
+	return opts, nil
+}
+
+func toImportReportResponse(r ports.ImportReport) ImportReportResponse {
+	resp := ImportReportResponse{
+		DryRun:          r.DryRun,
+		Rows:            r.Rows,
+		Accepted:        r.Accepted,
+		Rejected:        r.Rejected,
+		Heartbeats:      r.Heartbeats,
+		Stats:           r.Stats,
+		Errors:          make([]ImportRowResponse, 0, len(r.Errors)),
+		ErrorsTruncated: r.ErrorsTruncated,
+	}
+	for _, e := range r.Errors {
+		resp.Errors = append(resp.Errors, ImportRowResponse{Line: e.Line, DeviceID: e.DeviceID, Reason: e.Reason})
+	}
+	return resp
+}
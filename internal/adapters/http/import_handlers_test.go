@@ -0,0 +1,102 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/services"
+)
+
+func newImportServer(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := memory.NewDeviceRepository()
+	seedDevice(t, repo, integrationDeviceID)
+	deviceSvc := services.NewDeviceService(repo)
+	r := gin.New()
+	RegisterRoutes(r, deviceSvc)
+	RegisterImportRoutes(r, services.NewImportService(deviceSvc))
+	return r
+}
+
+func postImport(t *testing.T, r *gin.Engine, query, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/admin/import"+query, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPostImport_CSVThenStats(t *testing.T) {
+	r := newImportServer(t)
+	body := "type,device_id,sent_at,upload_time\n" +
+		"heartbeat," + integrationDeviceID + ",2025-11-09T10:00:00Z,\n" +
+		"heartbeat," + integrationDeviceID + ",2025-11-09T10:02:00Z,\n" +
+		"stats," + integrationDeviceID + ",2025-11-09T10:01:00Z,60000000000\n" +
+		"stats,not-an-id,2025-11-09T10:01:00Z,1\n"
+
+	w := postImport(t, r, "", "text/csv; charset=utf-8", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	var report ImportReportResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid report: %v", err)
+	}
+	if report.DryRun || report.Accepted != 3 || report.Rejected != 1 || len(report.Errors) != 1 || report.Errors[0].Line != 5 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/devices/"+integrationDeviceID+"/stats", nil)
+	sw := httptest.NewRecorder()
+	r.ServeHTTP(sw, req)
+	if got := sw.Body.String(); got != `{"uptime":100,"avg_upload_time":"1m0s"}` {
+		t.Errorf("unexpected stats after import: %s", got)
+	}
+}
+
+func TestPostImport_DryRunNDJSON(t *testing.T) {
+	r := newImportServer(t)
+	body := `{"device_id":"` + integrationDeviceID + `","sent_at":"2025-11-09T10:00:00Z"}` + "\n"
+
+	w := postImport(t, r, "?format=ndjson&dry_run=true", "application/octet-stream", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	var report ImportReportResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid report: %v", err)
+	}
+	if !report.DryRun || report.Accepted != 1 || report.Errors == nil {
+		t.Fatalf("unexpected report %s", w.Body.String())
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/devices/"+integrationDeviceID+"/stats", nil)
+	sw := httptest.NewRecorder()
+	r.ServeHTTP(sw, req)
+	if got := sw.Body.String(); got != `{"uptime":0,"avg_upload_time":"0s"}` {
+		t.Errorf("dry run recorded data: %s", got)
+	}
+}
+
+func TestPostImport_BadOptionsAreProblems(t *testing.T) {
+	r := newImportServer(t)
+
+	for _, tc := range []struct{ query, contentType string }{
+		{"", "application/json"},
+		{"?format=xml", "text/csv"},
+		{"?dry_run=maybe", "text/csv"},
+	} {
+		w := postImport(t, r, tc.query, tc.contentType, "device_id,sent_at\n")
+		if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != mimeProblem {
+			t.Errorf("%s %s: expected a 400 problem, got %d %s", tc.query, tc.contentType, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"safelyyou/pkg/logging"
+	"safelyyou/pkg/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the gin context key holding the request ID.
+const requestIDKey = "request_id"
+
+// validRequestID bounds what we accept from clients before echoing it back
+// and writing it to logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// heartbeatRoute is sampled in the access log; it dominates traffic.
+const heartbeatRoute = "/api/v1/devices/:device_id/heartbeat"
+
+// RequestID reuses a well-formed incoming X-Request-ID or generates one,
+// echoes it on the response and attaches it (and the device ID, on device
+// routes) to the request context so every log record of the request
+// carries them.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = utils.NewID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+
+		attrs := []slog.Attr{slog.String("request_id", id)}
+		if deviceID := c.Param("device_id"); deviceID != "" {
+			attrs = append(attrs, slog.String("device_id", deviceID))
+		}
+		c.Request = c.Request.WithContext(logging.WithAttrs(c.Request.Context(), attrs...))
+		c.Next()
+	}
+}
+
+// AccessLogConfig tunes the access log.
+type AccessLogConfig struct {
+	// HeartbeatSampleEvery keeps one successful heartbeat entry out of
+	// this many; 0 or 1 logs them all. Failures are always logged.
+	HeartbeatSampleEvery uint64
+}
+
+// AccessLog writes one structured record per request, replacing gin's
+// default text logger.
+func AccessLog(cfg AccessLogConfig) gin.HandlerFunc {
+	sampler := logging.NewSampler(cfg.HeartbeatSampleEvery)
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		route := c.FullPath()
+		if status < http.StatusBadRequest && route == heartbeatRoute && !sampler.Allow() {
+			return
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		logging.For("http").LogAttrs(c.Request.Context(), level, "request",
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// Recovery turns a panic into a 500 and logs it with the request's
+// attributes, replacing gin's default recovery writer.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err any) {
+		logging.For("http").ErrorContext(c.Request.Context(), "panic recovered",
+			"panic", err, "path", c.Request.URL.Path)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"safelyyou/pkg/logging"
+)
+
+func newLoggingRouter(t *testing.T, sampleEvery uint64) (*gin.Engine, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	logging.Setup(logging.Config{Level: slog.LevelInfo, Output: &buf})
+	t.Cleanup(func() { logging.Setup(logging.Config{Level: slog.LevelInfo}) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID(), AccessLog(AccessLogConfig{HeartbeatSampleEvery: sampleEvery}), Recovery())
+	h := NewHandler(&testDeviceService{})
+	r.POST("/api/v1/devices/:device_id/heartbeat", h.PostHeartbeat)
+	r.POST("/api/v1/devices/:device_id/stats", h.PostStats)
+	r.GET("/panic", func(*gin.Context) { panic("boom") })
+	return r, &buf
+}
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid JSON log line %q: %v", line, err)
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+func postHeartbeat(r *gin.Engine, requestID string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices/"+validDeviceID+"/heartbeat",
+		strings.NewReader(`{"sent_at":"2025-11-09T10:00:00Z"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if requestID != "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequestID_HonoursIncomingAndGeneratesOtherwise(t *testing.T) {
+	r, buf := newLoggingRouter(t, 0)
+
+	w := postHeartbeat(r, "abc-123")
+	if got := w.Header().Get(RequestIDHeader); got != "abc-123" {
+		t.Fatalf("expected incoming request ID to be echoed, got %q", got)
+	}
+
+	w = postHeartbeat(r, "")
+	generated := w.Header().Get(RequestIDHeader)
+	if generated == "" {
+		t.Fatalf("expected a generated request ID")
+	}
+
+	w = postHeartbeat(r, "bad id\nwith newline")
+	if got := w.Header().Get(RequestIDHeader); got == "bad id\nwith newline" || got == "" {
+		t.Fatalf("expected malformed request ID to be replaced, got %q", got)
+	}
+
+	recs := logRecords(t, buf)
+	if len(recs) != 3 {
+		t.Fatalf("expected 3 access log records, got %d", len(recs))
+	}
+	if recs[0]["request_id"] != "abc-123" || recs[0]["device_id"] != validDeviceID {
+		t.Fatalf("expected request_id and device_id on access log, got %v", recs[0])
+	}
+	if recs[1]["request_id"] != generated {
+		t.Fatalf("expected generated request_id in log, got %v", recs[1]["request_id"])
+	}
+	if recs[0]["route"] != heartbeatRoute || recs[0]["status"] != float64(http.StatusNoContent) {
+		t.Fatalf("unexpected access log fields: %v", recs[0])
+	}
+}
+
+func TestAccessLog_SamplesSuccessfulHeartbeats(t *testing.T) {
+	r, buf := newLoggingRouter(t, 5)
+
+	for i := 0; i < 10; i++ {
+		postHeartbeat(r, "")
+	}
+	if n := len(logRecords(t, buf)); n != 2 {
+		t.Fatalf("expected 2 sampled heartbeat records out of 10, got %d", n)
+	}
+}
+
+func TestPostStats_LogsInvalidPayloadWithDeviceID(t *testing.T) {
+	r, buf := newLoggingRouter(t, 0)
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices/"+validDeviceID+"/stats",
+		strings.NewReader(`{"upload_time":"fast"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	recs := logRecords(t, buf)
+	if len(recs) != 2 {
+		t.Fatalf("expected validation and access log records, got %d: %s", len(recs), buf.String())
+	}
+	if recs[0]["msg"] != "invalid stats payload" || recs[0]["level"] != "WARN" ||
+		recs[0]["device_id"] != validDeviceID || recs[0]["component"] != "http" {
+		t.Fatalf("unexpected validation log record: %v", recs[0])
+	}
+}
+
+func TestRecovery_LogsPanicAndReturns500(t *testing.T) {
+	r, buf := newLoggingRouter(t, 0)
+
+	req, _ := http.NewRequest(http.MethodGet, "/panic", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+	if recs := logRecords(t, buf); len(recs) == 0 || recs[0]["msg"] != "panic recovered" {
+		t.Fatalf("expected panic log record, got %s", buf.String())
+	}
+}
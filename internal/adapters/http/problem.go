@@ -0,0 +1,175 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"reflect"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/pkg/logging"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// mimeProblem is the RFC 7807 media type used for every error response,
+// except to protobuf clients, which get the ErrorResponse message instead.
+const mimeProblem = "application/problem+json"
+
+// problemTypePrefix prefixes the error kind to form the problem type URI.
+const problemTypePrefix = "urn:fleet:problem:"
+
+// Problem is an RFC 7807 problem details body. RequestID and Errors are
+// extension members.
+type Problem struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail,omitempty"`
+	Instance  string         `json:"instance,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Errors    []FieldProblem `json:"errors,omitempty"`
+}
+
+// FieldProblem is one rejected input field.
+type FieldProblem struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// kindStatus maps core error kinds to HTTP statuses; anything else is 500.
+var kindStatus = map[coreerrors.Kind]int{
+	coreerrors.KindValidation:   http.StatusBadRequest,
+	coreerrors.KindNotFound:     http.StatusNotFound,
+	coreerrors.KindConflict:     http.StatusConflict,
+	coreerrors.KindRateLimited:  http.StatusTooManyRequests,
+	coreerrors.KindUnauthorized: http.StatusUnauthorized,
+	coreerrors.KindUnavailable:  http.StatusServiceUnavailable,
+	coreerrors.KindForbidden:    http.StatusForbidden,
+}
+
+func init() {
+	// Report validation failures with the JSON field names clients send.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// respondError maps err to its problem response and aborts the request.
+// Internal errors are logged and their details withheld from the client.
+func respondError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeProblem(c, newProblem(c, http.StatusRequestEntityTooLarge,
+			"body exceeds "+strconv.FormatInt(tooLarge.Limit, 10)+" bytes"))
+		return
+	}
+
+	kind := coreerrors.KindOf(err)
+	status, ok := kindStatus[kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	p := newProblem(c, status, err.Error())
+	p.Type = problemTypePrefix + kind.String()
+	for _, f := range coreerrors.FieldsOf(err) {
+		p.Errors = append(p.Errors, FieldProblem{Field: f.Field, Reason: f.Reason})
+	}
+
+	var ce *coreerrors.Error
+	if errors.As(err, &ce) && ce.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(ce.RetryAfter.Seconds()))))
+	}
+	switch kind {
+	case coreerrors.KindUnauthorized:
+		c.Header("WWW-Authenticate", `Bearer realm="fleet"`)
+	case coreerrors.KindInternal:
+		logging.For("http").ErrorContext(c.Request.Context(), "request failed", "error", err)
+		p.Detail = "internal error"
+	}
+	writeProblem(c, p)
+}
+
+// newProblem returns a problem of the generic type for status.
+func newProblem(c *gin.Context, status int, detail string) Problem {
+	return Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		RequestID: c.Writer.Header().Get(RequestIDHeader),
+	}
+}
+
+// writeProblem writes p and aborts the request.
+func writeProblem(c *gin.Context, p Problem) {
+	switch c.NegotiateFormat(mimeProblem, binding.MIMEJSON, mimeProtobuf, mimeProtobuf2) {
+	case mimeProtobuf, mimeProtobuf2:
+		respond(c, p.Status, ErrorResponse{Msg: p.Detail})
+	default:
+		c.Render(p.Status, problemRender{p})
+	}
+	c.Abort()
+}
+
+type problemRender struct{ p Problem }
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	b, err := json.Marshal(r.p)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", mimeProblem)
+}
+
+// invalidPayload turns a body decoding error into a validation error,
+// listing the offending fields when the binding validator reports them.
+// Oversized bodies are passed through for respondError to answer 413.
+func invalidPayload(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return coreerrors.New(coreerrors.KindValidation, "invalid payload: "+err.Error())
+	}
+	fields := make([]coreerrors.FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		fields = append(fields, coreerrors.FieldError{Field: fe.Field(), Reason: validationReason(fe)})
+	}
+	return coreerrors.New(coreerrors.KindValidation, "invalid payload: "+coreerrors.Join(fields), fields...)
+}
+
+func validationReason(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "gte":
+		return "must be >= " + fe.Param()
+	case "lte":
+		return "must be <= " + fe.Param()
+	case "oneof":
+		return "must be one of " + fe.Param()
+	default:
+		return "failed " + fe.Tag() + " validation"
+	}
+}
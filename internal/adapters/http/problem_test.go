@@ -0,0 +1,83 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	coreerrors "safelyyou/internal/core/errors"
+)
+
+func doProblemRequest(t *testing.T, svc *testDeviceService, method, path, body string) (*httptest.ResponseRecorder, Problem) {
+	t.Helper()
+	r := newCodecRouter(svc)
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != mimeProblem {
+		t.Fatalf("expected Content-Type %s, got %q", mimeProblem, ct)
+	}
+	var p Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("invalid problem body %q: %v", w.Body.String(), err)
+	}
+	if p.Status != w.Code || p.Title != http.StatusText(w.Code) || p.Instance != path {
+		t.Errorf("inconsistent problem %+v for status %d", p, w.Code)
+	}
+	return w, p
+}
+
+func TestProblem_BindingErrorsListFields(t *testing.T) {
+	path := "/api/v1/devices/" + validDeviceID + "/stats"
+	w, p := doProblemRequest(t, &testDeviceService{}, http.MethodPost, path, `{"upload_time":-1}`)
+
+	if w.Code != http.StatusBadRequest || p.Type != problemTypePrefix+"validation" {
+		t.Fatalf("expected a 400 validation problem, got %d %+v", w.Code, p)
+	}
+	if len(p.Errors) != 1 || p.Errors[0] != (FieldProblem{Field: "upload_time", Reason: "must be >= 0"}) {
+		t.Errorf("unexpected field errors %+v", p.Errors)
+	}
+}
+
+func TestProblem_MapsServiceErrors(t *testing.T) {
+	path := "/api/v1/devices/" + validDeviceID + "/stats"
+	cases := []struct {
+		name     string
+		err      error
+		status   int
+		kind     string
+		detail   string
+		nFields  int
+		retryHdr string
+	}{
+		{"validation", coreerrors.Invalid("upload_time", "must be >= 0"), http.StatusBadRequest, "validation", "upload_time must be >= 0", 1, ""},
+		{"not found", coreerrors.ErrDeviceNotFound, http.StatusNotFound, "not-found", "device not found", 0, ""},
+		{"conflict", coreerrors.New(coreerrors.KindConflict, "already exists"), http.StatusConflict, "conflict", "already exists", 0, ""},
+		{"rate limited", coreerrors.RateLimited(1500 * time.Millisecond), http.StatusTooManyRequests, "rate-limited", "rate limit exceeded", 0, "2"},
+		{"unavailable", coreerrors.New(coreerrors.KindUnavailable, "store offline"), http.StatusServiceUnavailable, "unavailable", "store offline", 0, ""},
+		{"internal", errors.New("disk on fire"), http.StatusInternalServerError, "internal", "internal error", 0, ""},
+	}
+	for _, tc := range cases {
+		w, p := doProblemRequest(t, &testDeviceService{statsErr: tc.err}, http.MethodPost, path,
+			`{"sent_at":"2024-01-01T00:00:00Z","upload_time":1}`)
+		if w.Code != tc.status || p.Type != problemTypePrefix+tc.kind || p.Detail != tc.detail || len(p.Errors) != tc.nFields {
+			t.Errorf("%s: unexpected response %d %+v", tc.name, w.Code, p)
+		}
+		if got := w.Header().Get("Retry-After"); got != tc.retryHdr {
+			t.Errorf("%s: expected Retry-After %q, got %q", tc.name, tc.retryHdr, got)
+		}
+	}
+}
+
+func TestProblem_InvalidDeviceID(t *testing.T) {
+	w, p := doProblemRequest(t, &testDeviceService{}, http.MethodGet, "/api/v1/devices/not-an-id/stats", "")
+	if w.Code != http.StatusBadRequest || len(p.Errors) != 1 || p.Errors[0].Field != "device_id" {
+		t.Fatalf("unexpected response %d %+v", w.Code, p)
+	}
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/ratelimit"
+	"safelyyou/pkg/utils"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ingestion routes that can be rate limited.
+const (
+	HeartbeatRoute = heartbeatRoute
+	StatsRoute     = "/api/v1/devices/:device_id/stats"
+)
+
+// rateLimitIdle is how long an unused bucket is kept.
+const rateLimitIdle = 10 * time.Minute
+
+// RateLimitConfig configures NewRateLimiter.
+type RateLimitConfig struct {
+	// Routes maps a route template (e.g. HeartbeatRoute) to its limits.
+	// POST requests to routes not listed here are not limited.
+	Routes map[string]ratelimit.Rule
+	// DeviceOverride, when set, returns a per-device limit that replaces
+	// the route's device limit.
+	DeviceOverride func(ctx context.Context, deviceID string) (ratelimit.Limit, bool)
+}
+
+// RateLimitCounters are the limiter's decisions since start.
+type RateLimitCounters struct {
+	Allowed         uint64 `json:"allowed"`
+	DeviceThrottled uint64 `json:"device_throttled"`
+	IPThrottled     uint64 `json:"ip_throttled"`
+}
+
+// RateLimiter throttles ingestion per device and per client IP with token
+// buckets, answering 429 with Retry-After when a bucket is empty.
+type RateLimiter struct {
+	cfg     RateLimitConfig
+	buckets *ratelimit.Limiter
+	now     func() time.Time
+
+	allowed         atomic.Uint64
+	deviceThrottled atomic.Uint64
+	ipThrottled     atomic.Uint64
+}
+
+// NewRateLimiter creates a RateLimiter from cfg.
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	return &RateLimiter{cfg: cfg, buckets: ratelimit.NewLimiter(rateLimitIdle), now: time.Now}
+}
+
+// Handler returns the middleware. Only POST requests are limited so stats
+// reads are never throttled by a device's own ingestion.
+func (l *RateLimiter) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		rule, ok := l.cfg.Routes[route]
+		if !ok || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+		// Every ID would get its own bucket, so invalid ones are refused
+		// before they reach the limiter.
+		deviceID := c.Param("device_id")
+		if !utils.IsId(deviceID) {
+			respondError(c, errInvalidDeviceID)
+			return
+		}
+		now := l.now()
+
+		// The IP token is given back when the device bucket refuses the
+		// request, so that a device over its limit does not spend the
+		// allowance of the other devices behind the same address.
+		cancelIP, allowed, wait := l.buckets.Reserve("ip|"+route+"|"+c.ClientIP(), rule.IP, now)
+		if !allowed {
+			l.ipThrottled.Add(1)
+			respondError(c, coreerrors.RateLimited(wait))
+			return
+		}
+
+		limit := rule.Device
+		if l.cfg.DeviceOverride != nil {
+			if override, ok := l.cfg.DeviceOverride(c.Request.Context(), deviceID); ok {
+				limit = override
+			}
+		}
+		key := "device|" + route + "|" + ports.TenantFrom(c.Request.Context()) + "/" + deviceID
+		if allowed, wait := l.buckets.Allow(key, limit, now); !allowed {
+			cancelIP()
+			l.deviceThrottled.Add(1)
+			respondError(c, coreerrors.RateLimited(wait))
+			return
+		}
+
+		l.allowed.Add(1)
+		c.Next()
+	}
+}
+
+// Counters returns a snapshot of the limiter's decisions.
+func (l *RateLimiter) Counters() RateLimitCounters {
+	return RateLimitCounters{
+		Allowed:         l.allowed.Load(),
+		DeviceThrottled: l.deviceThrottled.Load(),
+		IPThrottled:     l.ipThrottled.Load(),
+	}
+}
@@ -0,0 +1,147 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"safelyyou/pkg/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRateLimitedRouter(cfg RateLimitConfig) (*gin.Engine, *RateLimiter) {
+	gin.SetMode(gin.TestMode)
+	limiter := NewRateLimiter(cfg)
+	now := time.Unix(1700000000, 0)
+	limiter.now = func() time.Time { return now }
+
+	r := gin.New()
+	r.Use(limiter.Handler())
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	r.POST(HeartbeatRoute, ok)
+	r.POST(StatsRoute, ok)
+	r.GET(StatsRoute, ok)
+	return r, limiter
+}
+
+func postFrom(r http.Handler, method, path, ip string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	req.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiter_PerDevice(t *testing.T) {
+	r, limiter := newRateLimitedRouter(RateLimitConfig{Routes: map[string]ratelimit.Rule{
+		HeartbeatRoute: {Device: ratelimit.Limit{Rate: 1, Burst: 2}},
+	}})
+
+	for i := 0; i < 2; i++ {
+		if w := postFrom(r, http.MethodPost, "/api/v1/devices/60-6b-44-84-dc-0a/heartbeat", "10.0.0.1"); w.Code != http.StatusNoContent {
+			t.Fatalf("request %d: expected 204, got %d", i, w.Code)
+		}
+	}
+	w := postFrom(r, http.MethodPost, "/api/v1/devices/60-6b-44-84-dc-0a/heartbeat", "10.0.0.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the burst is spent, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("expected Retry-After 1, got %q", got)
+	}
+
+	if w := postFrom(r, http.MethodPost, "/api/v1/devices/60-6b-44-84-dc-0b/heartbeat", "10.0.0.1"); w.Code != http.StatusNoContent {
+		t.Errorf("other devices must have their own bucket, got %d", w.Code)
+	}
+	if w := postFrom(r, http.MethodPost, "/api/v1/devices/60-6b-44-84-dc-0a/stats", "10.0.0.1"); w.Code != http.StatusNoContent {
+		t.Errorf("unconfigured routes must not be limited, got %d", w.Code)
+	}
+
+	got := limiter.Counters()
+	if got.Allowed != 3 || got.DeviceThrottled != 1 || got.IPThrottled != 0 {
+		t.Errorf("unexpected counters %+v", got)
+	}
+}
+
+func TestRateLimiter_PerIP(t *testing.T) {
+	r, limiter := newRateLimitedRouter(RateLimitConfig{Routes: map[string]ratelimit.Rule{
+		StatsRoute: {IP: ratelimit.Limit{Rate: 1, Burst: 1}},
+	}})
+
+	if w := postFrom(r, http.MethodPost, "/api/v1/devices/60-6b-44-84-dc-0a/stats", "10.0.0.1"); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if w := postFrom(r, http.MethodPost, "/api/v1/devices/60-6b-44-84-dc-0b/stats", "10.0.0.1"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the IP bucket to span devices, got %d", w.Code)
+	}
+	if w := postFrom(r, http.MethodPost, "/api/v1/devices/60-6b-44-84-dc-0b/stats", "10.0.0.2"); w.Code != http.StatusNoContent {
+		t.Errorf("expected a separate bucket per IP, got %d", w.Code)
+	}
+	if w := postFrom(r, http.MethodGet, "/api/v1/devices/60-6b-44-84-dc-0a/stats", "10.0.0.1"); w.Code != http.StatusNoContent {
+		t.Errorf("reads must not be limited, got %d", w.Code)
+	}
+	if got := limiter.Counters().IPThrottled; got != 1 {
+		t.Errorf("expected 1 IP throttle, got %d", got)
+	}
+}
+
+func TestRateLimiter_DeviceOverride(t *testing.T) {
+	r, _ := newRateLimitedRouter(RateLimitConfig{
+		Routes: map[string]ratelimit.Rule{
+			HeartbeatRoute: {Device: ratelimit.Limit{Rate: 1, Burst: 1}},
+		},
+		DeviceOverride: func(_ context.Context, id string) (ratelimit.Limit, bool) {
+			if id == "60-6b-44-84-dc-0c" {
+				return ratelimit.Limit{Rate: 10, Burst: 5}, true
+			}
+			return ratelimit.Limit{}, false
+		},
+	})
+
+	for i := 0; i < 5; i++ {
+		if w := postFrom(r, http.MethodPost, "/api/v1/devices/60-6b-44-84-dc-0c/heartbeat", "10.0.0.1"); w.Code != http.StatusNoContent {
+			t.Fatalf("request %d: override should allow a burst of 5, got %d", i, w.Code)
+		}
+	}
+	postFrom(r, http.MethodPost, "/api/v1/devices/60-6b-44-84-dc-0d/heartbeat", "10.0.0.1")
+	if w := postFrom(r, http.MethodPost, "/api/v1/devices/60-6b-44-84-dc-0d/heartbeat", "10.0.0.1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("devices without override keep the route limit, got %d", w.Code)
+	}
+}
+
+func TestRateLimiter_DeviceThrottleKeepsTheIPToken(t *testing.T) {
+	r, limiter := newRateLimitedRouter(RateLimitConfig{Routes: map[string]ratelimit.Rule{
+		HeartbeatRoute: {Device: ratelimit.Limit{Rate: 1, Burst: 1}, IP: ratelimit.Limit{Rate: 1, Burst: 2}},
+	}})
+
+	// A device in a reboot loop, behind the same NAT as a healthy one.
+	postFrom(r, http.MethodPost, "/api/v1/devices/60-6b-44-84-dc-0a/heartbeat", "10.0.0.1")
+	for i := 0; i < 3; i++ {
+		if w := postFrom(r, http.MethodPost, "/api/v1/devices/60-6b-44-84-dc-0a/heartbeat", "10.0.0.1"); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("request %d: expected the device limit to apply, got %d", i, w.Code)
+		}
+	}
+	if w := postFrom(r, http.MethodPost, "/api/v1/devices/60-6b-44-84-dc-0b/heartbeat", "10.0.0.1"); w.Code != http.StatusNoContent {
+		t.Errorf("expected the IP's second token to be left for another device, got %d", w.Code)
+	}
+	if got := limiter.Counters(); got.DeviceThrottled != 3 || got.IPThrottled != 0 {
+		t.Errorf("unexpected counters %+v", got)
+	}
+}
+
+func TestRateLimiter_RejectsInvalidDeviceIDs(t *testing.T) {
+	r, limiter := newRateLimitedRouter(RateLimitConfig{Routes: map[string]ratelimit.Rule{
+		HeartbeatRoute: {Device: ratelimit.Limit{Rate: 1, Burst: 1}},
+	}})
+
+	for _, id := range []string{"random-1", "random-2"} {
+		if w := postFrom(r, http.MethodPost, "/api/v1/devices/"+id+"/heartbeat", "10.0.0.1"); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", id, w.Code)
+		}
+	}
+	if n := limiter.buckets.Len(); n != 0 {
+		t.Errorf("expected invalid IDs not to create buckets, got %d", n)
+	}
+}
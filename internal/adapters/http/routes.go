@@ -12,14 +12,99 @@ func RegisterRoutes(r *gin.Engine, deviceSvc ports.DeviceService) {
 
 	h := NewHandler(deviceSvc)
 
+	r.Use(Tracing(), Compression(DefaultMaxDecompressedBytes))
+
 	api := r.Group("/api/v1")
 	{
 		devicesGroup := api.Group("/devices")
 		{
+			devicesGroup.GET("", h.ListDevices)
+			devicesGroup.POST("", h.PostDevice)
+			devicesGroup.DELETE("/:device_id", h.DeleteDevice)
+			devicesGroup.GET("/:device_id/outages", h.GetOutages)
+			devicesGroup.PUT("/:device_id/labels", h.PutLabels)
 			devicesGroup.POST("/:device_id/heartbeat", h.PostHeartbeat)
 			devicesGroup.POST("/:device_id/stats", h.PostStats)
 			devicesGroup.GET("/:device_id/stats", h.GetStats)
 		}
 	}
+
+	// v2 serves reads with explicit units; ingestion stays on v1.
+	v2 := r.Group("/api/v2")
+	{
+		v2.GET("/devices/:device_id/stats", h.GetStatsV2)
+	}
 	r.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
+
+// RegisterWebhookRoutes mounts the webhook management API, reserved to the
+// default tenant: webhooks receive the events of every tenant.
+func RegisterWebhookRoutes(r *gin.Engine, webhookSvc ports.WebhookService) {
+
+	h := NewWebhookHandler(webhookSvc)
+
+	webhooks := r.Group("/api/v1/webhooks", DefaultTenantOnly())
+	{
+		webhooks.POST("", h.PostWebhook)
+		webhooks.GET("", h.ListWebhooks)
+		webhooks.DELETE("/:webhook_id", h.DeleteWebhook)
+		webhooks.GET("/:webhook_id/deliveries", h.ListDeliveries)
+	}
+}
+
+// RegisterAlertRoutes mounts the alert rules, alerts and silences API,
+// reserved to the default tenant whose devices the rules evaluate.
+func RegisterAlertRoutes(r *gin.Engine, alertSvc ports.AlertService) {
+
+	h := NewAlertHandler(alertSvc)
+
+	alerts := r.Group("/api/v1/alerts", DefaultTenantOnly())
+	{
+		alerts.GET("", h.ListAlerts)
+		alerts.GET("/rules", h.ListAlertRules)
+		alerts.POST("/rules", h.PostAlertRule)
+		alerts.DELETE("/rules/:rule_id", h.DeleteAlertRule)
+		alerts.GET("/silences", h.ListSilences)
+		alerts.POST("/silences", h.PostSilence)
+		alerts.DELETE("/silences/:silence_id", h.DeleteSilence)
+	}
+}
+
+// RegisterExportRoutes mounts the bulk CSV and Parquet exports.
+func RegisterExportRoutes(r *gin.Engine, exportSvc ports.ExportService) {
+
+	h := NewExportHandler(exportSvc)
+
+	export := r.Group("/api/v1/export")
+	{
+		export.GET("/stats.csv", h.ExportStatsCSV)
+		export.GET("/stats.parquet", h.ExportStatsParquet)
+		export.GET("/series.csv", h.ExportSeriesCSV)
+		export.GET("/series.parquet", h.ExportSeriesParquet)
+	}
+}
+
+// RegisterImportRoutes mounts the admin import of historical data.
+func RegisterImportRoutes(r *gin.Engine, importSvc ports.ImportService) {
+
+	h := NewImportHandler(importSvc)
+
+	admin := r.Group("/api/v1/admin")
+	{
+		admin.POST("/import", h.PostImport)
+	}
+}
+
+// RegisterGroupRoutes mounts the device groups API.
+func RegisterGroupRoutes(r *gin.Engine, groupSvc ports.GroupService) {
+
+	h := NewGroupHandler(groupSvc)
+
+	groups := r.Group("/api/v1/groups")
+	{
+		groups.POST("", h.PostGroup)
+		groups.GET("", h.ListGroups)
+		groups.DELETE("/:group", h.DeleteGroup)
+		groups.GET("/:group/stats", h.GetGroupStats)
+	}
+}
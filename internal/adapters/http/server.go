@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Defaults for zero ServerConfig fields.
+const (
+	DefaultDrainTimeout      = 15 * time.Second
+	DefaultReadHeaderTimeout = 10 * time.Second
+)
+
+// ServerConfig tunes the HTTP server.
+type ServerConfig struct {
+	// DrainTimeout bounds how long shutdown waits for in-flight requests.
+	DrainTimeout      time.Duration
+	ReadHeaderTimeout time.Duration
+	// CertFile and KeyFile enable TLS when both are set.
+	CertFile string
+	KeyFile  string
+}
+
+// Server runs the HTTP API and shuts it down gracefully: once its context
+// is cancelled it stops accepting connections and lets in-flight requests
+// finish, up to the drain timeout.
+type Server struct {
+	srv *http.Server
+	cfg ServerConfig
+}
+
+// NewServer wraps handler in an http.Server listening on addr (e.g. ":8080").
+func NewServer(addr string, handler http.Handler, cfg ServerConfig) *Server {
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = DefaultDrainTimeout
+	}
+	if cfg.ReadHeaderTimeout <= 0 {
+		cfg.ReadHeaderTimeout = DefaultReadHeaderTimeout
+	}
+	return &Server{
+		srv: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		},
+		cfg: cfg,
+	}
+}
+
+// ListenAndServe listens on the server address and serves until ctx is
+// cancelled and in-flight requests have drained.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve accepts connections on ln until ctx is cancelled, then drains. It
+// returns nil after a clean drain, context.DeadlineExceeded when requests
+// were still running at the drain deadline, or the error that stopped the
+// listener.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	errCh := make(chan error, 1)
+	go func() {
+		if s.cfg.CertFile != "" && s.cfg.KeyFile != "" {
+			errCh <- s.srv.ServeTLS(ln, s.cfg.CertFile, s.cfg.KeyFile)
+			return
+		}
+		errCh <- s.srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), s.cfg.DrainTimeout)
+	defer cancel()
+	err := s.srv.Shutdown(drainCtx)
+	if serveErr := <-errCh; !errors.Is(serveErr, http.ErrServerClosed) && err == nil {
+		err = serveErr
+	}
+	if err != nil {
+		_ = s.srv.Close()
+	}
+	return err
+}
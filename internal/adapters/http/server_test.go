@@ -0,0 +1,104 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"safelyyou/internal/core/domain"
+)
+
+func TestServer_DrainsAcceptedRequestsOnShutdown(t *testing.T) {
+	engine, repo := newIntegrationServer(t)
+	seedDevice(t, repo, integrationDeviceID)
+
+	const requests = 20
+	var started sync.WaitGroup
+	started.Add(requests)
+	// Hold every request after it is accepted so shutdown begins while all
+	// of them are still in flight.
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Done()
+		time.Sleep(100 * time.Millisecond)
+		engine.ServeHTTP(w, r)
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := NewServer(ln.Addr().String(), slow, ServerConfig{DrainTimeout: 5 * time.Second})
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ctx, ln) }()
+
+	url := "http://" + ln.Addr().String() + "/api/v1/devices/" + integrationDeviceID + "/heartbeat"
+	codes := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			resp, err := http.Post(url, "application/json", strings.NewReader(`{"sent_at":"2025-11-09T10:00:00Z"}`))
+			if err != nil {
+				codes <- 0
+				return
+			}
+			_ = resp.Body.Close()
+			codes <- resp.StatusCode
+		}()
+	}
+
+	started.Wait()
+	cancel()
+
+	if err := <-served; err != nil {
+		t.Fatalf("Serve returned error after drain: %v", err)
+	}
+	for i := 0; i < requests; i++ {
+		if code := <-codes; code != http.StatusNoContent {
+			t.Fatalf("accepted request finished with %d, want 204", code)
+		}
+	}
+
+	snap, err := repo.GetSnapshot(context.Background(), domain.DefaultTenant, integrationDeviceID)
+	if err != nil {
+		t.Fatalf("GetSnapshot returned error: %v", err)
+	}
+	if snap.HeartbeatCount != requests {
+		t.Fatalf("expected %d recorded heartbeats, got %d", requests, snap.HeartbeatCount)
+	}
+
+	if _, err := http.Post(url, "application/json", strings.NewReader(`{}`)); err == nil {
+		t.Fatalf("expected new connections to be refused after shutdown")
+	}
+}
+
+func TestServer_ReturnsDeadlineExceededWhenDrainTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	entered := make(chan struct{})
+	stuck := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := NewServer(ln.Addr().String(), stuck, ServerConfig{DrainTimeout: 50 * time.Millisecond})
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ctx, ln) }()
+
+	go func() { _, _ = http.Get("http://" + ln.Addr().String() + "/") }()
+	<-entered
+	cancel()
+
+	if err := <-served; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
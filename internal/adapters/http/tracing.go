@@ -0,0 +1,37 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingServiceName is the service name reported on HTTP server spans.
+const TracingServiceName = "fleet-server"
+
+var tracer = otel.Tracer("safelyyou/internal/adapters/http")
+
+// Tracing starts a server span per request, continuing the trace from an
+// incoming W3C traceparent header when present. Spans are named after the
+// route template ("POST /api/v1/devices/:device_id/heartbeat").
+func Tracing() gin.HandlerFunc {
+	return otelgin.Middleware(TracingServiceName)
+}
+
+// bindBody decodes the request body into obj (see decodeBody) inside a
+// child span, so slow or failing payload decoding shows up in traces.
+func bindBody(c *gin.Context, obj any) error {
+	_, span := tracer.Start(c.Request.Context(), "bind",
+		trace.WithAttributes(attribute.String("http.request.content_type", c.ContentType())))
+	defer span.End()
+
+	err := decodeBody(c, obj)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing_ContinuesTraceAcrossHandlerServiceAndRepository(t *testing.T) {
+	r, repo := newIntegrationServer(t)
+	seedDevice(t, repo, integrationDeviceID)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/devices/"+integrationDeviceID+"/heartbeat",
+		bytes.NewReader([]byte(`{"sent_at":"2025-11-09T10:00:00Z"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", w.Code)
+	}
+
+	names := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		if got := s.SpanContext().TraceID().String(); got != traceID {
+			t.Fatalf("span %q has trace ID %s, want %s", s.Name(), got, traceID)
+		}
+		names[s.Name()] = s
+	}
+
+	for _, want := range []string{
+		"POST /api/v1/devices/:device_id/heartbeat",
+		"bind",
+		"DeviceService.RecordHeartbeat",
+		"DeviceRepository.Exists",
+		"DeviceRepository.WithDevice",
+	} {
+		if _, ok := names[want]; !ok {
+			t.Fatalf("missing span %q; got %v", want, names)
+		}
+	}
+
+	server := names["POST /api/v1/devices/:device_id/heartbeat"]
+	service := names["DeviceService.RecordHeartbeat"]
+	repoSpan := names["DeviceRepository.WithDevice"]
+	if service.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Fatalf("service span should be a child of the server span")
+	}
+	if repoSpan.Parent().SpanID() != service.SpanContext().SpanID() {
+		t.Fatalf("repository span should be a child of the service span")
+	}
+
+	hasLockWait := false
+	for _, kv := range repoSpan.Attributes() {
+		if kv.Key == "repo.lock_wait_ms" {
+			hasLockWait = true
+		}
+	}
+	if !hasLockWait {
+		t.Fatalf("expected repo.lock_wait_ms attribute on %v", repoSpan.Attributes())
+	}
+}
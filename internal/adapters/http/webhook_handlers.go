@@ -0,0 +1,157 @@
+package http
+
+import (
+	"net/http"
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookSvc ports.WebhookService
+}
+
+// NewWebhookHandler constructs a handler that depends on the WebhookService interface.
+func NewWebhookHandler(svc ports.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookSvc: svc}
+}
+
+// PostWebhook godoc
+// @Summary Register a webhook
+// @Description Register a URL that receives signed JSON callbacks for device events.
+// @Description Known events: device.offline, device.online, device.upload_threshold_breached.
+// @Description An empty events list subscribes to every event.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param request body WebhookRequest true "Webhook registration"
+// @Success 201 {object} WebhookResponse
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /api/v1/webhooks [post]
+func (h *WebhookHandler) PostWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+
+	events := make([]domain.EventType, 0, len(req.Events))
+	for _, e := range req.Events {
+		events = append(events, domain.EventType(e))
+	}
+
+	w, err := h.webhookSvc.Register(req.URL, req.Secret, events)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toWebhookResponse(*w))
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} WebhookResponse
+// @Router /api/v1/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	hooks := h.webhookSvc.List()
+	resp := make([]WebhookResponse, 0, len(hooks))
+	for _, w := range hooks {
+		resp = append(resp, toWebhookResponse(w))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteWebhook godoc
+// @Summary Remove a webhook
+// @Description Pending deliveries of a removed webhook are dead-lettered.
+// @Tags webhooks
+// @Param webhook_id path string true "Webhook ID"
+// @Success 204 "no content"
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /api/v1/webhooks/{webhook_id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.webhookSvc.Delete(c.Param("webhook_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries godoc
+// @Summary Webhook delivery log
+// @Description Return the deliveries of a webhook, oldest first. Use status=dead to list dead letters.
+// @Tags webhooks
+// @Produce json
+// @Param webhook_id path string true "Webhook ID"
+// @Param status query string false "pending, delivered or dead"
+// @Success 200 {array} DeliveryResponse
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /api/v1/webhooks/{webhook_id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	status := domain.DeliveryStatus(c.Query("status"))
+	switch status {
+	case "", domain.DeliveryPending, domain.DeliveryDelivered, domain.DeliveryDead:
+	default:
+		respondError(c, coreerrors.Invalid("status", "must be pending, delivered or dead"))
+		return
+	}
+
+	deliveries, err := h.webhookSvc.Deliveries(c.Param("webhook_id"), status)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	resp := make([]DeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		resp = append(resp, toDeliveryResponse(d))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func toWebhookResponse(w domain.Webhook) WebhookResponse {
+	events := make([]string, 0, len(w.Events))
+	for _, e := range w.Events {
+		events = append(events, string(e))
+	}
+	return WebhookResponse{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    events,
+		Signed:    w.Secret != "",
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+func toDeliveryResponse(d domain.Delivery) DeliveryResponse {
+	resp := DeliveryResponse{
+		ID:             d.ID,
+		WebhookID:      d.WebhookID,
+		EventID:        d.Event.ID,
+		EventType:      string(d.Event.Type),
+		DeviceID:       d.Event.DeviceID,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		CreatedAt:      d.CreatedAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+	}
+	if d.Status == domain.DeliveryPending {
+		next := d.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+	if !d.LastAttemptAt.IsZero() {
+		last := d.LastAttemptAt
+		resp.LastAttemptAt = &last
+	}
+	return resp
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"safelyyou/internal/adapters/repository/file"
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/domain"
+	"safelyyou/internal/core/services"
+)
+
+func newWebhookServer(t *testing.T) (*gin.Engine, *services.WebhookServiceImpl) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	queue, err := file.NewDeliveryQueue("")
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	svc := services.NewWebhookService(memory.NewWebhookRepository(), queue)
+
+	r := gin.New()
+	RegisterWebhookRoutes(r, svc)
+	return r, svc
+}
+
+func TestPostWebhook_CreatesAndHidesSecret(t *testing.T) {
+	r, _ := newWebhookServer(t)
+
+	body := []byte(`{"url":"http://receiver.example.com/hook","secret":"s3cret","events":["device.offline"]}`)
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/webhooks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte("s3cret")) {
+		t.Fatalf("response must not echo the secret: %s", w.Body.String())
+	}
+
+	var resp WebhookResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.ID == "" || !resp.Signed || len(resp.Events) != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestPostWebhook_InvalidURLReturns400(t *testing.T) {
+	r, _ := newWebhookServer(t)
+
+	body := []byte(`{"url":"not a url"}`)
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/webhooks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestListDeliveries_ReturnsLogAndFiltersByStatus(t *testing.T) {
+	r, svc := newWebhookServer(t)
+
+	hook, err := svc.Register("http://receiver.example.com/hook", "", nil)
+	if err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	svc.Publish(domain.Event{ID: "e1", Type: domain.EventDeviceOnline, DeviceID: validDeviceID})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/webhooks/"+hook.ID+"/deliveries?status=pending", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	var resp []DeliveryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp) != 1 || resp[0].EventType != "device.online" || resp[0].DeviceID != validDeviceID {
+		t.Fatalf("unexpected deliveries: %+v", resp)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/webhooks/"+hook.ID+"/deliveries?status=dead", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "[]" {
+		t.Fatalf("expected no dead deliveries, got %s", w.Body.String())
+	}
+}
+
+func TestDeleteWebhook_UnknownReturns404(t *testing.T) {
+	r, _ := newWebhookServer(t)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/v1/webhooks/missing", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,165 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/logging"
+	"safelyyou/pkg/utils"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Config holds the broker connection settings.
+type Config struct {
+	// BrokerURL is e.g. "tcp://localhost:1883".
+	BrokerURL string
+	ClientID  string
+	Username  string
+	Password  string
+	// TopicPrefix defaults to "devices", giving topics such as
+	// devices/{device_id}/heartbeat and devices/{device_id}/stats.
+	TopicPrefix string
+}
+
+// heartbeatPayload mirrors the HTTP HeartbeatRequest body.
+type heartbeatPayload struct {
+	SentAt *time.Time `json:"sent_at"`
+}
+
+// statsPayload mirrors the HTTP StatsRequest body.
+type statsPayload struct {
+	SentAt     time.Time `json:"sent_at"`
+	UploadTime *int64    `json:"upload_time"`
+}
+
+// errRejected marks messages that will never succeed (bad topic or
+// payload, or a validation, not-found or forbidden error from the service)
+// and those over the device's rate limit. They are acknowledged so the
+// broker drops them.
+var errRejected = errors.New("rejected")
+
+// Subscriber consumes device heartbeats and stats from an MQTT broker and
+// applies them through ports.DeviceService. Messages are received at QoS 1
+// with automatic acknowledgement disabled: a message is acknowledged only
+// once it has been applied (or rejected as permanently invalid), so a crash
+// before that leads the broker to redeliver it.
+type Subscriber struct {
+	cfg       Config
+	deviceSvc ports.DeviceService
+	client    paho.Client
+}
+
+// NewSubscriber constructs a subscriber that depends on the DeviceService interface.
+func NewSubscriber(cfg Config, svc ports.DeviceService) *Subscriber {
+	if cfg.TopicPrefix == "" {
+		cfg.TopicPrefix = "devices"
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = "fleet-server"
+	}
+	return &Subscriber{cfg: cfg, deviceSvc: svc}
+}
+
+// Start connects to the broker and subscribes to the device topics.
+// Subscriptions are re-established on every reconnect.
+func (s *Subscriber) Start() error {
+	filters := map[string]byte{
+		s.cfg.TopicPrefix + "/+/heartbeat": 1,
+		s.cfg.TopicPrefix + "/+/stats":     1,
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(s.cfg.BrokerURL).
+		SetClientID(s.cfg.ClientID).
+		SetUsername(s.cfg.Username).
+		SetPassword(s.cfg.Password).
+		SetCleanSession(false).
+		SetAutoAckDisabled(true).
+		SetAutoReconnect(true).
+		SetOnConnectHandler(func(c paho.Client) {
+			token := c.SubscribeMultiple(filters, s.onMessage)
+			if token.WaitTimeout(10*time.Second) && token.Error() != nil {
+				logging.For("mqtt").Error("subscribe failed", "error", token.Error())
+			}
+		})
+
+	s.client = paho.NewClient(opts)
+	token := s.client.Connect()
+	if !token.WaitTimeout(10 * time.Second) {
+		return fmt.Errorf("mqtt: timed out connecting to %s", s.cfg.BrokerURL)
+	}
+	return token.Error()
+}
+
+// Stop disconnects from the broker, waiting up to quiesce for in-flight work.
+func (s *Subscriber) Stop(quiesce time.Duration) {
+	if s.client != nil {
+		s.client.Disconnect(uint(quiesce.Milliseconds()))
+	}
+}
+
+func (s *Subscriber) onMessage(_ paho.Client, msg paho.Message) {
+	err := s.apply(msg.Topic(), msg.Payload())
+	switch {
+	case err == nil:
+		msg.Ack()
+	case errors.Is(err, errRejected):
+		logging.For("mqtt").Warn("dropping message", "topic", msg.Topic(), "error", err)
+		msg.Ack()
+	default:
+		// Leave unacknowledged: the broker redelivers after reconnect.
+		logging.For("mqtt").Error("failed to apply message", "topic", msg.Topic(), "error", err)
+	}
+}
+
+// apply decodes one message and records it through the device service.
+func (s *Subscriber) apply(topic string, payload []byte) error {
+	parts := strings.Split(topic, "/")
+	if len(parts) != 3 || parts[0] != s.cfg.TopicPrefix {
+		return fmt.Errorf("%w: unexpected topic", errRejected)
+	}
+	deviceID, kind := parts[1], parts[2]
+	if !utils.IsId(deviceID) {
+		return fmt.Errorf("%w: invalid device ID", errRejected)
+	}
+
+	var err error
+	switch kind {
+	case "heartbeat":
+		var p heartbeatPayload
+		if jerr := json.Unmarshal(payload, &p); jerr != nil || p.SentAt == nil {
+			return fmt.Errorf("%w: invalid heartbeat payload", errRejected)
+		}
+		err = s.deviceSvc.RecordHeartbeat(context.Background(), deviceID, *p.SentAt)
+	case "stats":
+		var p statsPayload
+		if jerr := json.Unmarshal(payload, &p); jerr != nil || p.UploadTime == nil || *p.UploadTime < 0 {
+			return fmt.Errorf("%w: invalid stats payload", errRejected)
+		}
+		err = s.deviceSvc.RecordStats(context.Background(), deviceID, p.SentAt, *p.UploadTime)
+	default:
+		return fmt.Errorf("%w: unexpected topic", errRejected)
+	}
+
+	if err != nil && rejected(err) {
+		return fmt.Errorf("%w: %v", errRejected, err)
+	}
+	return err
+}
+
+// rejected reports whether a message that failed with err should be
+// dropped: redelivering it would fail the same way, or the device is over
+// its rate limit and holding the message would only delay the next ones.
+func rejected(err error) bool {
+	switch coreerrors.KindOf(err) {
+	case coreerrors.KindValidation, coreerrors.KindNotFound, coreerrors.KindForbidden, coreerrors.KindRateLimited:
+		return true
+	}
+	return false
+}
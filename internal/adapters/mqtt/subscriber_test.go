@@ -0,0 +1,223 @@
+package mqtt
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/internal/core/services"
+)
+
+const knownDeviceID = "60-6b-44-84-dc-64"
+
+// ackHook reports QoS flows completed by a given client (i.e. PUBACKs).
+type ackHook struct {
+	mochi.HookBase
+	clientID string
+	acks     chan uint16
+}
+
+func (h *ackHook) ID() string { return "ack-recorder" }
+
+func (h *ackHook) Provides(b byte) bool {
+	return b == mochi.OnQosComplete
+}
+
+func (h *ackHook) OnQosComplete(cl *mochi.Client, pk packets.Packet) {
+	if cl.ID == h.clientID {
+		h.acks <- pk.PacketID
+	}
+}
+
+// startBroker runs an embedded broker on a free local port.
+func startBroker(t *testing.T, hook *ackHook) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find free port: %v", err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+
+	server := mochi.New(&mochi.Options{InlineClient: true})
+	if err := server.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatalf("failed to add auth hook: %v", err)
+	}
+	if hook != nil {
+		if err := server.AddHook(hook, nil); err != nil {
+			t.Fatalf("failed to add ack hook: %v", err)
+		}
+	}
+	if err := server.AddListener(listeners.NewTCP(listeners.Config{ID: "t1", Address: addr})); err != nil {
+		t.Fatalf("failed to add listener: %v", err)
+	}
+	go func() { _ = server.Serve() }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	return "tcp://" + addr
+}
+
+func newPublisher(t *testing.T, broker string) paho.Client {
+	t.Helper()
+
+	c := paho.NewClient(paho.NewClientOptions().AddBroker(broker).SetClientID("device-publisher"))
+	if token := c.Connect(); !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatalf("publisher failed to connect: %v", token.Error())
+	}
+	t.Cleanup(func() { c.Disconnect(100) })
+	return c
+}
+
+func publish(t *testing.T, c paho.Client, topic, payload string) {
+	t.Helper()
+	if token := c.Publish(topic, 1, false, payload); !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatalf("publish to %s failed: %v", topic, token.Error())
+	}
+}
+
+func TestSubscriber_AppliesHeartbeatsAndStats(t *testing.T) {
+	broker := startBroker(t, nil)
+
+	repo := memory.NewDeviceRepository()
+	_ = repo.Add(context.Background(), domain.NewDeviceStats(knownDeviceID))
+	sub := NewSubscriber(Config{BrokerURL: broker, ClientID: "fleet-test"}, services.NewDeviceService(repo))
+	if err := sub.Start(); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer sub.Stop(100 * time.Millisecond)
+
+	pub := newPublisher(t, broker)
+	publish(t, pub, "devices/"+knownDeviceID+"/heartbeat", `{"sent_at":"2025-11-09T10:00:00Z"}`)
+	publish(t, pub, "devices/"+knownDeviceID+"/stats", `{"sent_at":"2025-11-09T10:00:00Z","upload_time":30000000000}`)
+	publish(t, pub, "devices/"+knownDeviceID+"/stats", `{"upload_time":"oops"}`)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		d, _ := repo.GetSnapshot(context.Background(), domain.DefaultTenant, knownDeviceID)
+		if d.HeartbeatCount == 1 && d.UploadCount == 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	d, _ := repo.GetSnapshot(context.Background(), domain.DefaultTenant, knownDeviceID)
+	t.Fatalf("expected 1 heartbeat and 1 upload, got %d / %d", d.HeartbeatCount, d.UploadCount)
+}
+
+// blockingService holds RecordHeartbeat until released.
+type blockingService struct {
+	ports.DeviceService
+	release chan struct{}
+	once    sync.Once
+	entered chan struct{}
+}
+
+func (s *blockingService) RecordHeartbeat(context.Context, string, time.Time) error {
+	s.once.Do(func() { close(s.entered) })
+	<-s.release
+	return nil
+}
+
+func TestSubscriber_AcksOnlyAfterApply(t *testing.T) {
+	hook := &ackHook{clientID: "fleet-ack-test", acks: make(chan uint16, 10)}
+	broker := startBroker(t, hook)
+
+	svc := &blockingService{release: make(chan struct{}), entered: make(chan struct{})}
+	sub := NewSubscriber(Config{BrokerURL: broker, ClientID: hook.clientID}, svc)
+	if err := sub.Start(); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer sub.Stop(100 * time.Millisecond)
+
+	pub := newPublisher(t, broker)
+	publish(t, pub, "devices/"+knownDeviceID+"/heartbeat", `{"sent_at":"2025-11-09T10:00:00Z"}`)
+
+	select {
+	case <-svc.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("heartbeat never reached the service")
+	}
+
+	select {
+	case <-hook.acks:
+		t.Fatalf("message acknowledged before it was applied")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(svc.release)
+
+	select {
+	case <-hook.acks:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("message not acknowledged after it was applied")
+	}
+}
+
+// failingService fails every heartbeat with err.
+type failingService struct {
+	ports.DeviceService
+	err   error
+	calls chan struct{}
+}
+
+func (s *failingService) RecordHeartbeat(context.Context, string, time.Time) error {
+	s.calls <- struct{}{}
+	return s.err
+}
+
+func TestSubscriber_AcksPermanentlyRejectedMessages(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		err   error
+		acked bool
+	}{
+		{"clock skewed", coreerrors.ErrClockSkewed, true},
+		{"quota exceeded", coreerrors.ErrQuotaExceeded, true},
+		{"rate limited", coreerrors.RateLimited(time.Second), true},
+		{"unavailable", coreerrors.New(coreerrors.KindUnavailable, "repository unavailable"), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hook := &ackHook{clientID: "fleet-reject-test", acks: make(chan uint16, 10)}
+			broker := startBroker(t, hook)
+
+			svc := &failingService{err: tc.err, calls: make(chan struct{}, 10)}
+			sub := NewSubscriber(Config{BrokerURL: broker, ClientID: hook.clientID}, svc)
+			if err := sub.Start(); err != nil {
+				t.Fatalf("Start returned error: %v", err)
+			}
+			defer sub.Stop(100 * time.Millisecond)
+
+			pub := newPublisher(t, broker)
+			publish(t, pub, "devices/"+knownDeviceID+"/heartbeat", `{"sent_at":"2025-11-09T10:00:00Z"}`)
+
+			select {
+			case <-svc.calls:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("heartbeat never reached the service")
+			}
+
+			select {
+			case <-hook.acks:
+				if !tc.acked {
+					t.Fatalf("expected transient failure to stay unacknowledged")
+				}
+			case <-time.After(500 * time.Millisecond):
+				if tc.acked {
+					t.Fatalf("expected rejected message to be acknowledged")
+				}
+			}
+		})
+	}
+}
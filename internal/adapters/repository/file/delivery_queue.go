@@ -0,0 +1,292 @@
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"safelyyou/internal/core/domain"
+	"safelyyou/pkg/logging"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHistoryLimit and DefaultHistoryTTL bound the finished
+	// (delivered or dead) deliveries kept as a delivery log.
+	DefaultHistoryLimit = 1000
+	DefaultHistoryTTL   = 24 * time.Hour
+)
+
+// DeliveryQueue is a webhook delivery queue persisted as a JSON file.
+// The file is rewritten atomically (temp file + rename), so pending
+// deliveries survive a restart. With an empty path it behaves as a purely
+// in-memory queue.
+//
+// Finished deliveries are kept as a delivery log within the history limit
+// and TTL, so the file stays bounded. With a persist delay, the mutations
+// made within the delay are written at once, off the caller's path; Flush
+// writes what is left.
+type DeliveryQueue struct {
+	mu         sync.Mutex
+	path       string
+	deliveries map[string]domain.Delivery
+
+	historyLimit int
+	historyTTL   time.Duration
+	persistDelay time.Duration
+	// persistPending is set while a batched write is scheduled. Batched
+	// writes hold writeMu from encoding to rename, taken before mu, so
+	// they land in the order their contents were read.
+	persistPending bool
+	writeMu        sync.Mutex
+	now            func() time.Time
+}
+
+// QueueOption configures optional DeliveryQueue behaviour.
+type QueueOption func(*DeliveryQueue)
+
+// WithHistory keeps at most limit finished deliveries, none created more
+// than ttl ago. Zero disables the respective bound.
+func WithHistory(limit int, ttl time.Duration) QueueOption {
+	return func(q *DeliveryQueue) {
+		q.historyLimit = limit
+		q.historyTTL = ttl
+	}
+}
+
+// WithPersistDelay batches the file writes of the mutations made within d.
+// Zero writes the file on every mutation.
+func WithPersistDelay(d time.Duration) QueueOption {
+	return func(q *DeliveryQueue) { q.persistDelay = d }
+}
+
+// NewDeliveryQueue opens (or creates) the queue stored at path, dropping
+// the finished deliveries beyond the history bounds.
+func NewDeliveryQueue(path string, opts ...QueueOption) (*DeliveryQueue, error) {
+	q := &DeliveryQueue{
+		path:         path,
+		deliveries:   make(map[string]domain.Delivery),
+		historyLimit: DefaultHistoryLimit,
+		historyTTL:   DefaultHistoryTTL,
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if path == "" {
+		return q, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored []domain.Delivery
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("decode delivery queue %s: %w", path, err)
+	}
+	for _, d := range stored {
+		q.deliveries[d.ID] = d
+	}
+	if q.prune() > 0 {
+		if err := q.write(); err != nil {
+			return nil, fmt.Errorf("compact delivery queue %s: %w", path, err)
+		}
+	}
+	return q, nil
+}
+
+func (q *DeliveryQueue) Enqueue(d domain.Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.deliveries[d.ID] = d
+	return q.persist()
+}
+
+// Due returns up to limit pending deliveries whose next attempt is at or
+// before now, oldest first.
+func (q *DeliveryQueue) Due(now time.Time, limit int) []domain.Delivery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var due []domain.Delivery
+	for _, d := range q.deliveries {
+		if d.Due(now) {
+			due = append(due, d)
+		}
+	}
+	sortDeliveries(due)
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due
+}
+
+func (q *DeliveryQueue) Update(d domain.Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.deliveries[d.ID]; !ok {
+		return fmt.Errorf("delivery %s not found", d.ID)
+	}
+	q.deliveries[d.ID] = d
+	if d.Status != domain.DeliveryPending {
+		q.prune()
+	}
+	return q.persist()
+}
+
+// List returns the deliveries of a webhook (all webhooks when webhookID is
+// empty), oldest first.
+func (q *DeliveryQueue) List(webhookID string) []domain.Delivery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	out := make([]domain.Delivery, 0)
+	for _, d := range q.deliveries {
+		if webhookID == "" || d.WebhookID == webhookID {
+			out = append(out, d)
+		}
+	}
+	sortDeliveries(out)
+	return out
+}
+
+// Flush performs the batched write still pending, if any. It is called at
+// shutdown.
+func (q *DeliveryQueue) Flush() error {
+	if q.path == "" || q.persistDelay <= 0 {
+		// Every mutation was already written.
+		return nil
+	}
+	q.writeMu.Lock()
+	defer q.writeMu.Unlock()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.persistPending {
+		return nil
+	}
+	q.persistPending = false
+	return q.write()
+}
+
+// prune drops the finished deliveries beyond the history bounds, oldest
+// first, and returns how many were dropped. It must be called with q.mu
+// held.
+func (q *DeliveryQueue) prune() int {
+	var finished []domain.Delivery
+	for _, d := range q.deliveries {
+		if d.Status != domain.DeliveryPending {
+			finished = append(finished, d)
+		}
+	}
+	sortDeliveries(finished)
+
+	drop := 0
+	if q.historyLimit > 0 && len(finished) > q.historyLimit {
+		drop = len(finished) - q.historyLimit
+	}
+	if q.historyTTL > 0 {
+		cutoff := q.now().Add(-q.historyTTL)
+		for drop < len(finished) && finished[drop].CreatedAt.Before(cutoff) {
+			drop++
+		}
+	}
+	for _, d := range finished[:drop] {
+		delete(q.deliveries, d.ID)
+	}
+	return drop
+}
+
+// persist writes the file, or schedules a batched write when a persist
+// delay is set. It must be called with q.mu held.
+func (q *DeliveryQueue) persist() error {
+	if q.path == "" {
+		return nil
+	}
+	if q.persistDelay <= 0 {
+		return q.write()
+	}
+	if !q.persistPending {
+		q.persistPending = true
+		time.AfterFunc(q.persistDelay, q.persistBatch)
+	}
+	return nil
+}
+
+// persistBatch performs a scheduled write. Failures are logged: the
+// mutations it covers have already returned.
+func (q *DeliveryQueue) persistBatch() {
+	q.writeMu.Lock()
+	defer q.writeMu.Unlock()
+	q.mu.Lock()
+	if !q.persistPending {
+		// Flush got there first.
+		q.mu.Unlock()
+		return
+	}
+	q.persistPending = false
+	data, err := q.encode()
+	q.mu.Unlock()
+
+	if err == nil {
+		err = q.writeFile(data)
+	}
+	if err != nil {
+		logging.For("webhook").Error("failed to persist delivery queue", "path", q.path, "error", err)
+	}
+}
+
+// write rewrites the queue file. It must be called with q.mu held, or
+// before the queue is shared.
+func (q *DeliveryQueue) write() error {
+	data, err := q.encode()
+	if err != nil {
+		return err
+	}
+	return q.writeFile(data)
+}
+
+// encode must be called with q.mu held.
+func (q *DeliveryQueue) encode() ([]byte, error) {
+	all := make([]domain.Delivery, 0, len(q.deliveries))
+	for _, d := range q.deliveries {
+		all = append(all, d)
+	}
+	sortDeliveries(all)
+	return json.Marshal(all)
+}
+
+// writeFile atomically replaces the queue file with data.
+func (q *DeliveryQueue) writeFile(data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), ".queue-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), q.path)
+}
+
+func sortDeliveries(ds []domain.Delivery) {
+	sort.Slice(ds, func(i, j int) bool {
+		if ds[i].CreatedAt.Equal(ds[j].CreatedAt) {
+			return ds[i].ID < ds[j].ID
+		}
+		return ds[i].CreatedAt.Before(ds[j].CreatedAt)
+	})
+}
@@ -0,0 +1,165 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"safelyyou/internal/core/domain"
+)
+
+func newDelivery(id string, createdAt time.Time) domain.Delivery {
+	return domain.Delivery{
+		ID:            id,
+		WebhookID:     "hook-1",
+		Event:         domain.Event{ID: "evt-" + id, Type: domain.EventDeviceOnline, DeviceID: "60-6b-44-84-dc-64"},
+		Status:        domain.DeliveryPending,
+		CreatedAt:     createdAt,
+		NextAttemptAt: createdAt,
+	}
+}
+
+func TestDeliveryQueue_SurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	// Recent enough to stay within the default history TTL.
+	t0 := time.Now().UTC().Add(-time.Minute)
+
+	q, err := NewDeliveryQueue(path)
+	if err != nil {
+		t.Fatalf("NewDeliveryQueue returned error: %v", err)
+	}
+	if err := q.Enqueue(newDelivery("a", t0)); err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+	d := newDelivery("b", t0.Add(time.Second))
+	if err := q.Enqueue(d); err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+	d.Status = domain.DeliveryDelivered
+	if err := q.Update(d); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	reopened, err := NewDeliveryQueue(path)
+	if err != nil {
+		t.Fatalf("reopen returned error: %v", err)
+	}
+	all := reopened.List("")
+	if len(all) != 2 {
+		t.Fatalf("expected 2 deliveries after reopen, got %d", len(all))
+	}
+	if all[0].ID != "a" || all[1].Status != domain.DeliveryDelivered {
+		t.Fatalf("unexpected deliveries after reopen: %+v", all)
+	}
+	if all[0].Event.Type != domain.EventDeviceOnline {
+		t.Fatalf("expected event type to round-trip, got %q", all[0].Event.Type)
+	}
+}
+
+func TestDeliveryQueue_DueOnlyReturnsPendingAndReady(t *testing.T) {
+	q, _ := NewDeliveryQueue("")
+	now := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+
+	ready := newDelivery("ready", now.Add(-time.Minute))
+	later := newDelivery("later", now.Add(-time.Minute))
+	later.NextAttemptAt = now.Add(time.Minute)
+	dead := newDelivery("dead", now.Add(-time.Minute))
+	dead.Status = domain.DeliveryDead
+
+	for _, d := range []domain.Delivery{ready, later, dead} {
+		if err := q.Enqueue(d); err != nil {
+			t.Fatalf("Enqueue returned error: %v", err)
+		}
+	}
+
+	due := q.Due(now, 10)
+	if len(due) != 1 || due[0].ID != "ready" {
+		t.Fatalf("expected only 'ready' to be due, got %+v", due)
+	}
+}
+
+func TestDeliveryQueue_UpdateUnknownReturnsError(t *testing.T) {
+	q, _ := NewDeliveryQueue("")
+	if err := q.Update(newDelivery("missing", time.Now())); err == nil {
+		t.Fatalf("expected error updating unknown delivery")
+	}
+}
+
+func TestDeliveryQueue_PrunesFinishedDeliveries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	now := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+
+	q, err := NewDeliveryQueue(path, WithHistory(2, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.now = func() time.Time { return now }
+	for i, id := range []string{"a", "b", "c", "d"} {
+		d := newDelivery(id, now.Add(time.Duration(i-4)*time.Minute))
+		if err := q.Enqueue(d); err != nil {
+			t.Fatal(err)
+		}
+		if id != "d" {
+			d.Status = domain.DeliveryDelivered
+			if err := q.Update(d); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if got := ids(q.List("")); got != "b,c,d" {
+		t.Fatalf("expected the oldest finished delivery dropped, got %s", got)
+	}
+
+	// Reopening later compacts the file: finished deliveries have expired,
+	// the pending one stays.
+	reopened, err := NewDeliveryQueue(path, WithHistory(2, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(reopened.List("")); got != "d" {
+		t.Fatalf("expected only the pending delivery after compaction, got %s", got)
+	}
+	again, _ := NewDeliveryQueue(path, WithHistory(0, 0))
+	if got := ids(again.List("")); got != "d" {
+		t.Fatalf("expected the compacted file to be rewritten, got %s", got)
+	}
+}
+
+func TestDeliveryQueue_BatchesWritesUntilFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	t0 := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+
+	q, err := NewDeliveryQueue(path, WithPersistDelay(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := q.Enqueue(newDelivery(id, t0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no write before the persist delay, got %v", err)
+	}
+
+	if err := q.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+	reopened, err := NewDeliveryQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(reopened.List("")); got != "a,b" {
+		t.Fatalf("expected both deliveries after Flush, got %s", got)
+	}
+}
+
+func ids(ds []domain.Delivery) string {
+	out := make([]string, len(ds))
+	for i, d := range ds {
+		out[i] = d.ID
+	}
+	return strings.Join(out, ",")
+}
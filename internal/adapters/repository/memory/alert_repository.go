@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+	"sort"
+	"sync"
+)
+
+type AlertRepository struct {
+	mu       sync.RWMutex
+	rules    map[string]domain.AlertRule
+	alerts   map[string]domain.Alert
+	silences map[string]domain.Silence
+}
+
+// NewAlertRepository creates an empty in-memory AlertRepository.
+func NewAlertRepository() *AlertRepository {
+	return &AlertRepository{
+		rules:    make(map[string]domain.AlertRule),
+		alerts:   make(map[string]domain.Alert),
+		silences: make(map[string]domain.Silence),
+	}
+}
+
+func (r *AlertRepository) SaveRule(rule domain.AlertRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rules[rule.ID] = rule
+	return nil
+}
+
+// ListRules returns all rules ordered by creation time.
+func (r *AlertRepository) ListRules() []domain.AlertRule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]domain.AlertRule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		out = append(out, rule)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out
+}
+
+// DeleteRule removes a rule together with its alerts.
+func (r *AlertRepository) DeleteRule(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rules[id]; !ok {
+		return coreerrors.ErrAlertRuleNotFound
+	}
+	delete(r.rules, id)
+	for key, a := range r.alerts {
+		if a.RuleID == id {
+			delete(r.alerts, key)
+		}
+	}
+	return nil
+}
+
+func (r *AlertRepository) SaveAlert(a domain.Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.alerts[a.ID] = a
+	return nil
+}
+
+// ListAlerts returns all alerts ordered by ID.
+func (r *AlertRepository) ListAlerts() []domain.Alert {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]domain.Alert, 0, len(r.alerts))
+	for _, a := range r.alerts {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (r *AlertRepository) DeleteAlert(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.alerts, id)
+	return nil
+}
+
+func (r *AlertRepository) SaveSilence(s domain.Silence) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.silences[s.ID] = s
+	return nil
+}
+
+// ListSilences returns all silences ordered by start time.
+func (r *AlertRepository) ListSilences() []domain.Silence {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]domain.Silence, 0, len(r.silences))
+	for _, s := range r.silences {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].StartsAt.Equal(out[j].StartsAt) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].StartsAt.Before(out[j].StartsAt)
+	})
+	return out
+}
+
+func (r *AlertRepository) DeleteSilence(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.silences[id]; !ok {
+		return coreerrors.ErrSilenceNotFound
+	}
+	delete(r.silences, id)
+	return nil
+}
@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+)
+
+func TestAlertRepository_DeleteRuleRemovesAlertsOfEveryTenant(t *testing.T) {
+	repo := NewAlertRepository()
+	_ = repo.SaveRule(domain.AlertRule{ID: "r1"})
+	_ = repo.SaveRule(domain.AlertRule{ID: "r2"})
+	for _, a := range []domain.Alert{
+		{ID: domain.AlertID(domain.DefaultTenant, "r1", "dev-1"), RuleID: "r1", Tenant: domain.DefaultTenant, DeviceID: "dev-1"},
+		{ID: domain.AlertID("acme", "r1", "dev-1"), RuleID: "r1", Tenant: "acme", DeviceID: "dev-1"},
+		{ID: domain.AlertID("acme", "r2", "dev-1"), RuleID: "r2", Tenant: "acme", DeviceID: "dev-1"},
+	} {
+		_ = repo.SaveAlert(a)
+	}
+
+	if err := repo.DeleteRule("r1"); err != nil {
+		t.Fatalf("DeleteRule returned error: %v", err)
+	}
+
+	alerts := repo.ListAlerts()
+	if len(alerts) != 1 || alerts[0].RuleID != "r2" {
+		t.Fatalf("expected only r2's alert to remain, got %+v", alerts)
+	}
+	if err := repo.DeleteRule("r1"); !errors.Is(err, coreerrors.ErrAlertRuleNotFound) {
+		t.Fatalf("expected ErrAlertRuleNotFound, got %v", err)
+	}
+}
@@ -1,28 +1,69 @@
 package memory
 
 import (
+	"context"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"safelyyou/internal/core/domain"
 	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/ratelimit"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("safelyyou/internal/adapters/repository/memory")
+
+// attrLockWait is the span attribute holding how long a call waited for the
+// repository lock, in milliseconds.
+const attrLockWait = "repo.lock_wait_ms"
+
 type DeviceRepository struct {
 	mu      sync.RWMutex
+	tenants map[string]*tenantDevices
+
+	// snapshotPath, when set, is where Flush persists device state.
+	snapshotPath string
+}
+
+// tenantDevices holds the devices of one tenant.
+type tenantDevices struct {
 	devices map[string]*domain.DeviceStats
+	// labels indexes device IDs by label key and value, site included.
+	labels map[string]map[string]map[string]struct{}
 }
 
 // NewDeviceRepository creates an empty in-memory DeviceRepository.
 func NewDeviceRepository() *DeviceRepository {
-	return &DeviceRepository{
-		devices: make(map[string]*domain.DeviceStats),
-	}
+	return &DeviceRepository{tenants: make(map[string]*tenantDevices)}
 }
 
-// LoadFromCSV initializes the repository from a CSV file.
+// LoadFromCSV initializes the default tenant's devices from a CSV file.
 // Expected format: header line with "device_id", then one ID per line.
+// Optional columns, matched by header name:
+//   - "site" assigns the device to a site (also accepted unnamed as the
+//     second column, for older files);
+//   - "rate_limit" overrides the device's ingestion rate limit as
+//     "rate[:burst]" requests per second, e.g. "0.5:5";
+//   - "labels" tags the device with "key=value" pairs separated by ";",
+//     e.g. "floor=2;wing=b".
 func (r *DeviceRepository) LoadFromCSV(path string) error {
+	return r.LoadTenantCSV(domain.DefaultTenant, path)
+}
+
+// LoadTenantCSV adds the devices of a CSV file, in the LoadFromCSV format,
+// to the given tenant.
+func (r *DeviceRepository) LoadTenantCSV(tenant, path string) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return err
@@ -35,73 +76,469 @@ func (r *DeviceRepository) LoadFromCSV(path string) error {
 	}(f)
 
 	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
 	records, err := reader.ReadAll()
 	if err != nil {
 		return err
 	}
+	if len(records) == 0 {
+		return nil
+	}
 
-	for i, row := range records {
-		if i == 0 {
-			// skip header
+	cols := csvColumns(records[0])
+	for i, row := range records[1:] {
+		id := column(row, cols.id)
+		if id == "" {
 			continue
 		}
-		if len(row) == 0 {
-			continue
+		var limit domain.RateLimit
+		if spec := column(row, cols.rateLimit); spec != "" {
+			l, err := ratelimit.ParseLimit(spec)
+			if err != nil {
+				return fmt.Errorf("%s line %d: rate_limit: %w", path, i+2, err)
+			}
+			limit = domain.RateLimit{PerSecond: l.Rate, Burst: l.Burst}
+		}
+		labels, err := domain.ParseLabels(column(row, cols.labels))
+		if err != nil {
+			return fmt.Errorf("%s line %d: labels: %w", path, i+2, err)
+		}
+		if site := column(row, cols.site); site != "" {
+			labels[domain.LabelSite] = site
+		}
+		r.addDevice(tenant, id, labels, limit)
+	}
+	return nil
+}
+
+// csvLayout holds column indexes; -1 means absent.
+type csvLayout struct {
+	id, site, rateLimit, labels int
+}
+
+func csvColumns(header []string) csvLayout {
+	cols := csvLayout{id: 0, site: -1, rateLimit: -1, labels: -1}
+	for i, name := range header {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "device_id":
+			cols.id = i
+		case "site":
+			cols.site = i
+		case "rate_limit":
+			cols.rateLimit = i
+		case "labels":
+			cols.labels = i
+		}
+	}
+	if cols.site == -1 && len(header) > 1 && cols.rateLimit != 1 && cols.labels != 1 && cols.id != 1 {
+		cols.site = 1
+	}
+	return cols
+}
+
+func column(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// UseSnapshotFile restores device state from path when the file exists and
+// makes Flush write state back to it. Restored devices replace entries
+// already loaded from CSV, except for their rate limit, which the CSV owns.
+// Devices stored without a tenant belong to the default tenant.
+func (r *DeviceRepository) UseSnapshotFile(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.snapshotPath = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var stored []*domain.DeviceStats
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("decode device snapshot %s: %w", path, err)
+	}
+	for _, d := range stored {
+		if d.Tenant == "" {
+			d.Tenant = domain.DefaultTenant
+		}
+		t := r.tenantForWrite(d.Tenant)
+		if loaded, ok := t.devices[d.ID]; ok {
+			d.RateLimit = loaded.RateLimit
+			t.unindex(loaded)
 		}
-		id := row[0]
-		r.addDevice(id)
+		t.devices[d.ID] = d
+		t.index(d)
 	}
 	return nil
 }
 
-func (r *DeviceRepository) addDevice(id string) {
+// Flush writes all device state to the snapshot file (temp file + rename).
+// It is a no-op when no snapshot file is configured.
+func (r *DeviceRepository) Flush() error {
+	r.mu.RLock()
+	path := r.snapshotPath
+	if path == "" {
+		r.mu.RUnlock()
+		return nil
+	}
+	var all []*domain.DeviceStats
+	for _, tenant := range r.tenantNames() {
+		t := r.tenants[tenant]
+		for _, id := range t.ids() {
+			all = append(all, t.devices[id].Clone())
+		}
+	}
+	r.mu.RUnlock()
+
+	data, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".devices-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (r *DeviceRepository) addDevice(tenant, id string, labels map[string]string, limit domain.RateLimit) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.devices[id]; !exists {
-		r.devices[id] = domain.NewDeviceStats(id)
+	t := r.tenantForWrite(tenant)
+	if _, exists := t.devices[id]; !exists {
+		d := domain.NewDeviceStats(id)
+		d.Tenant = tenant
+		d.SetLabels(labels)
+		d.RateLimit = limit
+		t.devices[id] = d
+		t.index(d)
 	}
 }
 
+// Count returns the number of devices across all tenants.
 func (r *DeviceRepository) Count() int {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return len(r.devices)
+
+	n := 0
+	for _, t := range r.tenants {
+		n += len(t.devices)
+	}
+	return n
 }
 
 // WithDevice runs a function while holding a write lock on the device.
-// WithDevice finds (or creates) a device by id and executes fn while holding
+// WithDevice finds a device by id and executes fn while holding
 // a write lock on the underlying map. This lets the service perform
 // read-modify-write updates atomically without worrying about concurrency.
-func (r *DeviceRepository) WithDevice(id string, fn func(d *domain.DeviceStats) error) error {
+func (r *DeviceRepository) WithDevice(ctx context.Context, tenant, id string, fn func(d *domain.DeviceStats) error) error {
+	_, span := startSpan(ctx, "DeviceRepository.WithDevice", tenant, id)
+	defer span.End()
+
+	waitStart := time.Now()
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	recordLockWait(span, waitStart)
 
-	d, ok := r.devices[id]
+	// Devices are only created by Add and the device CSVs, so a device
+	// removed since the caller looked it up stays removed.
+	d, ok := r.tenants[tenant].device(id)
 	if !ok {
-		// Auto-create device if it doesn't exist yet.
-		d = domain.NewDeviceStats(id)
-		r.devices[id] = d
+		return coreerrors.ErrDeviceNotFound
 	}
-	return fn(d)
+	if err := fn(d); err != nil {
+		if errors.Is(err, ports.ErrUnchanged) {
+			return nil
+		}
+		return err
+	}
+	d.Version++
+	d.UpdatedAt = time.Now()
+	return nil
 }
 
-func (r *DeviceRepository) Exists(id string) bool {
+func (r *DeviceRepository) Exists(ctx context.Context, tenant, id string) bool {
+	_, span := startSpan(ctx, "DeviceRepository.Exists", tenant, id)
+	defer span.End()
+
+	waitStart := time.Now()
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	recordLockWait(span, waitStart)
 
-	_, ok := r.devices[id]
+	_, ok := r.tenants[tenant].device(id)
 	return ok
 }
 
-func (r *DeviceRepository) GetSnapshot(id string) (*domain.DeviceStats, error) {
+func (r *DeviceRepository) GetSnapshot(ctx context.Context, tenant, id string) (*domain.DeviceStats, error) {
+	_, span := startSpan(ctx, "DeviceRepository.GetSnapshot", tenant, id)
+	defer span.End()
+
+	waitStart := time.Now()
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	recordLockWait(span, waitStart)
 
-	deviceStats, ok := r.devices[id]
+	deviceStats, ok := r.tenants[tenant].device(id)
 	if !ok {
 		return nil, coreerrors.ErrDeviceNotFound
 	}
-	deviceCopy := *deviceStats
-	return &deviceCopy, nil
+	return deviceStats.Clone(), nil
+}
+
+// RateLimit returns the device's rate limit override without cloning the
+// device, for lookups on every ingestion request.
+func (r *DeviceRepository) RateLimit(tenant, id string) (domain.RateLimit, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d, ok := r.tenants[tenant].device(id)
+	if !ok {
+		return domain.RateLimit{}, false
+	}
+	return d.RateLimit, true
+}
+
+// Add stores d as a new device of d.Tenant.
+func (r *DeviceRepository) Add(ctx context.Context, d *domain.DeviceStats) error {
+	_, span := startSpan(ctx, "DeviceRepository.Add", d.Tenant, d.ID)
+	defer span.End()
+
+	waitStart := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recordLockWait(span, waitStart)
+
+	t := r.tenantForWrite(d.Tenant)
+	if _, ok := t.devices[d.ID]; ok {
+		return coreerrors.ErrDeviceExists
+	}
+	d.Version++
+	d.UpdatedAt = time.Now()
+	t.devices[d.ID] = d
+	t.index(d)
+	return nil
+}
+
+// Remove deletes a device.
+func (r *DeviceRepository) Remove(ctx context.Context, tenant, id string) error {
+	_, span := startSpan(ctx, "DeviceRepository.Remove", tenant, id)
+	defer span.End()
+
+	waitStart := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recordLockWait(span, waitStart)
+
+	t := r.tenants[tenant]
+	d, ok := t.device(id)
+	if !ok {
+		return coreerrors.ErrDeviceNotFound
+	}
+	t.unindex(d)
+	delete(t.devices, id)
+	if len(t.devices) == 0 {
+		delete(r.tenants, tenant)
+	}
+	return nil
+}
+
+// SetLabels replaces a device's labels and site.
+func (r *DeviceRepository) SetLabels(ctx context.Context, tenant, id string, labels map[string]string) error {
+	_, span := startSpan(ctx, "DeviceRepository.SetLabels", tenant, id)
+	defer span.End()
+
+	waitStart := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recordLockWait(span, waitStart)
+
+	t := r.tenants[tenant]
+	d, ok := t.device(id)
+	if !ok {
+		return coreerrors.ErrDeviceNotFound
+	}
+	t.unindex(d)
+	d.SetLabels(labels)
+	t.index(d)
+	d.Version++
+	d.UpdatedAt = time.Now()
+	return nil
+}
+
+// Select returns the IDs of the devices matching sel. Equality and
+// existence requirements are answered from the label index; the others
+// filter the candidates it yields, or every device when there are none.
+func (r *DeviceRepository) Select(ctx context.Context, tenant string, sel domain.Selector) []string {
+	_, span := tracer.Start(ctx, "DeviceRepository.Select", trace.WithAttributes(
+		attribute.String("tenant", tenant), attribute.String("selector", sel.String())))
+	defer span.End()
+
+	waitStart := time.Now()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	recordLockWait(span, waitStart)
+
+	out := []string{}
+	t, ok := r.tenants[tenant]
+	if !ok {
+		return out
+	}
+	var candidates map[string]struct{}
+	narrowed := false
+	for _, req := range sel {
+		var ids map[string]struct{}
+		switch req.Op {
+		case domain.SelectorEquals:
+			ids = t.labels[req.Key][req.Value]
+		case domain.SelectorExists:
+			ids = make(map[string]struct{})
+			for _, byValue := range t.labels[req.Key] {
+				for id := range byValue {
+					ids[id] = struct{}{}
+				}
+			}
+		default:
+			continue
+		}
+		if !narrowed || len(ids) < len(candidates) {
+			candidates, narrowed = ids, true
+		}
+	}
+
+	if narrowed {
+		for id := range candidates {
+			if sel.Matches(t.devices[id]) {
+				out = append(out, id)
+			}
+		}
+	} else {
+		for id, d := range t.devices {
+			if sel.Matches(d) {
+				out = append(out, id)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// tenantForWrite returns the devices of tenant, creating the partition if
+// needed; the caller holds the write lock.
+func (r *DeviceRepository) tenantForWrite(tenant string) *tenantDevices {
+	t, ok := r.tenants[tenant]
+	if !ok {
+		t = &tenantDevices{
+			devices: make(map[string]*domain.DeviceStats),
+			labels:  make(map[string]map[string]map[string]struct{}),
+		}
+		r.tenants[tenant] = t
+	}
+	return t
+}
+
+// tenantNames returns the tenants in sorted order; the caller holds the lock.
+func (r *DeviceRepository) tenantNames() []string {
+	names := make([]string, 0, len(r.tenants))
+	for name := range r.tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// device looks up a device; t may be nil for a tenant without devices.
+func (t *tenantDevices) device(id string) (*domain.DeviceStats, bool) {
+	if t == nil {
+		return nil, false
+	}
+	d, ok := t.devices[id]
+	return d, ok
+}
+
+// ids returns the device IDs in sorted order; t may be nil.
+func (t *tenantDevices) ids() []string {
+	if t == nil {
+		return []string{}
+	}
+	ids := make([]string, 0, len(t.devices))
+	for id := range t.devices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// index adds d to the label index; the caller holds the write lock.
+func (t *tenantDevices) index(d *domain.DeviceStats) {
+	for k, v := range d.AllLabels() {
+		byValue, ok := t.labels[k]
+		if !ok {
+			byValue = make(map[string]map[string]struct{})
+			t.labels[k] = byValue
+		}
+		ids, ok := byValue[v]
+		if !ok {
+			ids = make(map[string]struct{})
+			byValue[v] = ids
+		}
+		ids[d.ID] = struct{}{}
+	}
+}
+
+// unindex removes d from the label index; the caller holds the write lock.
+func (t *tenantDevices) unindex(d *domain.DeviceStats) {
+	for k, v := range d.AllLabels() {
+		delete(t.labels[k][v], d.ID)
+		if len(t.labels[k][v]) == 0 {
+			delete(t.labels[k], v)
+		}
+		if len(t.labels[k]) == 0 {
+			delete(t.labels, k)
+		}
+	}
+}
+
+// IDs returns the tenant's device IDs in sorted order.
+func (r *DeviceRepository) IDs(tenant string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.tenants[tenant].ids()
+}
+
+// Tenants returns the tenants owning at least one device, in sorted order.
+func (r *DeviceRepository) Tenants() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.tenantNames()
+}
+
+func startSpan(ctx context.Context, name, tenant, id string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("tenant", tenant), attribute.String("device.id", id)))
+}
+
+// recordLockWait attaches the time spent acquiring the lock since start.
+func recordLockWait(span trace.Span, start time.Time) {
+	if !span.IsRecording() {
+		return
+	}
+	waited := time.Since(start)
+	span.SetAttributes(attribute.Float64(attrLockWait, float64(waited)/float64(time.Millisecond)))
 }
@@ -1,13 +1,17 @@
 package memory
 
 import (
+	"context"
 	"errors"
 	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
 	"safelyyou/internal/core/domain"
 	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
 )
 
 // -----------------------------------------------------------------------------
@@ -51,7 +55,7 @@ func TestLoadFromCSV_Success(t *testing.T) {
 
 	// Verify IDs exist.
 	for _, id := range []string{"dev-1", "dev-2", "dev-3"} {
-		if !repo.Exists(id) {
+		if !repo.Exists(context.Background(), domain.DefaultTenant, id) {
 			t.Errorf("expected Exists(%q) to be true after LoadFromCSV", id)
 		}
 	}
@@ -65,16 +69,57 @@ func TestLoadFromCSV_FileNotFoundReturnsError(t *testing.T) {
 	}
 }
 
+func TestLoadFromCSV_SiteAndRateLimitColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.csv")
+	content := "device_id,rate_limit,site\n" +
+		"dev-1,0.5:5,north\n" +
+		"dev-2,,south\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+
+	repo := NewDeviceRepository()
+	if err := repo.LoadFromCSV(path); err != nil {
+		t.Fatalf("LoadFromCSV returned error: %v", err)
+	}
+
+	d1, _ := repo.GetSnapshot(context.Background(), domain.DefaultTenant, "dev-1")
+	if d1.Site != "north" || d1.RateLimit != (domain.RateLimit{PerSecond: 0.5, Burst: 5}) {
+		t.Errorf("unexpected dev-1: site=%q rate_limit=%+v", d1.Site, d1.RateLimit)
+	}
+	d2, _ := repo.GetSnapshot(context.Background(), domain.DefaultTenant, "dev-2")
+	if d2.Site != "south" || d2.RateLimit.IsSet() {
+		t.Errorf("unexpected dev-2: site=%q rate_limit=%+v", d2.Site, d2.RateLimit)
+	}
+
+	if rl, ok := repo.RateLimit(domain.DefaultTenant, "dev-1"); !ok || rl != d1.RateLimit {
+		t.Errorf("RateLimit(dev-1) = %+v, %v; want %+v", rl, ok, d1.RateLimit)
+	}
+	if _, ok := repo.RateLimit("acme", "dev-1"); ok {
+		t.Errorf("expected no rate limit for another tenant's device")
+	}
+}
+
+func TestLoadFromCSV_InvalidRateLimitReturnsError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.csv")
+	if err := os.WriteFile(path, []byte("device_id,rate_limit\ndev-1,fast\n"), 0o644); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+	if err := NewDeviceRepository().LoadFromCSV(path); err == nil {
+		t.Fatal("expected error for invalid rate_limit")
+	}
+}
+
 // -----------------------------------------------------------------------------
 // Tests for WithDevice
 // -----------------------------------------------------------------------------
 
-func TestWithDevice_CreatesAndMutatesDevice(t *testing.T) {
+func TestWithDevice_MutatesDevice(t *testing.T) {
 	repo := NewDeviceRepository()
 	id := "dev-1"
+	addDevice(t, repo, id)
 
-	// First call should auto-create the device.
-	if err := repo.WithDevice(id, func(d *domain.DeviceStats) error {
+	if err := repo.WithDevice(context.Background(), domain.DefaultTenant, id, func(d *domain.DeviceStats) error {
 		if d.ID != id {
 			t.Errorf("expected ID=%q, got %q", id, d.ID)
 		}
@@ -85,14 +130,14 @@ func TestWithDevice_CreatesAndMutatesDevice(t *testing.T) {
 	}
 
 	// Second call mutates the same device.
-	if err := repo.WithDevice(id, func(d *domain.DeviceStats) error {
+	if err := repo.WithDevice(context.Background(), domain.DefaultTenant, id, func(d *domain.DeviceStats) error {
 		d.HeartbeatCount++
 		return nil
 	}); err != nil {
 		t.Fatalf("WithDevice returned error on second call: %v", err)
 	}
 
-	snap, err := repo.GetSnapshot(id)
+	snap, err := repo.GetSnapshot(context.Background(), domain.DefaultTenant, id)
 	if err != nil {
 		t.Fatalf("GetSnapshot returned error: %v", err)
 	}
@@ -106,8 +151,9 @@ func TestWithDevice_PropagatesErrorFromCallback(t *testing.T) {
 	repo := NewDeviceRepository()
 	id := "dev-err"
 	wantErr := errors.New("boom")
+	addDevice(t, repo, id)
 
-	err := repo.WithDevice(id, func(d *domain.DeviceStats) error {
+	err := repo.WithDevice(context.Background(), domain.DefaultTenant, id, func(d *domain.DeviceStats) error {
 		return wantErr
 	})
 
@@ -116,26 +162,87 @@ func TestWithDevice_PropagatesErrorFromCallback(t *testing.T) {
 	}
 }
 
+func TestWithDevice_BumpsVersionOnlyOnChange(t *testing.T) {
+	repo := NewDeviceRepository()
+	ctx := context.Background()
+	id := "dev-version"
+	addDevice(t, repo, id)
+	version := func() uint64 {
+		d, err := repo.GetSnapshot(ctx, domain.DefaultTenant, id)
+		if err != nil {
+			t.Fatalf("GetSnapshot: %v", err)
+		}
+		return d.Version
+	}
+
+	_ = repo.WithDevice(ctx, domain.DefaultTenant, id, func(d *domain.DeviceStats) error { d.HeartbeatCount++; return nil })
+	_ = repo.WithDevice(ctx, domain.DefaultTenant, id, func(d *domain.DeviceStats) error { d.HeartbeatCount++; return nil })
+	if got := version(); got != 3 {
+		t.Fatalf("expected version 3 after Add and two changes, got %d", got)
+	}
+
+	if err := repo.WithDevice(ctx, domain.DefaultTenant, id, func(*domain.DeviceStats) error { return ports.ErrUnchanged }); err != nil {
+		t.Fatalf("expected ErrUnchanged to be swallowed, got %v", err)
+	}
+	_ = repo.WithDevice(ctx, domain.DefaultTenant, id, func(*domain.DeviceStats) error { return errors.New("boom") })
+	if got := version(); got != 3 {
+		t.Errorf("expected version to stay 3 without changes, got %d", got)
+	}
+	if d, _ := repo.GetSnapshot(ctx, domain.DefaultTenant, id); d.UpdatedAt.IsZero() {
+		t.Errorf("expected UpdatedAt to be set")
+	}
+}
+
+func TestWithDevice_DoesNotRecreateRemovedDevice(t *testing.T) {
+	repo := NewDeviceRepository()
+	ctx := context.Background()
+	d := domain.NewDeviceStats("dev-1")
+	d.SetLabels(map[string]string{"site": "north"})
+	if err := repo.Add(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+
+	// A heartbeat checks Exists, then the device is removed before it
+	// reaches WithDevice.
+	if !repo.Exists(ctx, domain.DefaultTenant, "dev-1") {
+		t.Fatal("expected the device to exist")
+	}
+	if err := repo.Remove(ctx, domain.DefaultTenant, "dev-1"); err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	err := repo.WithDevice(ctx, domain.DefaultTenant, "dev-1", func(*domain.DeviceStats) error { called = true; return nil })
+	if !errors.Is(err, coreerrors.ErrDeviceNotFound) || called {
+		t.Fatalf("expected ErrDeviceNotFound without running fn, got %v (called %v)", err, called)
+	}
+	if repo.Exists(ctx, domain.DefaultTenant, "dev-1") || repo.Count() != 0 {
+		t.Fatal("expected the removed device to stay removed")
+	}
+}
+
+func addDevice(t *testing.T, repo *DeviceRepository, id string) {
+	t.Helper()
+	if err := repo.Add(context.Background(), domain.NewDeviceStats(id)); err != nil {
+		t.Fatalf("Add(%q): %v", id, err)
+	}
+}
+
 // -----------------------------------------------------------------------------
 // Tests for Exists
 // -----------------------------------------------------------------------------
 
-func TestExists_TrueAfterWithDeviceFalseOtherwise(t *testing.T) {
+func TestExists_TrueAfterAddFalseOtherwise(t *testing.T) {
 	repo := NewDeviceRepository()
 	id := "dev-1"
 
-	if repo.Exists(id) {
+	if repo.Exists(context.Background(), domain.DefaultTenant, id) {
 		t.Fatalf("expected Exists(%q) to be false before any creation", id)
 	}
 
-	if err := repo.WithDevice(id, func(d *domain.DeviceStats) error {
-		return nil
-	}); err != nil {
-		t.Fatalf("WithDevice returned error: %v", err)
-	}
+	addDevice(t, repo, id)
 
-	if !repo.Exists(id) {
-		t.Fatalf("expected Exists(%q) to be true after WithDevice", id)
+	if !repo.Exists(context.Background(), domain.DefaultTenant, id) {
+		t.Fatalf("expected Exists(%q) to be true after Add", id)
 	}
 }
 
@@ -146,7 +253,7 @@ func TestExists_TrueAfterWithDeviceFalseOtherwise(t *testing.T) {
 func TestGetSnapshot_NotFoundReturnsErrDeviceNotFound(t *testing.T) {
 	repo := NewDeviceRepository()
 
-	snap, err := repo.GetSnapshot("missing-id")
+	snap, err := repo.GetSnapshot(context.Background(), domain.DefaultTenant, "missing-id")
 	if !errors.Is(err, coreerrors.ErrDeviceNotFound) {
 		t.Fatalf("expected ErrDeviceNotFound, got %v", err)
 	}
@@ -159,8 +266,8 @@ func TestGetSnapshot_ReturnsCopyNotOriginal(t *testing.T) {
 	repo := NewDeviceRepository()
 	id := "dev-1"
 
-	// Seed a device via WithDevice.
-	if err := repo.WithDevice(id, func(d *domain.DeviceStats) error {
+	addDevice(t, repo, id)
+	if err := repo.WithDevice(context.Background(), domain.DefaultTenant, id, func(d *domain.DeviceStats) error {
 		d.HeartbeatCount = 5
 		d.FirstHeartbeat = time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
 		d.LastHeartbeat = d.FirstHeartbeat.Add(10 * time.Minute)
@@ -170,7 +277,7 @@ func TestGetSnapshot_ReturnsCopyNotOriginal(t *testing.T) {
 	}
 
 	// Take a snapshot and mutate it.
-	snap1, err := repo.GetSnapshot(id)
+	snap1, err := repo.GetSnapshot(context.Background(), domain.DefaultTenant, id)
 	if err != nil {
 		t.Fatalf("GetSnapshot returned error: %v", err)
 	}
@@ -178,7 +285,7 @@ func TestGetSnapshot_ReturnsCopyNotOriginal(t *testing.T) {
 	snap1.HeartbeatCount = 999 // mutate the snapshot
 
 	// Take another snapshot; it should not see the mutation.
-	snap2, err := repo.GetSnapshot(id)
+	snap2, err := repo.GetSnapshot(context.Background(), domain.DefaultTenant, id)
 	if err != nil {
 		t.Fatalf("GetSnapshot returned error: %v", err)
 	}
@@ -187,3 +294,220 @@ func TestGetSnapshot_ReturnsCopyNotOriginal(t *testing.T) {
 		t.Fatalf("expected underlying HeartbeatCount to remain 5, got %d", snap2.HeartbeatCount)
 	}
 }
+
+func TestFlush_RoundTripsThroughSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.json")
+	id := "60-6b-44-84-dc-64"
+	sentAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	repo := NewDeviceRepository()
+	if err := repo.UseSnapshotFile(path); err != nil {
+		t.Fatalf("UseSnapshotFile on missing file returned error: %v", err)
+	}
+	addDevice(t, repo, id)
+	if err := repo.WithDevice(context.Background(), domain.DefaultTenant, id, func(d *domain.DeviceStats) error {
+		d.Site = "north"
+		d.HeartbeatCount = 3
+		d.FirstHeartbeat = sentAt
+		d.AddHeartbeatSample(sentAt, sentAt)
+		return nil
+	}); err != nil {
+		t.Fatalf("WithDevice returned error: %v", err)
+	}
+	if err := repo.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+
+	restored := NewDeviceRepository()
+	if err := restored.UseSnapshotFile(path); err != nil {
+		t.Fatalf("UseSnapshotFile returned error: %v", err)
+	}
+	snap, err := restored.GetSnapshot(context.Background(), domain.DefaultTenant, id)
+	if err != nil {
+		t.Fatalf("GetSnapshot after restore returned error: %v", err)
+	}
+	if snap.Site != "north" || snap.HeartbeatCount != 3 || !snap.FirstHeartbeat.Equal(sentAt) || len(snap.Buckets) != 1 {
+		t.Fatalf("unexpected restored device: %+v", snap)
+	}
+}
+
+func TestFlush_NoSnapshotFileIsNoop(t *testing.T) {
+	if err := NewDeviceRepository().Flush(); err != nil {
+		t.Fatalf("Flush without snapshot file returned error: %v", err)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Tests for Add and Remove
+// -----------------------------------------------------------------------------
+
+func TestAddRemove_RoundTrip(t *testing.T) {
+	repo := NewDeviceRepository()
+	ctx := context.Background()
+
+	if err := repo.Add(ctx, domain.NewDeviceStats("dev-1")); err != nil {
+		t.Fatalf("Add returned error: %v", err)
+	}
+	if err := repo.Add(ctx, domain.NewDeviceStats("dev-1")); !errors.Is(err, coreerrors.ErrDeviceExists) {
+		t.Fatalf("expected ErrDeviceExists on second Add, got %v", err)
+	}
+	snap, err := repo.GetSnapshot(ctx, domain.DefaultTenant, "dev-1")
+	if err != nil || snap.Version != 1 || snap.UpdatedAt.IsZero() {
+		t.Fatalf("unexpected snapshot %+v (%v)", snap, err)
+	}
+
+	if err := repo.Remove(ctx, domain.DefaultTenant, "dev-1"); err != nil {
+		t.Fatalf("Remove returned error: %v", err)
+	}
+	if repo.Exists(ctx, domain.DefaultTenant, "dev-1") {
+		t.Fatal("expected device to be gone after Remove")
+	}
+	if err := repo.Remove(ctx, domain.DefaultTenant, "dev-1"); !errors.Is(err, coreerrors.ErrDeviceNotFound) {
+		t.Fatalf("expected ErrDeviceNotFound on second Remove, got %v", err)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Tests for labels
+// -----------------------------------------------------------------------------
+
+func TestLoadFromCSV_LabelsColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.csv")
+	content := "device_id,site,labels\n" +
+		"60-6b-44-84-dc-64,north,floor=1;wing=a\n" +
+		"b4-45-52-a2-f1-3c,north,floor=2\n" +
+		"26-9a-66-01-33-83,south,\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewDeviceRepository()
+	if err := repo.LoadFromCSV(path); err != nil {
+		t.Fatalf("LoadFromCSV: %v", err)
+	}
+
+	d, _ := repo.GetSnapshot(context.Background(), domain.DefaultTenant, "60-6b-44-84-dc-64")
+	if d.Site != "north" || d.Labels["floor"] != "1" || d.Labels["wing"] != "a" {
+		t.Fatalf("unexpected device %+v", d)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.csv")
+	_ = os.WriteFile(bad, []byte("device_id,labels\n60-6b-44-84-dc-64,floor\n"), 0o644)
+	if err := NewDeviceRepository().LoadFromCSV(bad); err == nil {
+		t.Fatal("expected invalid labels to be rejected")
+	}
+}
+
+func TestSelect_UsesIndexAcrossChanges(t *testing.T) {
+	repo := NewDeviceRepository()
+	ctx := context.Background()
+	for id, labels := range map[string]map[string]string{
+		"a": {"site": "north", "floor": "1"},
+		"b": {"site": "north", "floor": "2"},
+		"c": {"site": "south", "floor": "2"},
+	} {
+		d := domain.NewDeviceStats(id)
+		d.SetLabels(labels)
+		if err := repo.Add(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = repo.Add(ctx, domain.NewDeviceStats("d"))
+
+	selectIDs := func(s string) []string {
+		sel, err := domain.ParseSelector(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo.Select(ctx, domain.DefaultTenant, sel)
+	}
+	cases := map[string][]string{
+		"site=north":         {"a", "b"},
+		"site=north,floor=2": {"b"},
+		"floor":              {"a", "b", "c"},
+		"!floor":             {"d"},
+		"site!=north":        {"c", "d"},
+		"site=west":          {},
+		"":                   {"a", "b", "c", "d"},
+	}
+	for s, want := range cases {
+		if got := selectIDs(s); !slices.Equal(got, want) {
+			t.Errorf("%q: expected %v, got %v", s, want, got)
+		}
+	}
+
+	if err := repo.SetLabels(ctx, domain.DefaultTenant, "b", map[string]string{"site": "south", "floor": "2"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = repo.Remove(ctx, domain.DefaultTenant, "a")
+	if got := selectIDs("site=north"); len(got) != 0 {
+		t.Errorf("expected no north devices after relabel and removal, got %v", got)
+	}
+	if got := selectIDs("site=south,floor=2"); !slices.Equal(got, []string{"b", "c"}) {
+		t.Errorf("unexpected south devices %v", got)
+	}
+	if err := repo.SetLabels(ctx, domain.DefaultTenant, "missing", nil); !errors.Is(err, coreerrors.ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound, got %v", err)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Tests for tenants
+// -----------------------------------------------------------------------------
+
+func TestTenants_AreIsolated(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "acme.csv")
+	if err := os.WriteFile(csvPath, []byte("device_id,site\ndev-1,north\ndev-2,south\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewDeviceRepository()
+	repo.addDevice(domain.DefaultTenant, "dev-1", map[string]string{"site": "north"}, domain.RateLimit{})
+	if err := repo.LoadTenantCSV("acme", csvPath); err != nil {
+		t.Fatalf("LoadTenantCSV: %v", err)
+	}
+	_ = repo.WithDevice(ctx, "acme", "dev-1", func(d *domain.DeviceStats) error { d.HeartbeatCount = 5; return nil })
+
+	if d, _ := repo.GetSnapshot(ctx, domain.DefaultTenant, "dev-1"); d.HeartbeatCount != 0 || d.Tenant != domain.DefaultTenant {
+		t.Fatalf("acme write leaked into the default tenant: %+v", d)
+	}
+	if repo.Exists(ctx, domain.DefaultTenant, "dev-2") || repo.Exists(ctx, "globex", "dev-1") {
+		t.Fatal("expected devices to be visible in their own tenant only")
+	}
+	if got := repo.Select(ctx, "acme", domain.Selector{{Key: "site", Op: domain.SelectorEquals, Value: "north"}}); !slices.Equal(got, []string{"dev-1"}) {
+		t.Fatalf("unexpected acme selection %v", got)
+	}
+	if got := repo.Tenants(); !slices.Equal(got, []string{"acme", domain.DefaultTenant}) || repo.Count() != 3 {
+		t.Fatalf("unexpected tenants %v (count %d)", got, repo.Count())
+	}
+
+	// Snapshots keep the tenant; older entries without one are the default tenant's.
+	path := filepath.Join(dir, "devices.json")
+	if err := repo.UseSnapshotFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	restored := NewDeviceRepository()
+	if err := restored.UseSnapshotFile(path); err != nil {
+		t.Fatalf("UseSnapshotFile: %v", err)
+	}
+	if d, err := restored.GetSnapshot(ctx, "acme", "dev-1"); err != nil || d.HeartbeatCount != 5 {
+		t.Fatalf("acme device not restored: %+v, %v", d, err)
+	}
+	if err := os.WriteFile(path, []byte(`[{"ID":"dev-9"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	legacy := NewDeviceRepository()
+	if err := legacy.UseSnapshotFile(path); err != nil || !legacy.Exists(ctx, domain.DefaultTenant, "dev-9") {
+		t.Fatalf("legacy snapshot not restored into the default tenant: %v", err)
+	}
+
+	_ = repo.Remove(ctx, "acme", "dev-1")
+	_ = repo.Remove(ctx, "acme", "dev-2")
+	if got := repo.Tenants(); !slices.Equal(got, []string{domain.DefaultTenant}) {
+		t.Fatalf("expected emptied tenant to be dropped, got %v", got)
+	}
+}
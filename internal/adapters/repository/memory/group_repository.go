@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+	"sort"
+	"sync"
+)
+
+type GroupRepository struct {
+	mu sync.RWMutex
+	// groups holds each tenant's groups by name.
+	groups map[string]map[string]domain.Group
+}
+
+// NewGroupRepository creates an empty in-memory GroupRepository.
+func NewGroupRepository() *GroupRepository {
+	return &GroupRepository{groups: make(map[string]map[string]domain.Group)}
+}
+
+func (r *GroupRepository) SaveGroup(g domain.Group) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byName, ok := r.groups[g.Tenant]
+	if !ok {
+		byName = make(map[string]domain.Group)
+		r.groups[g.Tenant] = byName
+	}
+	if _, ok := byName[g.Name]; ok {
+		return coreerrors.ErrGroupExists
+	}
+	byName[g.Name] = g
+	return nil
+}
+
+func (r *GroupRepository) GetGroup(tenant, name string) (*domain.Group, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	g, ok := r.groups[tenant][name]
+	if !ok {
+		return nil, coreerrors.ErrGroupNotFound
+	}
+	return &g, nil
+}
+
+// ListGroups returns the tenant's groups ordered by name.
+func (r *GroupRepository) ListGroups(tenant string) []domain.Group {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]domain.Group, 0, len(r.groups[tenant]))
+	for _, g := range r.groups[tenant] {
+		out = append(out, g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (r *GroupRepository) DeleteGroup(tenant, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.groups[tenant][name]; !ok {
+		return coreerrors.ErrGroupNotFound
+	}
+	delete(r.groups[tenant], name)
+	return nil
+}
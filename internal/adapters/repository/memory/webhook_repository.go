@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+	"sort"
+	"sync"
+)
+
+type WebhookRepository struct {
+	mu    sync.RWMutex
+	hooks map[string]domain.Webhook
+}
+
+// NewWebhookRepository creates an empty in-memory WebhookRepository.
+func NewWebhookRepository() *WebhookRepository {
+	return &WebhookRepository{
+		hooks: make(map[string]domain.Webhook),
+	}
+}
+
+func (r *WebhookRepository) Save(w domain.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w.Events = append([]domain.EventType(nil), w.Events...)
+	r.hooks[w.ID] = w
+	return nil
+}
+
+func (r *WebhookRepository) Get(id string) (*domain.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.hooks[id]
+	if !ok {
+		return nil, coreerrors.ErrWebhookNotFound
+	}
+	return &w, nil
+}
+
+// List returns all webhooks ordered by creation time.
+func (r *WebhookRepository) List() []domain.Webhook {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]domain.Webhook, 0, len(r.hooks))
+	for _, w := range r.hooks {
+		out = append(out, w)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out
+}
+
+func (r *WebhookRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.hooks[id]; !ok {
+		return coreerrors.ErrWebhookNotFound
+	}
+	delete(r.hooks, id)
+	return nil
+}
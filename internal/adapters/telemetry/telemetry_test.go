@@ -0,0 +1,49 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup_StdoutExportsSpans(t *testing.T) {
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Config{
+		Exporter:    ExporterStdout,
+		ServiceName: "fleet-test",
+		Output:      &out,
+	})
+	if err != nil {
+		t.Fatalf("Setup returned error: %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "unit-of-work")
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), `"Name":"unit-of-work"`) {
+		t.Fatalf("expected span in stdout output, got %s", out.String())
+	}
+	if !strings.Contains(out.String(), "fleet-test") {
+		t.Fatalf("expected service name in stdout output, got %s", out.String())
+	}
+}
+
+func TestSetup_NoneAndUnknownExporters(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{})
+	if err != nil {
+		t.Fatalf("Setup with no exporter returned error: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("no-op shutdown returned error: %v", err)
+	}
+
+	if _, err := Setup(context.Background(), Config{Exporter: "zipkin"}); err == nil {
+		t.Fatalf("expected error for unknown exporter")
+	}
+}
@@ -0,0 +1,311 @@
+package udp
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/logging"
+	"safelyyou/pkg/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Heartbeat datagrams come in two encodings.
+//
+// Text (UTF-8, space separated):
+//
+//	<device_id> <sent_at>[ <hmac>]
+//
+// where sent_at is RFC 3339 or Unix milliseconds and hmac is the hex
+// HMAC-SHA256 of "<device_id> <sent_at>".
+//
+// Binary (big endian):
+//
+//	byte 0      version, always 0x01
+//	bytes 1-6   device ID (the six hex pairs of the ID)
+//	bytes 7-14  sent_at as Unix milliseconds (int64)
+//	bytes 15-46 optional HMAC-SHA256 of bytes 0-14
+const (
+	binaryVersion    = 0x01
+	binaryBodyLen    = 15
+	binarySignedLen  = binaryBodyLen + sha256.Size
+	maxDatagramBytes = 512
+)
+
+// rejectLogEvery samples the rejection log, so that a flood of bad
+// datagrams shows up in the counters rather than as a flood of log lines.
+const rejectLogEvery = 100
+
+// DefaultReplayWindow is how far the sent_at of a signed datagram may be
+// from server time, unless set with WithReplayWindow.
+const DefaultReplayWindow = 2 * time.Minute
+
+var (
+	errMalformed = errors.New("malformed datagram")
+	errSignature = errors.New("bad or missing signature")
+	errReplayed  = errors.New("stale or replayed datagram")
+)
+
+// Counters are the listener's packet counters. BadSignature counts
+// datagrams with a missing or invalid HMAC, Replayed signed datagrams
+// dropped as stale or already seen; both are also Rejected.
+type Counters struct {
+	Received     uint64 `json:"received"`
+	Accepted     uint64 `json:"accepted"`
+	Malformed    uint64 `json:"malformed"`
+	Rejected     uint64 `json:"rejected"`
+	BadSignature uint64 `json:"bad_signature"`
+	Replayed     uint64 `json:"replayed"`
+}
+
+// Listener receives heartbeat datagrams and feeds them to
+// DeviceService.RecordHeartbeat. Heartbeats are fire-and-forget: nothing is
+// sent back, failures only show up in the counters.
+//
+// The HMAC of a signed datagram covers its sent_at, so a captured datagram
+// cannot be altered, only resent. Signed datagrams are therefore dropped
+// when sent_at is more than the replay window away from server time, and
+// when the device already sent that sent_at within the window.
+type Listener struct {
+	deviceSvc    ports.DeviceService
+	secret       []byte
+	replayWindow time.Duration
+	now          func() time.Time
+
+	// seenMu guards seen, the sent_at of recent signed datagrams keyed by
+	// device ID and sent_at, and lastSweep.
+	seenMu    sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+
+	rejectLog *logging.Sampler
+
+	received     atomic.Uint64
+	accepted     atomic.Uint64
+	malformed    atomic.Uint64
+	rejected     atomic.Uint64
+	badSignature atomic.Uint64
+	replayed     atomic.Uint64
+}
+
+// ListenerOption configures optional Listener behaviour.
+type ListenerOption func(*Listener)
+
+// WithReplayWindow sets how far the sent_at of a signed datagram may be
+// from server time, in either direction.
+func WithReplayWindow(d time.Duration) ListenerOption {
+	return func(l *Listener) { l.replayWindow = d }
+}
+
+// NewListener constructs a listener. When secret is non-empty every
+// datagram must carry a valid HMAC and a fresh, not yet seen sent_at.
+func NewListener(svc ports.DeviceService, secret string, opts ...ListenerOption) *Listener {
+	l := &Listener{
+		deviceSvc:    svc,
+		secret:       []byte(secret),
+		replayWindow: DefaultReplayWindow,
+		now:          time.Now,
+		seen:         make(map[string]time.Time),
+		rejectLog:    logging.NewSampler(rejectLogEvery),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// ListenAndServe listens on addr (e.g. ":9999") until ctx is cancelled.
+func (l *Listener) ListenAndServe(ctx context.Context, addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	return l.Serve(ctx, conn)
+}
+
+// Serve reads datagrams from conn until ctx is cancelled, then closes conn.
+func (l *Listener) Serve(ctx context.Context, conn net.PacketConn) error {
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	buf := make([]byte, maxDatagramBytes)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		l.handle(ctx, buf[:n])
+	}
+}
+
+// Counters returns a snapshot of the packet counters.
+func (l *Listener) Counters() Counters {
+	return Counters{
+		Received:     l.received.Load(),
+		Accepted:     l.accepted.Load(),
+		Malformed:    l.malformed.Load(),
+		Rejected:     l.rejected.Load(),
+		BadSignature: l.badSignature.Load(),
+		Replayed:     l.replayed.Load(),
+	}
+}
+
+func (l *Listener) handle(ctx context.Context, packet []byte) {
+	l.received.Add(1)
+
+	deviceID, sentAt, err := l.decode(packet)
+	if err != nil {
+		if errors.Is(err, errMalformed) {
+			l.malformed.Add(1)
+			return
+		}
+		l.badSignature.Add(1)
+		l.reject(ctx, deviceID, err)
+		return
+	}
+	if err := l.checkFresh(deviceID, sentAt); err != nil {
+		l.replayed.Add(1)
+		l.reject(ctx, deviceID, err)
+		return
+	}
+
+	if err := l.deviceSvc.RecordHeartbeat(ctx, deviceID, sentAt); err != nil {
+		l.reject(ctx, deviceID, err)
+		return
+	}
+	l.accepted.Add(1)
+}
+
+// reject counts a rejected datagram and logs a sample of them.
+func (l *Listener) reject(ctx context.Context, deviceID string, err error) {
+	total := l.rejected.Add(1)
+	if l.rejectLog.Allow() {
+		logging.For("udp").WarnContext(ctx, "heartbeat rejected",
+			"device_id", deviceID, "error", err, "rejected_total", total, "sample_every", rejectLogEvery)
+	}
+}
+
+func (l *Listener) decode(packet []byte) (string, time.Time, error) {
+	if len(packet) > 0 && packet[0] == binaryVersion {
+		return l.decodeBinary(packet)
+	}
+	return l.decodeText(packet)
+}
+
+func (l *Listener) decodeBinary(packet []byte) (string, time.Time, error) {
+	if len(packet) != binaryBodyLen && len(packet) != binarySignedLen {
+		return "", time.Time{}, fmt.Errorf("%w: unexpected length %d", errMalformed, len(packet))
+	}
+	body := packet[:binaryBodyLen]
+	if err := l.verify(body, packet[binaryBodyLen:]); err != nil {
+		return "", time.Time{}, err
+	}
+
+	pairs := make([]string, 6)
+	for i, b := range body[1:7] {
+		pairs[i] = hex.EncodeToString([]byte{b})
+	}
+	millis := int64(binary.BigEndian.Uint64(body[7:15]))
+	return strings.Join(pairs, "-"), time.UnixMilli(millis).UTC(), nil
+}
+
+func (l *Listener) decodeText(packet []byte) (string, time.Time, error) {
+	fields := strings.Fields(string(bytes.TrimSpace(packet)))
+	if len(fields) != 2 && len(fields) != 3 {
+		return "", time.Time{}, fmt.Errorf("%w: expected 2 or 3 fields", errMalformed)
+	}
+
+	deviceID := fields[0]
+	if !utils.IsId(deviceID) {
+		return "", time.Time{}, fmt.Errorf("%w: invalid device ID", errMalformed)
+	}
+	sentAt, err := parseSentAt(fields[1])
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%w: %v", errMalformed, err)
+	}
+
+	var sig []byte
+	if len(fields) == 3 {
+		if sig, err = hex.DecodeString(fields[2]); err != nil {
+			return "", time.Time{}, fmt.Errorf("%w: signature is not hex", errMalformed)
+		}
+	}
+	if err := l.verify([]byte(fields[0]+" "+fields[1]), sig); err != nil {
+		return "", time.Time{}, err
+	}
+	return deviceID, sentAt, nil
+}
+
+// verify checks sig against body when a secret is configured. Without a
+// secret, signatures are ignored.
+func (l *Listener) verify(body, sig []byte) error {
+	if len(l.secret) == 0 {
+		return nil
+	}
+	if len(sig) == 0 || !hmac.Equal(Sign(l.secret, body), sig) {
+		return errSignature
+	}
+	return nil
+}
+
+// checkFresh rejects a signed datagram whose sent_at is outside the replay
+// window, or was already seen for the device, and remembers it otherwise.
+// Unsigned datagrams can be forged at will, so they are not checked.
+func (l *Listener) checkFresh(deviceID string, sentAt time.Time) error {
+	if len(l.secret) == 0 {
+		return nil
+	}
+	now := l.now()
+	if sentAt.Sub(now).Abs() > l.replayWindow {
+		return errReplayed
+	}
+
+	l.seenMu.Lock()
+	defer l.seenMu.Unlock()
+
+	// Entries older than the window can no longer match a fresh datagram.
+	if now.Sub(l.lastSweep) > l.replayWindow {
+		for key, at := range l.seen {
+			if now.Sub(at) > l.replayWindow {
+				delete(l.seen, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	key := deviceID + " " + strconv.FormatInt(sentAt.UnixNano(), 10)
+	if _, ok := l.seen[key]; ok {
+		return errReplayed
+	}
+	l.seen[key] = sentAt
+	return nil
+}
+
+func parseSentAt(s string) (time.Time, error) {
+	if millis, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(millis).UTC(), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// Sign returns the HMAC-SHA256 of body, as expected in signed datagrams.
+func Sign(secret, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,198 @@
+package udp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/logging"
+)
+
+const knownDeviceID = "60-6b-44-84-dc-64"
+
+// recordingService accepts heartbeats from knownDeviceID only.
+type recordingService struct {
+	ports.DeviceService
+	mu     sync.Mutex
+	sentAt []time.Time
+}
+
+func (s *recordingService) RecordHeartbeat(_ context.Context, id string, sentAt time.Time) error {
+	if id != knownDeviceID {
+		return coreerrors.ErrDeviceNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sentAt = append(s.sentAt, sentAt)
+	return nil
+}
+
+func binaryHeartbeat(millis int64, secret string) []byte {
+	pkt := []byte{binaryVersion, 0x60, 0x6b, 0x44, 0x84, 0xdc, 0x64}
+	pkt = binary.BigEndian.AppendUint64(pkt, uint64(millis))
+	if secret != "" {
+		pkt = append(pkt, Sign([]byte(secret), pkt)...)
+	}
+	return pkt
+}
+
+func TestHandle_TextAndBinaryWithoutSecret(t *testing.T) {
+	svc := &recordingService{}
+	l := NewListener(svc, "")
+
+	sentAt := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	l.handle(context.Background(), []byte(knownDeviceID+" 2025-11-09T10:00:00Z\n"))
+	l.handle(context.Background(), []byte(knownDeviceID+" 1762682400000"))
+	l.handle(context.Background(), binaryHeartbeat(sentAt.UnixMilli(), ""))
+
+	if got := l.Counters(); got.Accepted != 3 || got.Received != 3 {
+		t.Fatalf("expected 3 accepted, got %+v", got)
+	}
+	for i, ts := range svc.sentAt {
+		if !ts.Equal(sentAt) {
+			t.Fatalf("heartbeat %d: expected sent_at=%v, got %v", i, sentAt, ts)
+		}
+	}
+}
+
+func TestHandle_CountsMalformedAndRejected(t *testing.T) {
+	l := NewListener(&recordingService{}, "")
+
+	l.handle(context.Background(), []byte("bad-id 1762682400000"))
+	l.handle(context.Background(), []byte(knownDeviceID))
+	l.handle(context.Background(), []byte(knownDeviceID+" yesterday"))
+	l.handle(context.Background(), []byte{binaryVersion, 0x01})
+	l.handle(context.Background(), []byte("aa-bb-cc-11-22-33 1762682400000")) // unknown device
+
+	got := l.Counters()
+	if got.Malformed != 4 || got.Rejected != 1 || got.Accepted != 0 {
+		t.Fatalf("expected 4 malformed and 1 rejected, got %+v", got)
+	}
+}
+
+func TestHandle_SecretRequiresValidHMAC(t *testing.T) {
+	const secret = "s3cret"
+	svc := &recordingService{}
+	l := NewListener(svc, secret)
+	l.now = func() time.Time { return time.UnixMilli(1762682400000) }
+
+	body := knownDeviceID + " 1762682400000"
+	sig := hex.EncodeToString(Sign([]byte(secret), []byte(body)))
+	wrong := hex.EncodeToString(Sign([]byte("other"), []byte(body)))
+
+	l.handle(context.Background(), []byte(body+" "+sig))
+	l.handle(context.Background(), binaryHeartbeat(1762682401000, secret))
+	l.handle(context.Background(), []byte(body))
+	l.handle(context.Background(), []byte(body+" "+wrong))
+	l.handle(context.Background(), binaryHeartbeat(1762682400000, ""))
+
+	got := l.Counters()
+	if got.Accepted != 2 || got.Rejected != 3 {
+		t.Fatalf("expected 2 accepted and 3 rejected, got %+v", got)
+	}
+}
+
+func TestHandle_SecretDropsStaleAndReplayedDatagrams(t *testing.T) {
+	const secret = "s3cret"
+	svc := &recordingService{}
+	l := NewListener(svc, secret, WithReplayWindow(time.Minute))
+	now := time.UnixMilli(1762682400000)
+	l.now = func() time.Time { return now }
+
+	signed := func(millis int64) []byte {
+		body := knownDeviceID + " " + strconv.FormatInt(millis, 10)
+		return []byte(body + " " + hex.EncodeToString(Sign([]byte(secret), []byte(body))))
+	}
+
+	l.handle(context.Background(), signed(now.UnixMilli()))
+	l.handle(context.Background(), signed(now.UnixMilli()))                     // replayed
+	l.handle(context.Background(), binaryHeartbeat(now.UnixMilli(), secret))    // same sent_at, other encoding
+	l.handle(context.Background(), signed(now.Add(-2*time.Minute).UnixMilli())) // stale
+	l.handle(context.Background(), signed(now.Add(2*time.Minute).UnixMilli()))  // too far ahead
+	l.handle(context.Background(), signed(now.Add(30*time.Second).UnixMilli()))
+
+	got := l.Counters()
+	if got.Accepted != 2 || got.Replayed != 4 || got.Rejected != 4 {
+		t.Fatalf("expected 2 accepted and 4 replayed, got %+v", got)
+	}
+
+	// Once the window has passed, the replayed datagram is stale.
+	now = now.Add(2 * time.Minute)
+	l.handle(context.Background(), signed(now.Add(-2*time.Minute).UnixMilli()))
+	if got := l.Counters(); got.Replayed != 5 || len(svc.sentAt) != 2 {
+		t.Fatalf("expected the old datagram to stay rejected, got %+v", got)
+	}
+	l.handle(context.Background(), signed(now.UnixMilli()))
+	if len(l.seen) != 1 {
+		t.Fatalf("expected expired entries to be swept, got %v", l.seen)
+	}
+}
+
+func TestHandle_SamplesRejectionLog(t *testing.T) {
+	var buf bytes.Buffer
+	logging.Setup(logging.Config{Level: slog.LevelInfo, Output: &buf})
+	t.Cleanup(func() { logging.Setup(logging.Config{Level: slog.LevelInfo}) })
+
+	l := NewListener(&recordingService{}, "s3cret")
+	for i := 0; i < 250; i++ {
+		l.handle(context.Background(), []byte(knownDeviceID+" 1762682400000"))
+	}
+
+	got := l.Counters()
+	if got.Rejected != 250 || got.BadSignature != 250 {
+		t.Fatalf("expected 250 rejected for bad signature, got %+v", got)
+	}
+	if lines := strings.Count(buf.String(), "heartbeat rejected"); lines != 3 {
+		t.Fatalf("expected 3 sampled log lines for 250 rejections, got %d", lines)
+	}
+}
+
+func TestServe_ReceivesOverUDPAndStopsOnCancel(t *testing.T) {
+	svc := &recordingService{}
+	l := NewListener(svc, "")
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- l.Serve(ctx, conn) }()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+	if _, err := client.Write([]byte(knownDeviceID + " 1762682400000")); err != nil {
+		t.Fatalf("failed to send datagram: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for l.Counters().Accepted == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if l.Counters().Accepted != 1 {
+		t.Fatalf("expected datagram to be accepted, got %+v", l.Counters())
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Serve returned error after cancel: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Serve did not return after cancel")
+	}
+}
@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"safelyyou/internal/core/domain"
+)
+
+func TestSend_PostsSignedPayload(t *testing.T) {
+	var (
+		gotBody    []byte
+		gotHeaders http.Header
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeaders = r.Header.Clone()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer receiver.Close()
+
+	sender := NewHTTPSender(receiver.Client())
+	hook := domain.Webhook{ID: "hook-1", URL: receiver.URL, Secret: "s3cret"}
+	delivery := domain.Delivery{
+		ID:        "del-1",
+		WebhookID: hook.ID,
+		Event: domain.Event{
+			ID:         "evt-1",
+			Type:       domain.EventDeviceOffline,
+			DeviceID:   "60-6b-44-84-dc-64",
+			OccurredAt: time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC),
+		},
+	}
+
+	code, err := sender.Send(context.Background(), hook, delivery)
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", code)
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatalf("failed to unmarshal payload: %v", err)
+	}
+	if payload.Type != "device.offline" || payload.DeviceID != "60-6b-44-84-dc-64" || payload.ID != "evt-1" {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+
+	if gotHeaders.Get(HeaderEvent) != "device.offline" {
+		t.Fatalf("expected %s=device.offline, got %q", HeaderEvent, gotHeaders.Get(HeaderEvent))
+	}
+	if gotHeaders.Get(HeaderDelivery) != "del-1" {
+		t.Fatalf("expected %s=del-1, got %q", HeaderDelivery, gotHeaders.Get(HeaderDelivery))
+	}
+	ts := gotHeaders.Get(HeaderTimestamp)
+	if !Verify("s3cret", ts, gotBody, gotHeaders.Get(HeaderSignature)) {
+		t.Fatalf("signature %q does not verify", gotHeaders.Get(HeaderSignature))
+	}
+	if Verify("wrong", ts, gotBody, gotHeaders.Get(HeaderSignature)) {
+		t.Fatalf("signature should not verify with another secret")
+	}
+}
+
+func TestSend_NoSecretOmitsSignature(t *testing.T) {
+	var gotSignature string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(HeaderSignature)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	sender := NewHTTPSender(receiver.Client())
+	_, err := sender.Send(context.Background(), domain.Webhook{URL: receiver.URL}, domain.Delivery{})
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if gotSignature != "" {
+		t.Fatalf("expected no signature header, got %q", gotSignature)
+	}
+}
+
+func TestSend_UnreachableReturnsError(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := receiver.URL
+	receiver.Close()
+
+	sender := NewHTTPSender(nil)
+	code, err := sender.Send(context.Background(), domain.Webhook{URL: url}, domain.Delivery{})
+	if err == nil {
+		t.Fatalf("expected error for closed receiver")
+	}
+	if code != 0 {
+		t.Fatalf("expected status 0 on transport error, got %d", code)
+	}
+}
@@ -0,0 +1,162 @@
+// Package config defines the server's typed configuration and loads it
+// from, in increasing order of precedence:
+//
+//  1. built-in defaults (Defaults)
+//  2. a YAML or TOML file (--config or CONFIG_FILE)
+//  3. environment variables (the `env` tag on each field, e.g. PORT)
+//  4. command-line flags named after the file keys (e.g. --server.port)
+//
+// Fields tagged `secret:"true"` are redacted by Redacted.
+package config
+
+import (
+	"safelyyou/internal/core/domain"
+	"time"
+)
+
+// Config is the effective server configuration.
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	TLS       TLSConfig       `yaml:"tls"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Timeouts  TimeoutsConfig  `yaml:"timeouts"`
+	Retention RetentionConfig `yaml:"retention"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Tenants   TenantsConfig   `yaml:"tenants"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Webhooks  WebhooksConfig  `yaml:"webhooks"`
+	Alerts    AlertsConfig    `yaml:"alerts"`
+	Clock     ClockConfig     `yaml:"clock"`
+	MQTT      MQTTConfig      `yaml:"mqtt"`
+	UDP       UDPConfig       `yaml:"udp"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Logging   LoggingConfig   `yaml:"logging"`
+}
+
+type ServerConfig struct {
+	Port     int `yaml:"port" env:"PORT" usage:"HTTP listen port"`
+	GRPCPort int `yaml:"grpc_port" env:"GRPC_PORT" usage:"gRPC listen port"`
+}
+
+// TLSConfig enables TLS on the HTTP and gRPC listeners when both files are set.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file" env:"TLS_CERT_FILE" usage:"PEM certificate for HTTP and gRPC"`
+	KeyFile  string `yaml:"key_file" env:"TLS_KEY_FILE" usage:"PEM private key for HTTP and gRPC"`
+}
+
+// Enabled reports whether TLS is configured.
+func (t TLSConfig) Enabled() bool { return t.CertFile != "" && t.KeyFile != "" }
+
+type StorageConfig struct {
+	// Backend selects the device store; only "memory" exists today.
+	Backend          string `yaml:"backend" env:"STORAGE_BACKEND" usage:"device store backend (memory)"`
+	DeviceCSV        string `yaml:"device_csv" env:"DEVICE_CSV" usage:"CSV of known devices"`
+	SnapshotPath     string `yaml:"snapshot_path" env:"DEVICE_SNAPSHOT_PATH" usage:"device state file restored at start and flushed at shutdown"`
+	WebhookQueuePath string `yaml:"webhook_queue_path" env:"WEBHOOK_QUEUE_PATH" usage:"persistent webhook delivery queue"`
+}
+
+type TimeoutsConfig struct {
+	ReadHeader time.Duration `yaml:"read_header" env:"READ_HEADER_TIMEOUT" usage:"HTTP request header read timeout"`
+	Shutdown   time.Duration `yaml:"shutdown" env:"SHUTDOWN_TIMEOUT" usage:"drain deadline for in-flight requests on shutdown"`
+}
+
+type RetentionConfig struct {
+	Series time.Duration `yaml:"series" env:"SERIES_RETENTION" usage:"per-minute history kept per device"`
+}
+
+// AuthConfig protects the /api routes with a bearer token when Token is set,
+// and with HMAC request signatures when SigningSecret is set.
+type AuthConfig struct {
+	Token         string `yaml:"token" env:"API_TOKEN" secret:"true" usage:"bearer token required on /api routes"`
+	SigningSecret string `yaml:"signing_secret" env:"API_SIGNING_SECRET" secret:"true" usage:"HMAC secret required to sign /api requests"`
+}
+
+// TenantsConfig separates customers sharing the server. Each setting is a
+// comma-separated list of "tenant:value" pairs: a tenant authenticates with
+// its own bearer token, sees only its own devices, may have its devices
+// loaded from its own CSV and may be capped to a number of devices.
+// Everything else belongs to the default tenant.
+type TenantsConfig struct {
+	Tokens     string `yaml:"tokens" env:"TENANT_TOKENS" secret:"true" usage:"per-tenant bearer tokens, e.g. acme:token1,globex:token2"`
+	DeviceCSVs string `yaml:"device_csvs" env:"TENANT_DEVICE_CSVS" usage:"per-tenant device CSVs, e.g. acme:acme.csv"`
+	Quotas     string `yaml:"quotas" env:"TENANT_QUOTAS" usage:"per-tenant device limits, e.g. acme:100"`
+}
+
+// RateLimitConfig holds ingestion limits as "device=rate:burst,ip=rate:burst"
+// rules; a rate of 0 or an omitted key leaves that dimension unlimited.
+// Devices may override the device limit with a rate_limit CSV column. The
+// device limit applies to every ingestion adapter, the IP limit to HTTP.
+type RateLimitConfig struct {
+	Heartbeat string `yaml:"heartbeat" env:"RATE_LIMIT_HEARTBEAT" usage:"heartbeat ingestion limit, e.g. device=1:10,ip=100:200"`
+	Stats     string `yaml:"stats" env:"RATE_LIMIT_STATS" usage:"stats ingestion limit, e.g. device=1:10,ip=100:200"`
+}
+
+// WebhooksConfig also bounds the delivery log: delivered and dead
+// deliveries are dropped beyond HistoryLimit or once older than HistoryTTL.
+type WebhooksConfig struct {
+	URLs         []string      `yaml:"urls" env:"WEBHOOK_URLS" usage:"webhook URLs registered at startup (comma-separated)"`
+	Secret       string        `yaml:"secret" env:"WEBHOOK_SECRET" secret:"true" usage:"HMAC secret for configured webhooks"`
+	HistoryLimit int           `yaml:"history_limit" env:"WEBHOOK_HISTORY_LIMIT" usage:"finished deliveries kept in the delivery log (0 keeps all)"`
+	HistoryTTL   time.Duration `yaml:"history_ttl" env:"WEBHOOK_HISTORY_TTL" usage:"age after which finished deliveries are dropped (0 keeps all)"`
+}
+
+type AlertsConfig struct {
+	UploadThreshold time.Duration `yaml:"upload_threshold" env:"UPLOAD_THRESHOLD" usage:"uploads slower than this raise an event (0 disables)"`
+	OfflineAfter    time.Duration `yaml:"offline_after" env:"OFFLINE_AFTER" usage:"silence before a device is reported offline (0 disables)"`
+	EvalInterval    time.Duration `yaml:"eval_interval" env:"ALERT_EVAL_INTERVAL" usage:"alert rule evaluation interval"`
+}
+
+// ClockConfig decides how far device timestamps are trusted. Devices whose
+// estimated clock offset exceeds SkewThreshold are flagged as skewed.
+type ClockConfig struct {
+	TimestampPolicy string        `yaml:"timestamp_policy" env:"TIMESTAMP_POLICY" usage:"time reports are recorded at: trust_device, trust_server, correct or reject"`
+	SkewThreshold   time.Duration `yaml:"skew_threshold" env:"CLOCK_SKEW_THRESHOLD" usage:"device clock offset flagged as skewed (0 disables)"`
+}
+
+type MQTTConfig struct {
+	BrokerURL   string `yaml:"broker_url" env:"MQTT_BROKER_URL" usage:"MQTT broker URL (empty disables)"`
+	ClientID    string `yaml:"client_id" env:"MQTT_CLIENT_ID" usage:"MQTT client ID"`
+	Username    string `yaml:"username" env:"MQTT_USERNAME" usage:"MQTT username"`
+	Password    string `yaml:"password" env:"MQTT_PASSWORD" secret:"true" usage:"MQTT password"`
+	TopicPrefix string `yaml:"topic_prefix" env:"MQTT_TOPIC_PREFIX" usage:"MQTT topic prefix"`
+}
+
+type UDPConfig struct {
+	Addr       string `yaml:"addr" env:"UDP_HEARTBEAT_ADDR" usage:"UDP heartbeat listen address (empty disables)"`
+	HMACSecret string `yaml:"hmac_secret" env:"UDP_HMAC_SECRET" secret:"true" usage:"HMAC secret required on UDP datagrams"`
+	// ReplayWindow bounds how far the sent_at of a signed datagram may be
+	// from server time; repeats within it are dropped.
+	ReplayWindow time.Duration `yaml:"replay_window" env:"UDP_REPLAY_WINDOW" usage:"accepted sent_at offset of signed UDP datagrams"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" usage:"span exporter: none, stdout or otlp"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" usage:"OTLP/gRPC collector address"`
+	Insecure    bool    `yaml:"insecure" env:"OTEL_EXPORTER_OTLP_INSECURE" usage:"disable TLS towards the collector"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" usage:"fraction of new traces recorded"`
+}
+
+type LoggingConfig struct {
+	// Level is a default level plus per-component overrides, e.g. "info,mqtt=warn".
+	Level           string `yaml:"level" env:"LOG_LEVEL" usage:"log level spec, e.g. info,mqtt=warn"`
+	Format          string `yaml:"format" env:"LOG_FORMAT" usage:"log format: json or text"`
+	HeartbeatSample uint64 `yaml:"heartbeat_sample" env:"LOG_HEARTBEAT_SAMPLE" usage:"log one successful heartbeat request out of N"`
+}
+
+// Defaults returns the configuration used when nothing overrides it.
+func Defaults() Config {
+	return Config{
+		Server:    ServerConfig{Port: 8080, GRPCPort: 9090},
+		Storage:   StorageConfig{Backend: "memory", DeviceCSV: "devices.csv"},
+		Timeouts:  TimeoutsConfig{ReadHeader: 10 * time.Second, Shutdown: 15 * time.Second},
+		Retention: RetentionConfig{Series: 24 * time.Hour},
+		RateLimit: RateLimitConfig{Heartbeat: "device=10:20", Stats: "device=5:10"},
+		Webhooks:  WebhooksConfig{HistoryLimit: 1000, HistoryTTL: 24 * time.Hour},
+		Alerts:    AlertsConfig{EvalInterval: 30 * time.Second},
+		Clock:     ClockConfig{TimestampPolicy: string(domain.TimestampTrustDevice), SkewThreshold: 2 * time.Minute},
+		MQTT:      MQTTConfig{ClientID: "fleet-server", TopicPrefix: "devices"},
+		UDP:       UDPConfig{ReplayWindow: 2 * time.Minute},
+		Tracing:   TracingConfig{Exporter: "none", SampleRatio: 1},
+		Logging:   LoggingConfig{Level: "info", Format: "json", HeartbeatSample: 1},
+	}
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func envFrom(m map[string]string) func(string) string {
+	return func(k string) string { return m[k] }
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoad_DefaultsAreValid(t *testing.T) {
+	cfg, opts, err := Load(nil, envFrom(nil))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Server.Port != 8080 || cfg.Timeouts.Shutdown != 15*time.Second || cfg.Storage.Backend != "memory" {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+	if opts.File != "" || opts.PrintConfig {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "fleet.yaml", `
+server:
+  port: 8001
+  grpc_port: 9001
+alerts:
+  offline_after: 5m
+  eval_interval: 1m
+webhooks:
+  urls: [https://a.example/hook, https://b.example/hook]
+`)
+	env := envFrom(map[string]string{
+		"CONFIG_FILE":         path,
+		"GRPC_PORT":           "9002",
+		"ALERT_EVAL_INTERVAL": "2m",
+	})
+	cfg, opts, err := Load([]string{"--alerts.eval_interval=3m"}, env)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if opts.File != path {
+		t.Fatalf("expected config file from CONFIG_FILE, got %q", opts.File)
+	}
+	if cfg.Server.Port != 8001 {
+		t.Fatalf("file should override default port, got %d", cfg.Server.Port)
+	}
+	if cfg.Server.GRPCPort != 9002 {
+		t.Fatalf("env should override file grpc_port, got %d", cfg.Server.GRPCPort)
+	}
+	if cfg.Alerts.EvalInterval != 3*time.Minute {
+		t.Fatalf("flag should override env eval_interval, got %v", cfg.Alerts.EvalInterval)
+	}
+	if cfg.Alerts.OfflineAfter != 5*time.Minute {
+		t.Fatalf("expected offline_after from file, got %v", cfg.Alerts.OfflineAfter)
+	}
+	if len(cfg.Webhooks.URLs) != 2 || cfg.Webhooks.URLs[1] != "https://b.example/hook" {
+		t.Fatalf("unexpected webhook URLs: %v", cfg.Webhooks.URLs)
+	}
+}
+
+func TestLoad_TOMLFile(t *testing.T) {
+	path := writeFile(t, "fleet.toml", `
+[server]
+port = 8443
+
+[tls]
+cert_file = "cert.pem"
+key_file = "key.pem"
+
+[logging]
+level = "info,mqtt=warn"
+`)
+	cfg, _, err := Load([]string{"--config", path}, envFrom(nil))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Server.Port != 8443 || !cfg.TLS.Enabled() || cfg.Logging.Level != "info,mqtt=warn" {
+		t.Fatalf("unexpected config from TOML: %+v", cfg)
+	}
+}
+
+func TestLoad_RejectsUnknownKeysAndBadValues(t *testing.T) {
+	path := writeFile(t, "fleet.yaml", "server:\n  prot: 8080\n")
+	if _, _, err := Load([]string{"--config", path}, envFrom(nil)); err == nil || !strings.Contains(err.Error(), `unknown key "server.prot"`) {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+
+	if _, _, err := Load(nil, envFrom(map[string]string{"OFFLINE_AFTER": "soon"})); err == nil || !strings.Contains(err.Error(), "OFFLINE_AFTER") {
+		t.Fatalf("expected env parse error naming the variable, got %v", err)
+	}
+
+	if _, _, err := Load([]string{"--help"}, envFrom(nil)); !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("expected flag.ErrHelp, got %v", err)
+	}
+}
+
+func TestValidate_ReportsAllErrors(t *testing.T) {
+	cfg := Defaults()
+	cfg.Server.Port = 0
+	cfg.TLS.CertFile = "cert.pem"
+	cfg.Storage.Backend = "postgres"
+	cfg.Webhooks.URLs = []string{"ftp://example.com"}
+	cfg.Tracing.Exporter = "zipkin"
+	cfg.Logging.Level = "loud"
+	cfg.RateLimit.Stats = "device=fast"
+	cfg.Tenants.Quotas = "acme:many"
+	cfg.Clock.TimestampPolicy = "guess"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatalf("expected validation errors")
+	}
+	for _, key := range []string{"server.port", "tls", "storage.backend", "webhooks.urls", "tracing.exporter", "logging.level", "rate_limit.stats", "tenants.quotas", "clock.timestamp_policy"} {
+		if !strings.Contains(err.Error(), key+":") {
+			t.Errorf("expected an error for %s, got:\n%v", key, err)
+		}
+	}
+}
+
+func TestTenants_ParsesPairs(t *testing.T) {
+	cfg := TenantsConfig{
+		Tokens:     "acme:tok:1, globex:tok2",
+		DeviceCSVs: "acme:/etc/fleet/acme.csv",
+		Quotas:     "acme:10,globex:0",
+	}
+	tokens, err := cfg.TokenTenants()
+	if err != nil || tokens["tok:1"] != "acme" || tokens["tok2"] != "globex" {
+		t.Fatalf("TokenTenants: %v, %v", tokens, err)
+	}
+	if csvs, err := cfg.DeviceCSVPaths(); err != nil || csvs["acme"] != "/etc/fleet/acme.csv" {
+		t.Fatalf("DeviceCSVPaths: %v, %v", csvs, err)
+	}
+	if quotas, err := cfg.DeviceQuotas(); err != nil || quotas["acme"] != 10 || quotas["globex"] != 0 {
+		t.Fatalf("DeviceQuotas: %v, %v", quotas, err)
+	}
+
+	for _, bad := range []TenantsConfig{
+		{Tokens: "acme"},
+		{Tokens: "Acme:tok"},
+		{Tokens: "acme:tok,globex:tok"},
+	} {
+		if _, err := bad.TokenTenants(); err == nil {
+			t.Errorf("expected %q to be rejected", bad.Tokens)
+		}
+	}
+
+	full := Defaults()
+	full.Tenants.Tokens = "acme:tok"
+	full.MQTT.BrokerURL = "tcp://broker:1883"
+	full.UDP.Addr = ":9999"
+	err = full.Validate()
+	if err == nil || !strings.Contains(err.Error(), "mqtt.broker_url") || !strings.Contains(err.Error(), "udp.addr") {
+		t.Errorf("expected tenant tokens to be refused alongside MQTT and UDP, got %v", err)
+	}
+}
+
+func TestPrint_RedactsSecrets(t *testing.T) {
+	cfg := Defaults()
+	cfg.Auth.Token = "api-token"
+	cfg.Webhooks.Secret = "webhook-secret"
+	cfg.MQTT.Password = "mqtt-password"
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatalf("Print returned error: %v", err)
+	}
+	out := buf.String()
+	for _, secret := range []string{"api-token", "webhook-secret", "mqtt-password"} {
+		if strings.Contains(out, secret) {
+			t.Fatalf("secret %q leaked into printed config:\n%s", secret, out)
+		}
+	}
+	if strings.Count(out, redacted) != 3 {
+		t.Fatalf("expected 3 redacted values, got:\n%s", out)
+	}
+	if !strings.Contains(out, "udp:\n  addr: \"\"\n  hmac_secret: \"\"") {
+		t.Fatalf("empty secrets should stay empty, got:\n%s", out)
+	}
+	if cfg.Auth.Token != "api-token" {
+		t.Fatalf("Print must not modify the config")
+	}
+}
+
+func TestLoad_ExampleFileIsValid(t *testing.T) {
+	if _, _, err := Load([]string{"--config", "../../config.example.yaml"}, envFrom(nil)); err != nil {
+		t.Fatalf("config.example.yaml does not load: %v", err)
+	}
+}
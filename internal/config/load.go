@@ -0,0 +1,279 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Options are the command-line switches that are not configuration values.
+type Options struct {
+	// File is the config file that was read, if any.
+	File string
+	// PrintConfig asks the caller to print the effective config and exit.
+	PrintConfig bool
+}
+
+// redacted replaces non-empty secrets in Redacted output.
+const redacted = "REDACTED"
+
+// field is one leaf of Config, addressed by its dotted file key.
+type field struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	value  reflect.Value
+}
+
+// Load builds the effective configuration from defaults, the config file,
+// the environment (looked up through getenv) and args, validates it and
+// returns it with the non-config options. A flag.ErrHelp error means -h
+// was requested and usage has been written to stderr.
+func Load(args []string, getenv func(string) string) (*Config, Options, error) {
+	cfg := Defaults()
+	fields := fieldsOf(&cfg)
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	var opts Options
+	fs.StringVar(&opts.File, "config", getenv("CONFIG_FILE"), "YAML or TOML config file (env CONFIG_FILE)")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective configuration with secrets redacted and exit")
+	flagValues := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		flagValues[f.key] = fs.String(f.key, "", fmt.Sprintf("%s (env %s)", f.usage, f.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, opts, err
+	}
+
+	if opts.File != "" {
+		if err := loadFile(opts.File, fields); err != nil {
+			return nil, opts, err
+		}
+	}
+
+	for _, f := range fields {
+		if v := getenv(f.env); v != "" {
+			if err := setValue(f.value, v); err != nil {
+				return nil, opts, fmt.Errorf("env %s: %w", f.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	byKey := indexFields(fields)
+	fs.Visit(func(fl *flag.Flag) {
+		f, ok := byKey[fl.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		if err := setValue(f.value, *flagValues[fl.Name]); err != nil {
+			flagErr = fmt.Errorf("flag --%s: %w", fl.Name, err)
+		}
+	})
+	if flagErr != nil {
+		return nil, opts, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, opts, err
+	}
+	return &cfg, opts, nil
+}
+
+// Redacted returns a copy of c with every non-empty secret replaced.
+func (c Config) Redacted() Config {
+	out := c
+	out.Webhooks.URLs = append([]string(nil), c.Webhooks.URLs...)
+	for _, f := range fieldsOf(&out) {
+		if f.secret && f.value.String() != "" {
+			f.value.SetString(redacted)
+		}
+	}
+	return out
+}
+
+// Print writes the redacted configuration as YAML.
+func (c Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// loadFile applies a YAML (.yaml, .yml) or TOML (.toml) file. Unknown keys
+// are rejected so typos do not silently fall back to defaults.
+func loadFile(path string, fields []field) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	raw := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("config file %s: unsupported extension %q (want .yaml, .yml or .toml)", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	flat := map[string]any{}
+	flatten("", raw, flat)
+	byKey := indexFields(fields)
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, k := range keys {
+		f, ok := byKey[k]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown key %q", path, k))
+			continue
+		}
+		if err := setValue(f.value, fileValueString(flat[k])); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", path, k, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func flatten(prefix string, in map[string]any, out map[string]any) {
+	for k, v := range in {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]any); ok {
+			flatten(key, nested, out)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+// fileValueString renders a decoded file scalar (or list) in the same
+// textual form accepted from env vars and flags.
+func fileValueString(v any) string {
+	switch t := v.(type) {
+	case []any:
+		parts := make([]string, len(t))
+		for i, e := range t {
+			parts[i] = fmt.Sprint(e)
+		}
+		return strings.Join(parts, ",")
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func indexFields(fields []field) map[string]field {
+	m := make(map[string]field, len(fields))
+	for _, f := range fields {
+		m[f.key] = f
+	}
+	return m
+}
+
+// fieldsOf lists the leaves of cfg in declaration order.
+func fieldsOf(cfg *Config) []field {
+	var out []field
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			key := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			fv := v.Field(i)
+			if sf.Type.Kind() == reflect.Struct && sf.Type != reflect.TypeOf(time.Duration(0)) {
+				walk(key, fv)
+				continue
+			}
+			out = append(out, field{
+				key:    key,
+				env:    sf.Tag.Get("env"),
+				usage:  sf.Tag.Get("usage"),
+				secret: sf.Tag.Get("secret") == "true",
+				value:  fv,
+			})
+		}
+	}
+	walk("", reflect.ValueOf(cfg).Elem())
+	return out
+}
+
+// setValue parses raw into v according to v's type.
+func setValue(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
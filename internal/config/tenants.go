@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"safelyyou/internal/core/domain"
+	"strconv"
+	"strings"
+)
+
+// TokenTenants maps each tenant token to its tenant.
+func (t TenantsConfig) TokenTenants() (map[string]string, error) {
+	out := make(map[string]string)
+	err := eachTenantPair(t.Tokens, func(tenant, token string) error {
+		if _, dup := out[token]; dup {
+			return fmt.Errorf("token of tenant %s is already used", tenant)
+		}
+		out[token] = tenant
+		return nil
+	})
+	return out, err
+}
+
+// DeviceCSVPaths maps tenants to the CSV listing their devices.
+func (t TenantsConfig) DeviceCSVPaths() (map[string]string, error) {
+	out := make(map[string]string)
+	err := eachTenantPair(t.DeviceCSVs, func(tenant, path string) error {
+		out[tenant] = path
+		return nil
+	})
+	return out, err
+}
+
+// DeviceQuotas maps tenants to the number of devices they may own.
+func (t TenantsConfig) DeviceQuotas() (map[string]int, error) {
+	out := make(map[string]int)
+	err := eachTenantPair(t.Quotas, func(tenant, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("quota of tenant %s must be a non-negative integer, got %q", tenant, value)
+		}
+		out[tenant] = n
+		return nil
+	})
+	return out, err
+}
+
+// eachTenantPair calls fn for every "tenant:value" pair of a comma-separated
+// list. The value may itself contain ":".
+func eachTenantPair(s string, fn func(tenant, value string) error) error {
+	for part := range strings.SplitSeq(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tenant, value, ok := strings.Cut(part, ":")
+		tenant, value = strings.TrimSpace(tenant), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return fmt.Errorf("%q is not tenant:value", part)
+		}
+		if !domain.ValidTenant(tenant) {
+			return fmt.Errorf("invalid tenant %q", tenant)
+		}
+		if err := fn(tenant, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"safelyyou/internal/core/domain"
+	"safelyyou/pkg/logging"
+	"safelyyou/pkg/ratelimit"
+)
+
+// Validate reports every invalid setting at once, each prefixed with its
+// file key.
+func (c Config) Validate() error {
+	var errs []error
+	add := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	validPort := func(key string, port int) {
+		if port < 1 || port > 65535 {
+			add(key, "must be between 1 and 65535, got %d", port)
+		}
+	}
+	validPort("server.port", c.Server.Port)
+	validPort("server.grpc_port", c.Server.GRPCPort)
+	if c.Server.Port == c.Server.GRPCPort {
+		add("server.grpc_port", "must differ from server.port")
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		add("tls", "cert_file and key_file must be set together")
+	}
+
+	if c.Storage.Backend != "memory" {
+		add("storage.backend", "unknown backend %q (supported: memory)", c.Storage.Backend)
+	}
+	if c.Storage.DeviceCSV == "" {
+		add("storage.device_csv", "is required")
+	}
+
+	if c.Timeouts.ReadHeader <= 0 {
+		add("timeouts.read_header", "must be positive")
+	}
+	if c.Timeouts.Shutdown <= 0 {
+		add("timeouts.shutdown", "must be positive")
+	}
+	if c.Retention.Series <= 0 {
+		add("retention.series", "must be positive")
+	}
+
+	if _, err := c.Tenants.TokenTenants(); err != nil {
+		add("tenants.tokens", "%v", err)
+	}
+	// MQTT and UDP reports carry no tenant credentials, so they could only
+	// reach the default tenant's devices unauthenticated.
+	if c.Tenants.Tokens != "" && c.MQTT.BrokerURL != "" {
+		add("tenants.tokens", "cannot be combined with mqtt.broker_url, whose reports are not authenticated per tenant")
+	}
+	if c.Tenants.Tokens != "" && c.UDP.Addr != "" {
+		add("tenants.tokens", "cannot be combined with udp.addr, whose reports are not authenticated per tenant")
+	}
+	if _, err := c.Tenants.DeviceCSVPaths(); err != nil {
+		add("tenants.device_csvs", "%v", err)
+	}
+	if _, err := c.Tenants.DeviceQuotas(); err != nil {
+		add("tenants.quotas", "%v", err)
+	}
+
+	if _, err := ratelimit.ParseRule(c.RateLimit.Heartbeat); err != nil {
+		add("rate_limit.heartbeat", "%v", err)
+	}
+	if _, err := ratelimit.ParseRule(c.RateLimit.Stats); err != nil {
+		add("rate_limit.stats", "%v", err)
+	}
+
+	for _, u := range c.Webhooks.URLs {
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			add("webhooks.urls", "%q is not an http(s) URL", u)
+		}
+	}
+
+	if c.Webhooks.HistoryLimit < 0 {
+		add("webhooks.history_limit", "must not be negative")
+	}
+	if c.Webhooks.HistoryTTL < 0 {
+		add("webhooks.history_ttl", "must not be negative")
+	}
+
+	if c.Alerts.UploadThreshold < 0 {
+		add("alerts.upload_threshold", "must not be negative")
+	}
+	if c.Alerts.OfflineAfter < 0 {
+		add("alerts.offline_after", "must not be negative")
+	}
+	if c.Alerts.EvalInterval <= 0 {
+		add("alerts.eval_interval", "must be positive")
+	}
+
+	if !domain.ValidTimestampPolicy(domain.TimestampPolicy(c.Clock.TimestampPolicy)) {
+		add("clock.timestamp_policy", "unknown policy %q (supported: trust_device, trust_server, correct, reject)", c.Clock.TimestampPolicy)
+	}
+	if c.Clock.SkewThreshold < 0 {
+		add("clock.skew_threshold", "must not be negative")
+	}
+	if c.Clock.TimestampPolicy == string(domain.TimestampReject) && c.Clock.SkewThreshold == 0 {
+		add("clock.skew_threshold", "must be positive with the reject policy")
+	}
+
+	if c.UDP.ReplayWindow <= 0 {
+		add("udp.replay_window", "must be positive")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		add("tracing.exporter", "unknown exporter %q (supported: none, stdout, otlp)", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio <= 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio", "must be in (0, 1], got %g", c.Tracing.SampleRatio)
+	}
+
+	if _, _, err := logging.ParseLevels(c.Logging.Level); err != nil {
+		add("logging.level", "%v", err)
+	}
+	if c.Logging.Format != "json" && c.Logging.Format != "text" {
+		add("logging.format", "must be json or text, got %q", c.Logging.Format)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,200 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AlertMetric is a per-device value an alert rule compares against.
+type AlertMetric string
+
+const (
+	MetricUptime         AlertMetric = "uptime"
+	MetricUptime24h      AlertMetric = "uptime_24h"
+	MetricAvgUpload      AlertMetric = "avg_upload"
+	MetricP95Upload      AlertMetric = "p95_upload"
+	MetricNoHeartbeatFor AlertMetric = "no_heartbeat_for"
+)
+
+// IsDuration reports whether the metric is a duration, expressed in seconds.
+// Other metrics are percentages.
+func (m AlertMetric) IsDuration() bool {
+	switch m {
+	case MetricAvgUpload, MetricP95Upload, MetricNoHeartbeatFor:
+		return true
+	}
+	return false
+}
+
+func validMetric(m AlertMetric) bool {
+	switch m {
+	case MetricUptime, MetricUptime24h, MetricAvgUpload, MetricP95Upload, MetricNoHeartbeatFor:
+		return true
+	}
+	return false
+}
+
+// AlertScope selects the devices a rule applies to.
+type AlertScope string
+
+const (
+	ScopeFleet  AlertScope = "fleet"
+	ScopeSite   AlertScope = "site"
+	ScopeDevice AlertScope = "device"
+)
+
+// AlertRule is a threshold condition evaluated periodically per device.
+type AlertRule struct {
+	ID   string
+	Name string
+	// Expr is the condition as written, e.g. "p95_upload > 5m".
+	Expr      string
+	Metric    AlertMetric
+	Op        string
+	Threshold float64
+	Scope     AlertScope
+	// Tenant limits the rule to one tenant's devices; empty means every
+	// tenant. Site names and device IDs are unique within a tenant only,
+	// so site and device scoped rules always have one.
+	Tenant string
+	// Target is the site or device ID for site/device scoped rules.
+	Target string
+	// For is how long the condition must hold before the alert fires.
+	For       time.Duration
+	Severity  string
+	CreatedAt time.Time
+}
+
+// Matches reports whether value satisfies the rule's condition.
+func (r *AlertRule) Matches(value float64) bool {
+	switch r.Op {
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	}
+	return false
+}
+
+// AppliesTo reports whether the rule covers a device of tenant in the
+// given site.
+func (r *AlertRule) AppliesTo(tenant, deviceID, site string) bool {
+	if r.Tenant != "" && r.Tenant != tenant {
+		return false
+	}
+	switch r.Scope {
+	case ScopeDevice:
+		return r.Target == deviceID
+	case ScopeSite:
+		return r.Target == site
+	}
+	return true
+}
+
+// ParseAlertExpr parses "<metric> <op> <value>". Percent metrics take a
+// plain number; duration metrics take a Go duration ("5m") or seconds.
+func ParseAlertExpr(expr string) (AlertMetric, string, float64, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 3 {
+		return "", "", 0, fmt.Errorf("expression %q must be '<metric> <op> <value>'", expr)
+	}
+
+	metric := AlertMetric(fields[0])
+	if !validMetric(metric) {
+		return "", "", 0, fmt.Errorf("unknown metric %q", fields[0])
+	}
+
+	op := fields[1]
+	switch op {
+	case "<", "<=", ">", ">=":
+	default:
+		return "", "", 0, fmt.Errorf("unknown operator %q", op)
+	}
+
+	if metric.IsDuration() {
+		if d, err := time.ParseDuration(fields[2]); err == nil {
+			return metric, op, d.Seconds(), nil
+		}
+	}
+	v, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("invalid value %q", fields[2])
+	}
+	return metric, op, v, nil
+}
+
+// AlertState is the lifecycle state of an alert instance.
+type AlertState string
+
+const (
+	AlertPending  AlertState = "pending"
+	AlertFiring   AlertState = "firing"
+	AlertResolved AlertState = "resolved"
+)
+
+// Alert is the state of one rule for one device. There is at most one
+// alert per (rule, device) pair; it cycles pending → firing → resolved and
+// starts over when the condition matches again.
+type Alert struct {
+	ID       string
+	RuleID   string
+	RuleName string
+	// Tenant owns the device; DeviceID is unique within it only.
+	Tenant   string
+	DeviceID string
+	Severity string
+	State    AlertState
+	Value    float64
+	// StartsAt is when the condition started matching in this cycle.
+	StartsAt        time.Time
+	FiredAt         time.Time
+	ResolvedAt      time.Time
+	LastEvaluatedAt time.Time
+	Silenced        bool
+}
+
+// AlertID is the deduplication key of the alert for a rule and a device
+// of tenant. Default tenant alerts keep the "rule/device" form.
+func AlertID(tenant, ruleID, deviceID string) string {
+	if tenant == DefaultTenant {
+		return ruleID + "/" + deviceID
+	}
+	return tenant + "/" + ruleID + "/" + deviceID
+}
+
+// Silence mutes notifications for matching alerts during a time range.
+// Empty Tenant, RuleID or DeviceID match any tenant, rule or device.
+type Silence struct {
+	ID     string
+	Tenant string
+	RuleID string
+	// DeviceID is unique within Tenant only.
+	DeviceID  string
+	StartsAt  time.Time
+	EndsAt    time.Time
+	Comment   string
+	CreatedAt time.Time
+}
+
+// Mutes reports whether the silence applies to the alert at now.
+func (s *Silence) Mutes(a *Alert, now time.Time) bool {
+	if now.Before(s.StartsAt) || !now.Before(s.EndsAt) {
+		return false
+	}
+	if s.Tenant != "" && s.Tenant != a.Tenant {
+		return false
+	}
+	if s.RuleID != "" && s.RuleID != a.RuleID {
+		return false
+	}
+	if s.DeviceID != "" && s.DeviceID != a.DeviceID {
+		return false
+	}
+	return true
+}
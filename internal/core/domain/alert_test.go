@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAlertExpr_PercentAndDurationMetrics(t *testing.T) {
+	cases := []struct {
+		expr      string
+		metric    AlertMetric
+		op        string
+		threshold float64
+	}{
+		{"uptime_24h < 95", MetricUptime24h, "<", 95},
+		{"p95_upload > 5m", MetricP95Upload, ">", 300},
+		{"no_heartbeat_for >= 10m", MetricNoHeartbeatFor, ">=", 600},
+		{"avg_upload > 90", MetricAvgUpload, ">", 90},
+	}
+	for _, tc := range cases {
+		metric, op, threshold, err := ParseAlertExpr(tc.expr)
+		if err != nil {
+			t.Fatalf("ParseAlertExpr(%q) returned error: %v", tc.expr, err)
+		}
+		if metric != tc.metric || op != tc.op || threshold != tc.threshold {
+			t.Fatalf("ParseAlertExpr(%q) = %s %s %v, want %s %s %v",
+				tc.expr, metric, op, threshold, tc.metric, tc.op, tc.threshold)
+		}
+	}
+}
+
+func TestParseAlertExpr_RejectsMalformed(t *testing.T) {
+	for _, expr := range []string{"", "uptime < ", "cpu > 5", "uptime == 5", "uptime < lots"} {
+		if _, _, _, err := ParseAlertExpr(expr); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+}
+
+func TestSilenceMutes_RespectsWindowAndMatchers(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	s := Silence{RuleID: "r1", StartsAt: t0, EndsAt: t0.Add(time.Hour)}
+
+	a := &Alert{RuleID: "r1", DeviceID: "d1"}
+	if !s.Mutes(a, t0.Add(time.Minute)) {
+		t.Fatalf("expected silence to mute matching alert inside window")
+	}
+	if s.Mutes(a, t0.Add(time.Hour)) {
+		t.Fatalf("expected silence to have ended at EndsAt")
+	}
+	if s.Mutes(&Alert{RuleID: "r2", DeviceID: "d1"}, t0.Add(time.Minute)) {
+		t.Fatalf("expected silence not to mute another rule")
+	}
+}
+
+func TestSilenceMutes_MatchesTenant(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	s := Silence{Tenant: DefaultTenant, DeviceID: "d1", StartsAt: t0, EndsAt: t0.Add(time.Hour)}
+
+	if !s.Mutes(&Alert{Tenant: DefaultTenant, RuleID: "r1", DeviceID: "d1"}, t0) {
+		t.Fatalf("expected silence to mute the tenant's device")
+	}
+	if s.Mutes(&Alert{Tenant: "acme", RuleID: "r1", DeviceID: "d1"}, t0) {
+		t.Fatalf("expected silence not to mute another tenant's device with the same ID")
+	}
+}
+
+func TestAlertRuleAppliesTo_MatchesTenant(t *testing.T) {
+	device := AlertRule{Scope: ScopeDevice, Tenant: DefaultTenant, Target: "dev-1"}
+	if !device.AppliesTo(DefaultTenant, "dev-1", "") || device.AppliesTo("acme", "dev-1", "") {
+		t.Error("expected a device rule to cover its tenant's device only")
+	}
+	site := AlertRule{Scope: ScopeSite, Tenant: "acme", Target: "north"}
+	if !site.AppliesTo("acme", "dev-2", "north") || site.AppliesTo(DefaultTenant, "dev-2", "north") {
+		t.Error("expected a site rule to cover its tenant's site only")
+	}
+	fleet := AlertRule{Scope: ScopeFleet}
+	if !fleet.AppliesTo("acme", "dev-1", "") || !fleet.AppliesTo(DefaultTenant, "dev-1", "") {
+		t.Error("expected a fleet rule without tenant to cover every tenant")
+	}
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	// ClockSampleSize caps the number of recent clock offset samples kept
+	// per device.
+	ClockSampleSize = 16
+	// ClockMinSamples is how many samples the offset estimate needs before
+	// it is reported or acted upon.
+	ClockMinSamples = 3
+)
+
+// TimestampPolicy decides which time a device report is recorded at.
+type TimestampPolicy string
+
+const (
+	// TimestampTrustDevice records reports at the device's sent_at.
+	TimestampTrustDevice TimestampPolicy = "trust_device"
+	// TimestampTrustServer records reports at the time the server received
+	// them.
+	TimestampTrustServer TimestampPolicy = "trust_server"
+	// TimestampCorrect records reports at sent_at minus the estimated
+	// device clock offset.
+	TimestampCorrect TimestampPolicy = "correct"
+	// TimestampReject refuses reports from devices whose estimated clock
+	// offset exceeds the skew threshold.
+	TimestampReject TimestampPolicy = "reject"
+)
+
+// ValidTimestampPolicy reports whether p is one of the known policies.
+func ValidTimestampPolicy(p TimestampPolicy) bool {
+	switch p {
+	case TimestampTrustDevice, TimestampTrustServer, TimestampCorrect, TimestampReject:
+		return true
+	}
+	return false
+}
+
+// AddClockSample records the offset between a report's sent_at and the
+// server time it was received at. Reports without a sent_at are ignored.
+func (d *DeviceStats) AddClockSample(sentAt, receivedAt time.Time) {
+	if sentAt.IsZero() {
+		return
+	}
+	d.ClockSamples = append(d.ClockSamples, sentAt.Sub(receivedAt))
+	if over := len(d.ClockSamples) - ClockSampleSize; over > 0 {
+		d.ClockSamples = append(d.ClockSamples[:0], d.ClockSamples[over:]...)
+	}
+}
+
+// ClockOffset estimates how far the device clock is ahead of the server
+// clock, negative when it is behind. Every sample is the true offset minus
+// the delivery delay, so the largest recent sample is the closest: reports
+// delayed by the network or buffered on the device only lower samples.
+// ok is false until ClockMinSamples samples were recorded.
+func (d *DeviceStats) ClockOffset() (offset time.Duration, ok bool) {
+	if len(d.ClockSamples) < ClockMinSamples {
+		return 0, false
+	}
+	return slices.Max(d.ClockSamples), true
+}
@@ -1,20 +1,103 @@
 package domain
 
-import "time"
+import (
+	"maps"
+	"math"
+	"sort"
+	"time"
+)
+
+// SeriesRetention is how much per-minute history is kept per device,
+// measured back from the device's most recent bucket. It may be changed at
+// startup, before any samples are recorded.
+var SeriesRetention = 24 * time.Hour
+
+// MaxSampleAhead is how far past server time a sample may be bucketed.
+// Later samples, from a device clock running ahead, are bucketed at server
+// time instead, so that one far-future timestamp cannot become the most
+// recent bucket and prune the whole series.
+const MaxSampleAhead = 5 * time.Minute
+
+const (
+	// UploadSampleSize caps the number of recent upload durations kept for
+	// percentile computation.
+	UploadSampleSize = 1000
+)
+
+// MinuteBucket aggregates device activity for one minute of device time.
+type MinuteBucket struct {
+	Minute      time.Time
+	Heartbeats  int64
+	Uploads     int64
+	UploadSumNs int64
+}
+
+// RateLimit overrides the per-device ingestion rate limit: PerSecond
+// tokens refilled per second, up to Burst. The zero value keeps the
+// route's default.
+type RateLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// IsSet reports whether the override is present.
+func (r RateLimit) IsSet() bool { return r.PerSecond > 0 }
 
 // DeviceStats holds aggregated data per device.
 type DeviceStats struct {
+	// ID is unique within the Tenant owning the device only.
 	ID             string
+	Tenant         string
+	Site           string
 	FirstHeartbeat time.Time
 	LastHeartbeat  time.Time
 	HeartbeatCount int64
 	UploadCount    int64
 	UploadSumMs    int64
+
+	// Labels are key/value tags such as floor or wing, used to select
+	// devices into groups. The site is kept in Site, not here.
+	Labels map[string]string
+
+	// RateLimit is device metadata loaded with the device list.
+	RateLimit RateLimit
+
+	// LastSeenAt is the server time the last heartbeat was received,
+	// used for offline detection independently of device clocks.
+	LastSeenAt time.Time
+	// LastSentAt is the sent_at of the heartbeat received at LastSeenAt, as
+	// reported by the device whatever the timestamp policy.
+	LastSentAt time.Time
+	// Offline is set by the offline monitor and cleared by the next heartbeat.
+	Offline bool
+
+	// Version increases with every change and UpdatedAt is the server time
+	// of the latest one; both are maintained by the repository.
+	Version   uint64
+	UpdatedAt time.Time
+
+	// Buckets is the recent per-minute activity, oldest first.
+	Buckets []MinuteBucket
+	// UploadSamples holds the most recent upload durations in ns.
+	UploadSamples []int64
+	// ClockSamples holds the most recent sent_at minus receive time offsets.
+	ClockSamples []time.Duration
 }
 
-// NewDeviceStats creates a new stats struct for a device.
+// NewDeviceStats creates a new stats struct for a device of the default
+// tenant.
 func NewDeviceStats(id string) *DeviceStats {
-	return &DeviceStats{ID: id}
+	return &DeviceStats{ID: id, Tenant: DefaultTenant}
+}
+
+// Clone returns a deep copy, safe to read without holding repository locks.
+func (d *DeviceStats) Clone() *DeviceStats {
+	c := *d
+	c.Labels = maps.Clone(d.Labels)
+	c.Buckets = append([]MinuteBucket(nil), d.Buckets...)
+	c.UploadSamples = append([]int64(nil), d.UploadSamples...)
+	c.ClockSamples = append([]time.Duration(nil), d.ClockSamples...)
+	return &c
 }
 
 func (d *DeviceStats) UptimePercent() float64 {
@@ -34,6 +117,37 @@ func (d *DeviceStats) UptimePercent() float64 {
 	return (sumHeartbeats / minutes) * 100.0
 }
 
+// UptimePercentWithin computes uptime like UptimePercent, but only over the
+// trailing window ending at the last heartbeat. The window is limited by
+// SeriesRetention and by the first heartbeat.
+func (d *DeviceStats) UptimePercentWithin(window time.Duration) float64 {
+	if d.HeartbeatCount == 0 {
+		return 0
+	}
+	if window > SeriesRetention {
+		window = SeriesRetention
+	}
+
+	start := d.LastHeartbeat.Add(-window)
+	if start.Before(d.FirstHeartbeat) {
+		start = d.FirstHeartbeat
+	}
+	from := start.Truncate(time.Minute)
+
+	var sumHeartbeats int64
+	for _, b := range d.Buckets {
+		if !b.Minute.Before(from) {
+			sumHeartbeats += b.Heartbeats
+		}
+	}
+
+	minutes := d.LastHeartbeat.Sub(start).Minutes()
+	if minutes <= 0 {
+		minutes = 1
+	}
+	return (float64(sumHeartbeats) / minutes) * 100.0
+}
+
 func (d *DeviceStats) AvgUploadDuration() time.Duration {
 	if d.UploadCount == 0 {
 		return 0
@@ -45,3 +159,126 @@ func (d *DeviceStats) AvgUploadDuration() time.Duration {
 	}
 	return time.Duration(avgNs)
 }
+
+// UploadPercentile returns the p-th percentile (0-100, nearest rank) of the
+// recent upload durations, or 0 when there are none.
+func (d *DeviceStats) UploadPercentile(p float64) time.Duration {
+	return Percentile(d.UploadSamples, p)
+}
+
+// Outage is a run of minutes without a heartbeat, in device time. End is
+// the minute heartbeats resumed; it is zero while Ongoing.
+type Outage struct {
+	Start   time.Time
+	End     time.Time
+	Ongoing bool
+}
+
+// Outages returns the outages since the given time, oldest first: gaps
+// between heartbeat minutes in the series, plus an ongoing outage while the
+// device is flagged offline. History is limited by SeriesRetention.
+func (d *DeviceStats) Outages(since time.Time) []Outage {
+	since = since.UTC().Truncate(time.Minute)
+	var out []Outage
+	var prev time.Time
+	for _, b := range d.Buckets {
+		if b.Heartbeats == 0 {
+			continue
+		}
+		if !prev.IsZero() && b.Minute.Sub(prev) > time.Minute && b.Minute.After(since) {
+			start := prev.Add(time.Minute)
+			if start.Before(since) {
+				start = since
+			}
+			out = append(out, Outage{Start: start, End: b.Minute})
+		}
+		prev = b.Minute
+	}
+	if d.Offline && !prev.IsZero() {
+		start := prev.Add(time.Minute)
+		if start.Before(since) {
+			start = since
+		}
+		out = append(out, Outage{Start: start, Ongoing: true})
+	}
+	return out
+}
+
+// AddHeartbeatSample records a heartbeat sent at t, received at server
+// time now, in the minute series.
+func (d *DeviceStats) AddHeartbeatSample(t, now time.Time) {
+	d.bucket(seriesTime(t, now)).Heartbeats++
+	d.pruneBuckets()
+}
+
+// AddUploadSample records an upload of ns nanoseconds received at server
+// time now. The upload is added to the minute series only when sentAt is
+// known.
+func (d *DeviceStats) AddUploadSample(sentAt, now time.Time, ns int64) {
+	d.UploadSamples = append(d.UploadSamples, ns)
+	if over := len(d.UploadSamples) - UploadSampleSize; over > 0 {
+		d.UploadSamples = append(d.UploadSamples[:0], d.UploadSamples[over:]...)
+	}
+	if sentAt.IsZero() {
+		return
+	}
+	b := d.bucket(seriesTime(sentAt, now))
+	b.Uploads++
+	b.UploadSumNs += ns
+	d.pruneBuckets()
+}
+
+// seriesTime returns the time a sample at t is bucketed at, given the
+// server time now (see MaxSampleAhead).
+func seriesTime(t, now time.Time) time.Time {
+	if t.After(now.Add(MaxSampleAhead)) {
+		return now
+	}
+	return t
+}
+
+// bucket returns the bucket for t's minute, inserting it in order if needed.
+func (d *DeviceStats) bucket(t time.Time) *MinuteBucket {
+	minute := t.UTC().Truncate(time.Minute)
+	i := sort.Search(len(d.Buckets), func(i int) bool {
+		return !d.Buckets[i].Minute.Before(minute)
+	})
+	if i < len(d.Buckets) && d.Buckets[i].Minute.Equal(minute) {
+		return &d.Buckets[i]
+	}
+	d.Buckets = append(d.Buckets, MinuteBucket{})
+	copy(d.Buckets[i+1:], d.Buckets[i:])
+	d.Buckets[i] = MinuteBucket{Minute: minute}
+	return &d.Buckets[i]
+}
+
+func (d *DeviceStats) pruneBuckets() {
+	if len(d.Buckets) == 0 {
+		return
+	}
+	cutoff := d.Buckets[len(d.Buckets)-1].Minute.Add(-SeriesRetention)
+	i := sort.Search(len(d.Buckets), func(i int) bool {
+		return !d.Buckets[i].Minute.Before(cutoff)
+	})
+	if i > 0 {
+		d.Buckets = append(d.Buckets[:0], d.Buckets[i:]...)
+	}
+}
+
+// Percentile returns the p-th percentile (nearest rank) of samples in ns.
+func Percentile(samples []int64, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return time.Duration(sorted[rank-1])
+}
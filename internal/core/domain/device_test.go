@@ -95,3 +95,139 @@ func TestAvgUploadDuration_NegativeAverageClampedToZero(t *testing.T) {
 		t.Fatalf("expected negative average to clamp to 0, got %v", result)
 	}
 }
+
+func TestUptimePercentWithin_OnlyCountsTrailingWindow(t *testing.T) {
+	// One heartbeat per minute for 48h, then a 12h gap with no heartbeats
+	// inside the last 24h except the final one.
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDeviceStats("device-1")
+	record := func(ts time.Time) {
+		if d.HeartbeatCount == 0 {
+			d.FirstHeartbeat = ts
+		}
+		d.LastHeartbeat = ts
+		d.HeartbeatCount++
+		d.AddHeartbeatSample(ts, ts)
+	}
+	for i := 0; i < 36*60; i++ {
+		record(start.Add(time.Duration(i) * time.Minute))
+	}
+	record(start.Add(48 * time.Hour))
+
+	// Last 24h: minutes 24h..36h have heartbeats (720 + the final one).
+	got := d.UptimePercentWithin(24 * time.Hour)
+	want := float64(12*60+1) / (24 * 60) * 100
+	if math.Abs(got-want) > 0.0001 {
+		t.Fatalf("expected uptime_24h ≈ %f, got %f", want, got)
+	}
+
+	if len(d.Buckets) > 24*60+1 {
+		t.Fatalf("expected buckets pruned to retention, got %d", len(d.Buckets))
+	}
+}
+
+func TestAddHeartbeatSample_FarFutureDoesNotPruneHistory(t *testing.T) {
+	now := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	d := NewDeviceStats("device-1")
+	for i := 60; i > 0; i-- {
+		at := now.Add(-time.Duration(i) * time.Minute)
+		d.AddHeartbeatSample(at, at)
+	}
+
+	d.AddHeartbeatSample(now.AddDate(1, 0, 0), now)
+	d.AddHeartbeatSample(now.Add(2*time.Minute), now)
+
+	if len(d.Buckets) != 62 {
+		t.Fatalf("expected history kept plus 2 buckets, got %d", len(d.Buckets))
+	}
+	if last := d.Buckets[len(d.Buckets)-1].Minute; !last.Equal(now.Add(2 * time.Minute)) {
+		t.Fatalf("expected sample within MaxSampleAhead to keep its minute, got %v", last)
+	}
+	if b := d.Buckets[len(d.Buckets)-2]; !b.Minute.Equal(now) || b.Heartbeats != 1 {
+		t.Fatalf("expected far-future sample bucketed at server time, got %+v", b)
+	}
+}
+
+func TestUploadPercentile_NearestRank(t *testing.T) {
+	d := NewDeviceStats("device-1")
+	for i := 1; i <= 100; i++ {
+		d.AddUploadSample(time.Time{}, time.Time{}, int64(i)*int64(time.Second))
+	}
+
+	if got := d.UploadPercentile(95); got != 95*time.Second {
+		t.Fatalf("expected p95 = 95s, got %v", got)
+	}
+	if got := d.UploadPercentile(100); got != 100*time.Second {
+		t.Fatalf("expected p100 = 100s, got %v", got)
+	}
+	if got := NewDeviceStats("empty").UploadPercentile(95); got != 0 {
+		t.Fatalf("expected p95 of no samples = 0, got %v", got)
+	}
+}
+
+func TestClone_DoesNotShareSlices(t *testing.T) {
+	d := NewDeviceStats("device-1")
+	d.AddUploadSample(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 10)
+
+	c := d.Clone()
+	c.UploadSamples[0] = 99
+	c.Buckets[0].Uploads = 99
+
+	if d.UploadSamples[0] != 10 || d.Buckets[0].Uploads != 1 {
+		t.Fatalf("clone mutation leaked into original: %+v", d)
+	}
+}
+
+func TestOutages_GapsSinceAndOngoing(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDeviceStats("device-1")
+	at := func(m int) time.Time { return start.Add(time.Duration(m) * time.Minute) }
+	// Heartbeats at minutes 0-9, 15-19 and 30; an upload alone at minute 25.
+	for _, m := range []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 15, 16, 17, 18, 19, 30} {
+		d.AddHeartbeatSample(at(m), at(m))
+	}
+	d.AddUploadSample(at(25), at(25), int64(time.Second))
+
+	got := d.Outages(start)
+	if len(got) != 2 || !got[0].Start.Equal(at(10)) || !got[0].End.Equal(at(15)) ||
+		!got[1].Start.Equal(at(20)) || !got[1].End.Equal(at(30)) {
+		t.Fatalf("unexpected outages %+v", got)
+	}
+
+	// since clamps an outage that started before it.
+	got = d.Outages(at(25).Add(30 * time.Second))
+	if len(got) != 1 || !got[0].Start.Equal(at(25)) || !got[0].End.Equal(at(30)) {
+		t.Fatalf("expected the second outage clamped to 00:25, got %+v", got)
+	}
+
+	d.Offline = true
+	got = d.Outages(at(31))
+	if len(got) != 1 || !got[0].Ongoing || !got[0].Start.Equal(at(31)) || !got[0].End.IsZero() {
+		t.Fatalf("expected only an ongoing outage, got %+v", got)
+	}
+}
+
+func TestClockOffset_TakesTheLeastDelayedSample(t *testing.T) {
+	server := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDeviceStats("device-1")
+	// The device clock is 5m ahead; reports take 1s, 30s and 2s to arrive.
+	for i, delay := range []time.Duration{time.Second, 30 * time.Second} {
+		received := server.Add(time.Duration(i) * time.Minute)
+		d.AddClockSample(received.Add(5*time.Minute-delay), received)
+	}
+	if _, ok := d.ClockOffset(); ok {
+		t.Fatalf("expected no estimate before %d samples", ClockMinSamples)
+	}
+
+	d.AddClockSample(server.Add(7*time.Minute-2*time.Second), server.Add(2*time.Minute))
+	if offset, ok := d.ClockOffset(); !ok || offset != 5*time.Minute-time.Second {
+		t.Fatalf("expected an offset of 4m59s, got %v, %v", offset, ok)
+	}
+
+	for range ClockSampleSize {
+		d.AddClockSample(server, server)
+	}
+	if offset, _ := d.ClockOffset(); offset != 0 || len(d.ClockSamples) != ClockSampleSize {
+		t.Fatalf("expected old samples dropped, got %v over %d samples", offset, len(d.ClockSamples))
+	}
+}
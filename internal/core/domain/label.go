@@ -0,0 +1,197 @@
+package domain
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LabelSite is the label holding a device's site. It is stored in
+// DeviceStats.Site rather than in Labels.
+const LabelSite = "site"
+
+var (
+	labelKeyPattern   = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]{0,62})$`)
+	labelValuePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{0,63}$`)
+	groupNamePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+)
+
+// ValidateLabel checks a label key and value: keys are lowercase
+// alphanumerics with ".", "_" or "-"; values may also be uppercase or
+// empty. Both are at most 63 characters.
+func ValidateLabel(key, value string) error {
+	if !labelKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid label key %q", key)
+	}
+	if !labelValuePattern.MatchString(value) {
+		return fmt.Errorf("invalid value %q for label %s", value, key)
+	}
+	return nil
+}
+
+// ParseLabels reads "key=value" pairs separated by ";" or ",", as used in
+// the labels column of the device CSV.
+func ParseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == ',' }) {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok {
+			return nil, fmt.Errorf("label %q is not key=value", part)
+		}
+		if err := ValidateLabel(key, value); err != nil {
+			return nil, err
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// Label returns the value of a device label; "site" reads Site.
+func (d *DeviceStats) Label(key string) (string, bool) {
+	if key == LabelSite {
+		return d.Site, d.Site != ""
+	}
+	v, ok := d.Labels[key]
+	return v, ok
+}
+
+// AllLabels returns the device labels including site, when set.
+func (d *DeviceStats) AllLabels() map[string]string {
+	out := maps.Clone(d.Labels)
+	if out == nil {
+		out = make(map[string]string, 1)
+	}
+	if d.Site != "" {
+		out[LabelSite] = d.Site
+	}
+	return out
+}
+
+// SetLabels replaces the device labels. A "site" label sets Site, and its
+// absence clears it.
+func (d *DeviceStats) SetLabels(labels map[string]string) {
+	d.Site = labels[LabelSite]
+	d.Labels = nil
+	for k, v := range labels {
+		if k == LabelSite {
+			continue
+		}
+		if d.Labels == nil {
+			d.Labels = make(map[string]string, len(labels))
+		}
+		d.Labels[k] = v
+	}
+}
+
+// SelectorOp is how a requirement tests a label.
+type SelectorOp string
+
+const (
+	SelectorEquals    SelectorOp = "="
+	SelectorNotEquals SelectorOp = "!="
+	SelectorExists    SelectorOp = "exists"
+	SelectorNotExists SelectorOp = "!exists"
+)
+
+// Requirement is one term of a selector.
+type Requirement struct {
+	Key   string
+	Op    SelectorOp
+	Value string
+}
+
+// Matches reports whether the label value, present or not, satisfies r.
+func (r Requirement) Matches(value string, ok bool) bool {
+	switch r.Op {
+	case SelectorEquals:
+		return ok && value == r.Value
+	case SelectorNotEquals:
+		return !ok || value != r.Value
+	case SelectorExists:
+		return ok
+	case SelectorNotExists:
+		return !ok
+	}
+	return false
+}
+
+// Selector picks devices by label; all requirements must hold. The empty
+// selector matches every device.
+type Selector []Requirement
+
+// ParseSelector reads comma-separated requirements: "key=value",
+// "key!=value", "key" (label present) and "!key" (label absent), e.g.
+// "site=north,floor=2,!decommissioned".
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var r Requirement
+		switch {
+		case strings.Contains(part, "!="):
+			key, value, _ := strings.Cut(part, "!=")
+			r = Requirement{Key: strings.TrimSpace(key), Op: SelectorNotEquals, Value: strings.TrimSpace(value)}
+		case strings.Contains(part, "="):
+			key, value, _ := strings.Cut(part, "=")
+			r = Requirement{Key: strings.TrimSpace(key), Op: SelectorEquals, Value: strings.TrimSpace(value)}
+		case strings.HasPrefix(part, "!"):
+			r = Requirement{Key: strings.TrimSpace(part[1:]), Op: SelectorNotExists}
+		default:
+			r = Requirement{Key: part, Op: SelectorExists}
+		}
+		if err := ValidateLabel(r.Key, r.Value); err != nil {
+			return nil, err
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// Matches reports whether the device satisfies every requirement.
+func (s Selector) Matches(d *DeviceStats) bool {
+	for _, r := range s {
+		if !r.Matches(d.Label(r.Key)) {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the selector in the syntax ParseSelector reads, with
+// requirements sorted.
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		switch r.Op {
+		case SelectorExists:
+			parts[i] = r.Key
+		case SelectorNotExists:
+			parts[i] = "!" + r.Key
+		default:
+			parts[i] = r.Key + string(r.Op) + r.Value
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// Group is a named label selector over the devices of its tenant.
+type Group struct {
+	Name      string
+	Tenant    string
+	Selector  Selector
+	CreatedAt time.Time
+}
+
+// ValidGroupName reports whether name can name a group: lowercase
+// alphanumerics, "_" and "-", at most 63 characters.
+func ValidGroupName(name string) bool {
+	return groupNamePattern.MatchString(name)
+}
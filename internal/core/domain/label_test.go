@@ -0,0 +1,53 @@
+package domain
+
+import "testing"
+
+func TestParseSelector_MatchesLabelsAndSite(t *testing.T) {
+	sel, err := ParseSelector(" site=north, floor!=3 ,wing,!retired")
+	if err != nil {
+		t.Fatalf("ParseSelector: %v", err)
+	}
+	if got := sel.String(); got != "!retired,floor!=3,site=north,wing" {
+		t.Errorf("unexpected String() %q", got)
+	}
+
+	d := NewDeviceStats("dev-1")
+	d.SetLabels(map[string]string{"site": "north", "floor": "2", "wing": "b"})
+	if d.Site != "north" || len(d.Labels) != 2 {
+		t.Fatalf("expected site split from labels, got %q %v", d.Site, d.Labels)
+	}
+	if !sel.Matches(d) {
+		t.Error("expected the device to match")
+	}
+
+	for _, labels := range []map[string]string{
+		{"site": "south", "wing": "b"},
+		{"site": "north", "floor": "3", "wing": "b"},
+		{"site": "north"},
+		{"site": "north", "wing": "b", "retired": ""},
+	} {
+		d.SetLabels(labels)
+		if sel.Matches(d) {
+			t.Errorf("expected %v not to match", labels)
+		}
+	}
+
+	if sel, _ := ParseSelector(""); !sel.Matches(d) {
+		t.Error("expected the empty selector to match every device")
+	}
+	for _, bad := range []string{"Site=north", "floor=two floors", "=x", "!"} {
+		if _, err := ParseSelector(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels("floor=2; wing=b")
+	if err != nil || labels["floor"] != "2" || labels["wing"] != "b" {
+		t.Fatalf("unexpected labels %v (%v)", labels, err)
+	}
+	if _, err := ParseLabels("floor"); err == nil {
+		t.Error("expected a label without value to be rejected")
+	}
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "regexp"
+
+// DefaultTenant owns the devices of the main device CSV and every request
+// made with the plain API token, or without authentication when no token
+// is configured. MQTT and UDP reports always go to the default tenant,
+// which is why they cannot be enabled alongside tenant tokens.
+const DefaultTenant = "default"
+
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidTenant reports whether id can name a tenant: lowercase
+// alphanumerics, "_" and "-", at most 63 characters.
+func ValidTenant(id string) bool {
+	return tenantPattern.MatchString(id)
+}
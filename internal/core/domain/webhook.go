@@ -0,0 +1,87 @@
+package domain
+
+import "time"
+
+// EventType identifies the kind of device event delivered to webhooks.
+type EventType string
+
+const (
+	EventDeviceOffline           EventType = "device.offline"
+	EventDeviceOnline            EventType = "device.online"
+	EventUploadThresholdBreached EventType = "device.upload_threshold_breached"
+	EventAlertFiring             EventType = "alert.firing"
+	EventAlertResolved           EventType = "alert.resolved"
+)
+
+// ValidEventType reports whether t is one of the known event types.
+func ValidEventType(t EventType) bool {
+	switch t {
+	case EventDeviceOffline, EventDeviceOnline, EventUploadThresholdBreached,
+		EventAlertFiring, EventAlertResolved:
+		return true
+	}
+	return false
+}
+
+// Event is something that happened to a device and that subscribers may
+// want to hear about.
+type Event struct {
+	ID       string
+	Type     EventType
+	DeviceID string
+	// Tenant owns the device; empty for events that concern no device.
+	Tenant     string
+	OccurredAt time.Time
+	Data       map[string]any
+}
+
+// Webhook is a registered outbound callback URL.
+type Webhook struct {
+	ID        string
+	URL       string
+	Secret    string
+	Events    []EventType
+	CreatedAt time.Time
+}
+
+// Subscribes reports whether the webhook wants events of type t.
+// A webhook with no explicit event list receives everything.
+func (w *Webhook) Subscribes(t EventType) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryStatus is the lifecycle state of a webhook delivery.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// Delivery is one attempt-tracked send of an Event to a Webhook.
+type Delivery struct {
+	ID             string
+	WebhookID      string
+	Event          Event
+	Status         DeliveryStatus
+	Attempts       int
+	CreatedAt      time.Time
+	NextAttemptAt  time.Time
+	LastAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+}
+
+// Due reports whether a pending delivery should be attempted at now.
+func (d *Delivery) Due(now time.Time) bool {
+	return d.Status == DeliveryPending && !d.NextAttemptAt.After(now)
+}
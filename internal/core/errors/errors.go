@@ -1,7 +1,159 @@
+// Package errors defines the core error taxonomy. Every error returned by
+// the services is either an *Error carrying a Kind, or wraps one; adapters
+// map the Kind to their protocol (HTTP status, gRPC code, ...) in one place.
 package errors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Kind classifies an error by how a caller should react to it.
+type Kind int
+
+const (
+	// KindInternal is an unexpected failure; its details are not exposed.
+	KindInternal Kind = iota
+	KindValidation
+	KindNotFound
+	KindConflict
+	KindRateLimited
+	KindUnauthorized
+	KindUnavailable
+	KindForbidden
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindValidation:
+		return "validation"
+	case KindNotFound:
+		return "not-found"
+	case KindConflict:
+		return "conflict"
+	case KindRateLimited:
+		return "rate-limited"
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindUnavailable:
+		return "unavailable"
+	case KindForbidden:
+		return "forbidden"
+	default:
+		return "internal"
+	}
+}
+
+// FieldError describes why one input field was rejected.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (f FieldError) String() string { return f.Field + " " + f.Reason }
+
+// Error is a classified error. Err, when set, is the error it refines
+// (typically one of the sentinels below), so errors.Is keeps matching it.
+type Error struct {
+	Kind   Kind
+	Msg    string
+	Fields []FieldError
+	// RetryAfter is how long to wait before retrying a KindRateLimited or
+	// KindUnavailable error; zero if unknown.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Msg
+	case e.Msg == "":
+		return e.Err.Error()
+	default:
+		return e.Err.Error() + ": " + e.Msg
+	}
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// New returns an error of the given kind.
+func New(kind Kind, msg string, fields ...FieldError) *Error {
+	return &Error{Kind: kind, Msg: msg, Fields: fields}
+}
+
+// Invalid returns a validation error for a single field.
+func Invalid(field, reason string) *Error {
+	return New(KindValidation, field+" "+reason, FieldError{Field: field, Reason: reason})
+}
+
+// Wrap refines base with a message and field details, keeping base's kind.
+func Wrap(base error, msg string, fields ...FieldError) *Error {
+	return &Error{Kind: KindOf(base), Msg: msg, Fields: fields, Err: base}
+}
+
+// Wrapf is Wrap with a formatted message and no field details.
+func Wrapf(base error, format string, args ...any) *Error {
+	return Wrap(base, fmt.Sprintf(format, args...))
+}
+
+// RateLimited returns a KindRateLimited error.
+func RateLimited(retryAfter time.Duration) *Error {
+	return &Error{Kind: KindRateLimited, Msg: "rate limit exceeded", RetryAfter: retryAfter}
+}
+
+// KindOf returns the kind of the outermost *Error in err's chain, or
+// KindInternal if there is none.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// FieldsOf collects the field details found along err's chain, outermost
+// first.
+func FieldsOf(err error) []FieldError {
+	var out []FieldError
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			out = append(out, e.Fields...)
+		}
+		err = errors.Unwrap(err)
+	}
+	return out
+}
+
+// Join formats field errors as "a must be x; b must be y".
+func Join(fields []FieldError) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = f.String()
+	}
+	return strings.Join(parts, "; ")
+}
 
 var (
-	ErrDeviceNotFound = errors.New("device not found")
+	ErrDeviceNotFound  error = New(KindNotFound, "device not found")
+	ErrDeviceExists    error = New(KindConflict, "device already exists")
+	ErrWebhookNotFound error = New(KindNotFound, "webhook not found")
+	ErrInvalidWebhook  error = New(KindValidation, "invalid webhook")
+
+	ErrAlertRuleNotFound error = New(KindNotFound, "alert rule not found")
+	ErrInvalidAlertRule  error = New(KindValidation, "invalid alert rule")
+	ErrSilenceNotFound   error = New(KindNotFound, "silence not found")
+	ErrInvalidSilence    error = New(KindValidation, "invalid silence")
+
+	ErrGroupNotFound error = New(KindNotFound, "group not found")
+	ErrGroupExists   error = New(KindConflict, "group already exists")
+	ErrInvalidGroup  error = New(KindValidation, "invalid group")
+
+	ErrUnauthorized error = New(KindUnauthorized, "unauthorized")
+	ErrForbidden    error = New(KindForbidden, "forbidden")
+
+	ErrQuotaExceeded error = New(KindForbidden, "device quota exceeded")
+	ErrClockSkewed   error = New(KindValidation, "device clock skewed")
 )
@@ -0,0 +1,54 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWrap_KeepsKindAndSentinel(t *testing.T) {
+	err := Wrap(ErrInvalidWebhook, "url must be an absolute http(s) URL",
+		FieldError{Field: "url", Reason: "must be an absolute http(s) URL"})
+
+	if !errors.Is(err, ErrInvalidWebhook) {
+		t.Fatalf("expected errors.Is to match the sentinel")
+	}
+	if KindOf(err) != KindValidation {
+		t.Errorf("expected validation kind, got %s", KindOf(err))
+	}
+	if got, want := err.Error(), "invalid webhook: url must be an absolute http(s) URL"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	cases := []struct {
+		err  error
+		want Kind
+	}{
+		{ErrDeviceNotFound, KindNotFound},
+		{fmt.Errorf("lookup: %w", ErrDeviceNotFound), KindNotFound},
+		{Invalid("upload_time", "must be >= 0"), KindValidation},
+		{RateLimited(0), KindRateLimited},
+		{ErrUnauthorized, KindUnauthorized},
+		{errors.New("boom"), KindInternal},
+	}
+	for _, tc := range cases {
+		if got := KindOf(tc.err); got != tc.want {
+			t.Errorf("KindOf(%v) = %s, want %s", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestFieldsOf_CollectsAlongChain(t *testing.T) {
+	inner := Invalid("for", "must be >= 0")
+	outer := Wrap(inner, "rule rejected", FieldError{Field: "expr", Reason: "unknown metric"})
+
+	fields := FieldsOf(fmt.Errorf("create rule: %w", outer))
+	if len(fields) != 2 || fields[0].Field != "expr" || fields[1].Field != "for" {
+		t.Fatalf("unexpected fields %+v", fields)
+	}
+	if got := Join(fields); got != "expr unknown metric; for must be >= 0" {
+		t.Errorf("unexpected join %q", got)
+	}
+}
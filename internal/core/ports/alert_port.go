@@ -0,0 +1,43 @@
+package ports
+
+import (
+	"safelyyou/internal/core/domain"
+	"time"
+)
+
+// AlertRuleInput is what callers provide to create an alert rule.
+type AlertRuleInput struct {
+	Name     string
+	Expr     string
+	Scope    domain.AlertScope
+	Tenant   string
+	Target   string
+	For      time.Duration
+	Severity string
+}
+
+// AlertService is the port used by the HTTP layer to manage alerting.
+type AlertService interface {
+	CreateRule(in AlertRuleInput) (*domain.AlertRule, error)
+	ListRules() []domain.AlertRule
+	DeleteRule(id string) error
+	ListAlerts(state domain.AlertState) []domain.Alert
+	CreateSilence(s domain.Silence) (*domain.Silence, error)
+	ListSilences() []domain.Silence
+	DeleteSilence(id string) error
+}
+
+// AlertRepository stores alert rules, alert instances and silences.
+type AlertRepository interface {
+	SaveRule(r domain.AlertRule) error
+	ListRules() []domain.AlertRule
+	DeleteRule(id string) error
+
+	SaveAlert(a domain.Alert) error
+	ListAlerts() []domain.Alert
+	DeleteAlert(id string) error
+
+	SaveSilence(s domain.Silence) error
+	ListSilences() []domain.Silence
+	DeleteSilence(id string) error
+}
@@ -1,25 +1,87 @@
 package ports
 
 import (
+	"context"
+	"errors"
 	"safelyyou/internal/core/domain"
 	"time"
 )
 
 type Stats struct {
+	ID            string
 	Uptime        float64
 	AvgUploadTime string
+
+	Site string
+	// Labels are the device labels, site excluded.
+	Labels         map[string]string
+	Uptime24h      float64
+	AvgUpload      time.Duration
+	P95Upload      time.Duration
+	HeartbeatCount int64
+	UploadCount    int64
+	FirstHeartbeat time.Time
+	LastHeartbeat  time.Time
+	LastSeenAt     time.Time
+	LastSentAt     time.Time
+
+	// ClockOffset is how far the device clock is estimated to be ahead of
+	// the server's, zero until enough reports were received; ClockSkewed
+	// is set when it exceeds the configured skew threshold.
+	ClockOffset time.Duration
+	ClockSkewed bool
+
+	// Version and UpdatedAt identify this state of the device, for caching.
+	Version   uint64
+	UpdatedAt time.Time
 }
 
-// DeviceService is the main port used by the HTTP layer.
+// DeviceService is the main port used by the HTTP layer. Every method acts
+// on the tenant of ctx (see WithTenant).
 type DeviceService interface {
-	RecordHeartbeat(id string, sentAt time.Time) error
-	RecordStats(id string, sentAt time.Time, uploadTime int64) error
-	GetStats(id string) (*Stats, error)
+	RecordHeartbeat(ctx context.Context, id string, sentAt time.Time) error
+	RecordStats(ctx context.Context, id string, sentAt time.Time, uploadTime int64) error
+	GetStats(ctx context.Context, id string) (*Stats, error)
+	ListStats(ctx context.Context) ([]Stats, error)
+	ListDevices(ctx context.Context) []string
+	// Tenants lists the tenants owning devices, for workers that span
+	// tenants; it ignores the tenant of ctx.
+	Tenants(ctx context.Context) []string
+
+	// AddDevice registers a device at runtime, next to those from the
+	// device CSV; RemoveDevice forgets a device and its history.
+	AddDevice(ctx context.Context, id, site string) (*Stats, error)
+	RemoveDevice(ctx context.Context, id string) error
+	// GetOutages returns the device's outages since the given time.
+	GetOutages(ctx context.Context, id string, since time.Time) ([]domain.Outage, error)
+	// SetLabels replaces the device labels; a "site" label sets the site.
+	SetLabels(ctx context.Context, id string, labels map[string]string) (*Stats, error)
 }
 
-// DeviceRepository is the persistence port used by the service.
+// ErrUnchanged is returned by a WithDevice callback that left the device
+// as it was; WithDevice then returns nil without bumping the version.
+var ErrUnchanged = errors.New("device unchanged")
+
+// DeviceRepository is the persistence port used by the service. Devices
+// are partitioned by tenant: every operation names the tenant it acts on
+// and never sees the devices of another.
+// WithDevice increments the device's Version when fn returns nil, and
+// fails with ErrDeviceNotFound for an unknown device rather than creating it.
 type DeviceRepository interface {
-	WithDevice(id string, fn func(d *domain.DeviceStats) error) error
-	Exists(id string) bool
-	GetSnapshot(id string) (*domain.DeviceStats, error)
+	WithDevice(ctx context.Context, tenant, id string, fn func(d *domain.DeviceStats) error) error
+	Exists(ctx context.Context, tenant, id string) bool
+	GetSnapshot(ctx context.Context, tenant, id string) (*domain.DeviceStats, error)
+	IDs(tenant string) []string
+	// Tenants returns the tenants owning at least one device, in sorted
+	// order.
+	Tenants() []string
+	// Add stores a new device of d.Tenant, or fails with ErrDeviceExists.
+	Add(ctx context.Context, d *domain.DeviceStats) error
+	// Remove deletes a device, or fails with ErrDeviceNotFound.
+	Remove(ctx context.Context, tenant, id string) error
+	// SetLabels replaces a device's labels and site, keeping the label
+	// index in step, or fails with ErrDeviceNotFound.
+	SetLabels(ctx context.Context, tenant, id string, labels map[string]string) error
+	// Select returns the IDs of the devices matching sel, in sorted order.
+	Select(ctx context.Context, tenant string, sel domain.Selector) []string
 }
@@ -0,0 +1,34 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// ExportFilter selects what an export contains. Zero values match
+// everything; From is inclusive and To exclusive.
+type ExportFilter struct {
+	DeviceIDs []string
+	From      time.Time
+	To        time.Time
+}
+
+// SeriesPoint is one minute of activity of one device.
+type SeriesPoint struct {
+	DeviceID   string
+	Minute     time.Time
+	Heartbeats int64
+	Uploads    int64
+	AvgUpload  time.Duration
+}
+
+// ExportService streams device data for bulk export. Rows are passed to
+// emit one at a time, ordered by device ID (then minute); an error from
+// emit stops the export and is returned.
+type ExportService interface {
+	// ExportStats emits the stats of devices active within the filter's
+	// time range.
+	ExportStats(ctx context.Context, f ExportFilter, emit func(Stats) error) error
+	// ExportSeries emits the per-minute buckets within the time range.
+	ExportSeries(ctx context.Context, f ExportFilter, emit func(SeriesPoint) error) error
+}
@@ -0,0 +1,52 @@
+package ports
+
+import (
+	"context"
+	"safelyyou/internal/core/domain"
+	"time"
+)
+
+// GroupStats aggregates the stats of the devices a group selects.
+//
+// Uptime and Uptime24h are means over the devices that sent at least one
+// heartbeat, and MinUptime24h is the lowest of them. Upload figures pool
+// every upload of the group: AvgUpload weighs devices by upload count and
+// the percentiles are taken over their recent upload samples.
+type GroupStats struct {
+	Group domain.Group
+
+	Devices          int
+	ReportingDevices int
+	OfflineDevices   int
+
+	Uptime       float64
+	Uptime24h    float64
+	MinUptime24h float64
+
+	UploadCount int64
+	AvgUpload   time.Duration
+	P50Upload   time.Duration
+	P95Upload   time.Duration
+	P99Upload   time.Duration
+
+	// DeviceIDs are the selected devices, in sorted order.
+	DeviceIDs []string
+}
+
+// GroupService manages device groups and reports their stats. Groups
+// belong to the tenant of ctx and select only its devices.
+type GroupService interface {
+	CreateGroup(ctx context.Context, name, selector string) (*domain.Group, error)
+	ListGroups(ctx context.Context) []domain.Group
+	DeleteGroup(ctx context.Context, name string) error
+	GroupStats(ctx context.Context, name string) (*GroupStats, error)
+}
+
+// GroupRepository stores groups by tenant and name.
+type GroupRepository interface {
+	// SaveGroup stores a new group of g.Tenant, or fails with ErrGroupExists.
+	SaveGroup(g domain.Group) error
+	GetGroup(tenant, name string) (*domain.Group, error)
+	ListGroups(tenant string) []domain.Group
+	DeleteGroup(tenant, name string) error
+}
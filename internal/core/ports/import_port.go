@@ -0,0 +1,85 @@
+package ports
+
+import (
+	"context"
+	"io"
+)
+
+// ImportFormat is the encoding of an import file.
+type ImportFormat string
+
+const (
+	// ImportCSV has a header row naming the columns type, device_id,
+	// sent_at and upload_time (in any order).
+	ImportCSV ImportFormat = "csv"
+	// ImportNDJSON has one JSON object per line with the same keys.
+	ImportNDJSON ImportFormat = "ndjson"
+)
+
+// ImportOptions control an import.
+type ImportOptions struct {
+	Format ImportFormat
+	// DryRun runs every row through the same checks as a real import,
+	// including the device service's (see WithDryRun), without recording
+	// anything.
+	DryRun bool
+}
+
+// ImportRowError explains why one row was rejected. Line is 1-based and
+// counts the CSV header.
+type ImportRowError struct {
+	Line     int
+	DeviceID string
+	Reason   string
+}
+
+// ImportReport summarizes an import.
+type ImportReport struct {
+	DryRun     bool
+	Rows       int
+	Accepted   int
+	Rejected   int
+	Heartbeats int
+	Stats      int
+	// Errors lists the first rejected rows; ErrorsTruncated is set when
+	// there were more.
+	Errors          []ImportRowError
+	ErrorsTruncated bool
+}
+
+// ImportService backfills historical heartbeats and upload stats.
+type ImportService interface {
+	// Import applies the rows of r in order. Invalid rows are rejected and
+	// reported; an error is returned only if r itself cannot be read.
+	Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error)
+}
+
+type backfillKey struct{}
+
+// WithBackfill marks reports recorded with ctx as historical: they are
+// recorded at their sent_at, ignored by clock skew detection, and neither
+// mark the device as seen nor publish events.
+func WithBackfill(ctx context.Context) context.Context {
+	return context.WithValue(ctx, backfillKey{}, true)
+}
+
+// IsBackfill reports whether ctx was marked by WithBackfill.
+func IsBackfill(ctx context.Context) bool {
+	b, _ := ctx.Value(backfillKey{}).(bool)
+	return b
+}
+
+type dryRunKey struct{}
+
+// WithDryRun marks reports recorded with ctx as a rehearsal: the device
+// service runs every check it would for a real report, then records
+// nothing and publishes no event.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether ctx was marked by WithDryRun.
+func IsDryRun(ctx context.Context) bool {
+	b, _ := ctx.Value(dryRunKey{}).(bool)
+	return b
+}
@@ -0,0 +1,56 @@
+package ports
+
+import (
+	"context"
+	"crypto/subtle"
+	"safelyyou/internal/core/domain"
+)
+
+type tenantKey struct{}
+
+// WithTenant returns a context scoping the services to tenant. Adapters
+// set it once the caller is authenticated.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant ctx is scoped to, or DefaultTenant.
+func TenantFrom(ctx context.Context) string {
+	if t, ok := ctx.Value(tenantKey{}).(string); ok && t != "" {
+		return t
+	}
+	return domain.DefaultTenant
+}
+
+// TenantTokens maps the bearer tokens accepted by the adapters to tenants:
+// the main token acts as the default tenant, tenant tokens as their own.
+type TenantTokens struct {
+	token   string
+	tenants map[string]string
+}
+
+// NewTenantTokens builds the token map; tenantTokens maps token to tenant.
+func NewTenantTokens(token string, tenantTokens map[string]string) TenantTokens {
+	return TenantTokens{token: token, tenants: tenantTokens}
+}
+
+// Enabled reports whether any token is configured; without one, callers
+// are not authenticated.
+func (t TenantTokens) Enabled() bool {
+	return t.token != "" || len(t.tenants) > 0
+}
+
+// Tenant returns the tenant got authenticates as. Every configured token
+// is compared, in constant time.
+func (t TenantTokens) Tenant(got string) (string, bool) {
+	tenant := ""
+	if t.token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(t.token)) == 1 {
+		tenant = domain.DefaultTenant
+	}
+	for token, name := range t.tenants {
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+			tenant = name
+		}
+	}
+	return tenant, tenant != ""
+}
@@ -0,0 +1,43 @@
+package ports
+
+import (
+	"context"
+	"safelyyou/internal/core/domain"
+	"time"
+)
+
+// EventPublisher is used by the core to announce device events.
+type EventPublisher interface {
+	Publish(e domain.Event)
+}
+
+// WebhookService is the port used by the HTTP layer to manage webhooks.
+type WebhookService interface {
+	Register(url, secret string, events []domain.EventType) (*domain.Webhook, error)
+	List() []domain.Webhook
+	Delete(id string) error
+	Deliveries(webhookID string, status domain.DeliveryStatus) ([]domain.Delivery, error)
+}
+
+// WebhookRepository stores registered webhooks.
+type WebhookRepository interface {
+	Save(w domain.Webhook) error
+	Get(id string) (*domain.Webhook, error)
+	List() []domain.Webhook
+	Delete(id string) error
+}
+
+// DeliveryQueue stores webhook deliveries until they are delivered or
+// dead-lettered, and keeps the recent ones afterwards as a delivery log.
+type DeliveryQueue interface {
+	Enqueue(d domain.Delivery) error
+	Due(now time.Time, limit int) []domain.Delivery
+	Update(d domain.Delivery) error
+	List(webhookID string) []domain.Delivery
+}
+
+// WebhookSender performs the outbound HTTP call for a delivery.
+// It returns the response status code (0 when no response was received).
+type WebhookSender interface {
+	Send(ctx context.Context, w domain.Webhook, d domain.Delivery) (int, error)
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/utils"
+	"time"
+)
+
+// AlertServiceImpl manages alert rules and silences and evaluates the rules
+// against the stats exposed by ports.DeviceService.
+type AlertServiceImpl struct {
+	devices ports.DeviceService
+	repo    ports.AlertRepository
+	events  ports.EventPublisher
+	now     func() time.Time
+}
+
+// NewAlertService constructs a new AlertServiceImpl. events may be nil, in
+// which case firing/resolved transitions are only visible via ListAlerts.
+func NewAlertService(devices ports.DeviceService, repo ports.AlertRepository, events ports.EventPublisher) *AlertServiceImpl {
+	return &AlertServiceImpl{devices: devices, repo: repo, events: events, now: time.Now}
+}
+
+// CreateRule validates and stores a new alert rule. Site names and device
+// IDs are only unique within a tenant, so a site or device scoped rule
+// without a tenant applies to the default tenant's.
+func (s *AlertServiceImpl) CreateRule(in ports.AlertRuleInput) (*domain.AlertRule, error) {
+	metric, op, threshold, err := domain.ParseAlertExpr(in.Expr)
+	if err != nil {
+		return nil, coreerrors.Wrap(coreerrors.ErrInvalidAlertRule, err.Error(), coreerrors.FieldError{Field: "expr", Reason: err.Error()})
+	}
+
+	scope := in.Scope
+	switch scope {
+	case "":
+		scope = domain.ScopeFleet
+	case domain.ScopeFleet:
+	case domain.ScopeSite, domain.ScopeDevice:
+		if in.Target == "" {
+			return nil, coreerrors.Wrap(coreerrors.ErrInvalidAlertRule, fmt.Sprintf("%s scope requires a target", scope),
+				coreerrors.FieldError{Field: "target", Reason: "is required for " + string(scope) + " scope"})
+		}
+	default:
+		reason := fmt.Sprintf("unknown scope %q", scope)
+		return nil, coreerrors.Wrap(coreerrors.ErrInvalidAlertRule, reason, coreerrors.FieldError{Field: "scope", Reason: reason})
+	}
+	if in.Tenant != "" && !domain.ValidTenant(in.Tenant) {
+		return nil, coreerrors.Wrap(coreerrors.ErrInvalidAlertRule, fmt.Sprintf("invalid tenant %q", in.Tenant),
+			coreerrors.FieldError{Field: "tenant", Reason: "is not a valid tenant"})
+	}
+	if in.Tenant == "" && scope != domain.ScopeFleet {
+		in.Tenant = domain.DefaultTenant
+	}
+	if in.For < 0 {
+		return nil, coreerrors.Wrap(coreerrors.ErrInvalidAlertRule, "for must be >= 0", coreerrors.FieldError{Field: "for", Reason: "must be >= 0"})
+	}
+
+	name := in.Name
+	if name == "" {
+		name = in.Expr
+	}
+	severity := in.Severity
+	if severity == "" {
+		severity = "warning"
+	}
+
+	rule := domain.AlertRule{
+		ID:        utils.NewID(),
+		Name:      name,
+		Expr:      in.Expr,
+		Metric:    metric,
+		Op:        op,
+		Threshold: threshold,
+		Scope:     scope,
+		Tenant:    in.Tenant,
+		Target:    in.Target,
+		For:       in.For,
+		Severity:  severity,
+		CreatedAt: s.now(),
+	}
+	if scope == domain.ScopeFleet {
+		rule.Target = ""
+	}
+	if err := s.repo.SaveRule(rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *AlertServiceImpl) ListRules() []domain.AlertRule {
+	return s.repo.ListRules()
+}
+
+func (s *AlertServiceImpl) DeleteRule(id string) error {
+	return s.repo.DeleteRule(id)
+}
+
+// ListAlerts returns alerts in the given state, or all of them when state
+// is empty.
+func (s *AlertServiceImpl) ListAlerts(state domain.AlertState) []domain.Alert {
+	all := s.repo.ListAlerts()
+	if state == "" {
+		return all
+	}
+	filtered := make([]domain.Alert, 0, len(all))
+	for _, a := range all {
+		if a.State == state {
+			filtered = append(filtered, a)
+		}
+	}
+	return filtered
+}
+
+// CreateSilence validates and stores a silence. A zero StartsAt means now.
+// Device IDs are only unique within a tenant, so a device silence without
+// a tenant applies to the default tenant's device.
+func (s *AlertServiceImpl) CreateSilence(in domain.Silence) (*domain.Silence, error) {
+	now := s.now()
+	if in.Tenant != "" && !domain.ValidTenant(in.Tenant) {
+		return nil, coreerrors.Wrap(coreerrors.ErrInvalidSilence, fmt.Sprintf("invalid tenant %q", in.Tenant),
+			coreerrors.FieldError{Field: "tenant", Reason: "is not a valid tenant"})
+	}
+	if in.Tenant == "" && in.DeviceID != "" {
+		in.Tenant = domain.DefaultTenant
+	}
+	if in.StartsAt.IsZero() {
+		in.StartsAt = now
+	}
+	if !in.EndsAt.After(in.StartsAt) {
+		return nil, coreerrors.Wrap(coreerrors.ErrInvalidSilence, "ends_at must be after starts_at",
+			coreerrors.FieldError{Field: "ends_at", Reason: "must be after starts_at"})
+	}
+	in.ID = utils.NewID()
+	in.CreatedAt = now
+	if err := s.repo.SaveSilence(in); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+func (s *AlertServiceImpl) ListSilences() []domain.Silence {
+	return s.repo.ListSilences()
+}
+
+func (s *AlertServiceImpl) DeleteSilence(id string) error {
+	return s.repo.DeleteSilence(id)
+}
+
+// Run calls Evaluate every interval until ctx is cancelled.
+func (s *AlertServiceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Evaluate(ctx)
+		}
+	}
+}
+
+// Evaluate checks every rule against every device in its scope and moves
+// alerts through their pending → firing → resolved states. Alerts of
+// devices that no longer exist are resolved, then dropped.
+func (s *AlertServiceImpl) Evaluate(ctx context.Context) {
+	rules := s.repo.ListRules()
+	if len(rules) == 0 {
+		return
+	}
+	now := s.now()
+	silences := s.repo.ListSilences()
+
+	existing := make(map[string]domain.Alert)
+	for _, a := range s.repo.ListAlerts() {
+		existing[a.ID] = a
+	}
+
+	// Rules without a tenant span tenants: every tenant's devices are
+	// evaluated, each through a context scoped to its tenant.
+	live := make(map[string]bool)
+	for _, tenant := range s.devices.Tenants(ctx) {
+		tctx := ports.WithTenant(ctx, tenant)
+		for _, deviceID := range s.devices.ListDevices(tctx) {
+			live[tenant+"/"+deviceID] = true
+			stats, err := s.devices.GetStats(tctx, deviceID)
+			if err != nil {
+				continue
+			}
+			for i := range rules {
+				rule := &rules[i]
+				if !rule.AppliesTo(tenant, deviceID, stats.Site) {
+					continue
+				}
+				value, ok := metricValue(rule.Metric, stats, now)
+				a, seen := existing[domain.AlertID(tenant, rule.ID, deviceID)]
+				if ok && rule.Matches(value) {
+					s.onMatch(rule, tenant, deviceID, value, a, seen, silences, now)
+				} else if seen {
+					s.onClear(a, silences, now)
+				}
+			}
+		}
+	}
+
+	for _, a := range existing {
+		if live[a.Tenant+"/"+a.DeviceID] {
+			continue
+		}
+		if a.State == domain.AlertResolved {
+			_ = s.repo.DeleteAlert(a.ID)
+		} else {
+			s.onClear(a, silences, now)
+		}
+	}
+}
+
+func (s *AlertServiceImpl) onMatch(rule *domain.AlertRule, tenant, deviceID string, value float64, a domain.Alert, seen bool, silences []domain.Silence, now time.Time) {
+	if !seen || a.State == domain.AlertResolved {
+		a = domain.Alert{
+			ID:       domain.AlertID(tenant, rule.ID, deviceID),
+			RuleID:   rule.ID,
+			RuleName: rule.Name,
+			Tenant:   tenant,
+			DeviceID: deviceID,
+			Severity: rule.Severity,
+			State:    domain.AlertPending,
+			StartsAt: now,
+		}
+	}
+	a.Value = value
+	a.LastEvaluatedAt = now
+	a.Silenced = silenced(&a, silences, now)
+
+	if a.State == domain.AlertPending && now.Sub(a.StartsAt) >= rule.For {
+		a.State = domain.AlertFiring
+		a.FiredAt = now
+		s.notify(domain.EventAlertFiring, &a, rule.Expr)
+	}
+	_ = s.repo.SaveAlert(a)
+}
+
+func (s *AlertServiceImpl) onClear(a domain.Alert, silences []domain.Silence, now time.Time) {
+	switch a.State {
+	case domain.AlertPending:
+		// Never fired: nothing to resolve, just forget it.
+		_ = s.repo.DeleteAlert(a.ID)
+	case domain.AlertFiring:
+		a.State = domain.AlertResolved
+		a.ResolvedAt = now
+		a.LastEvaluatedAt = now
+		a.Silenced = silenced(&a, silences, now)
+		s.notify(domain.EventAlertResolved, &a, "")
+		_ = s.repo.SaveAlert(a)
+	}
+}
+
+func (s *AlertServiceImpl) notify(t domain.EventType, a *domain.Alert, expr string) {
+	if s.events == nil || a.Silenced {
+		return
+	}
+	data := map[string]any{
+		"alert_id":  a.ID,
+		"rule_id":   a.RuleID,
+		"rule_name": a.RuleName,
+		"severity":  a.Severity,
+		"value":     a.Value,
+	}
+	if expr != "" {
+		data["expr"] = expr
+	}
+	s.events.Publish(domain.Event{
+		ID:         utils.NewID(),
+		Type:       t,
+		Tenant:     a.Tenant,
+		DeviceID:   a.DeviceID,
+		OccurredAt: s.now(),
+		Data:       data,
+	})
+}
+
+func silenced(a *domain.Alert, silences []domain.Silence, now time.Time) bool {
+	for i := range silences {
+		if silences[i].Mutes(a, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// metricValue extracts a rule metric from device stats. ok is false when
+// the device has no data for the metric yet.
+func metricValue(m domain.AlertMetric, st *ports.Stats, now time.Time) (float64, bool) {
+	switch m {
+	case domain.MetricUptime:
+		return st.Uptime, st.HeartbeatCount > 0
+	case domain.MetricUptime24h:
+		return st.Uptime24h, st.HeartbeatCount > 0
+	case domain.MetricAvgUpload:
+		return st.AvgUpload.Seconds(), st.UploadCount > 0
+	case domain.MetricP95Upload:
+		return st.P95Upload.Seconds(), st.UploadCount > 0
+	case domain.MetricNoHeartbeatFor:
+		if st.LastSeenAt.IsZero() {
+			return 0, false
+		}
+		return now.Sub(st.LastSeenAt).Seconds(), true
+	}
+	return 0, false
+}
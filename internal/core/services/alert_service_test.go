@@ -0,0 +1,372 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+)
+
+// fakeAlertRepo is a map-backed AlertRepository used only for tests.
+type fakeAlertRepo struct {
+	rules    []domain.AlertRule
+	alerts   map[string]domain.Alert
+	silences []domain.Silence
+}
+
+func newFakeAlertRepo() *fakeAlertRepo {
+	return &fakeAlertRepo{alerts: make(map[string]domain.Alert)}
+}
+
+func (r *fakeAlertRepo) SaveRule(rule domain.AlertRule) error {
+	r.rules = append(r.rules, rule)
+	return nil
+}
+
+func (r *fakeAlertRepo) ListRules() []domain.AlertRule { return r.rules }
+
+func (r *fakeAlertRepo) DeleteRule(id string) error {
+	for i := range r.rules {
+		if r.rules[i].ID == id {
+			r.rules = append(r.rules[:i], r.rules[i+1:]...)
+			return nil
+		}
+	}
+	return coreerrors.ErrAlertRuleNotFound
+}
+
+func (r *fakeAlertRepo) SaveAlert(a domain.Alert) error {
+	r.alerts[a.ID] = a
+	return nil
+}
+
+func (r *fakeAlertRepo) ListAlerts() []domain.Alert {
+	out := make([]domain.Alert, 0, len(r.alerts))
+	for _, a := range r.alerts {
+		out = append(out, a)
+	}
+	return out
+}
+
+func (r *fakeAlertRepo) DeleteAlert(id string) error {
+	delete(r.alerts, id)
+	return nil
+}
+
+func (r *fakeAlertRepo) SaveSilence(s domain.Silence) error {
+	r.silences = append(r.silences, s)
+	return nil
+}
+
+func (r *fakeAlertRepo) ListSilences() []domain.Silence { return r.silences }
+
+func (r *fakeAlertRepo) DeleteSilence(string) error { return nil }
+
+// staticDeviceService serves fixed stats per device.
+type staticDeviceService struct {
+	stats map[string]*ports.Stats
+}
+
+func (s *staticDeviceService) RecordHeartbeat(context.Context, string, time.Time) error { return nil }
+func (s *staticDeviceService) RecordStats(context.Context, string, time.Time, int64) error {
+	return nil
+}
+func (s *staticDeviceService) GetStats(_ context.Context, id string) (*ports.Stats, error) {
+	st, ok := s.stats[id]
+	if !ok {
+		return nil, coreerrors.ErrDeviceNotFound
+	}
+	return st, nil
+}
+func (s *staticDeviceService) ListStats(context.Context) ([]ports.Stats, error) {
+	out := make([]ports.Stats, 0, len(s.stats))
+	for _, st := range s.stats {
+		out = append(out, *st)
+	}
+	return out, nil
+}
+func (s *staticDeviceService) ListDevices(context.Context) []string {
+	ids := make([]string, 0, len(s.stats))
+	for id := range s.stats {
+		ids = append(ids, id)
+	}
+	return ids
+}
+func (s *staticDeviceService) Tenants(context.Context) []string {
+	return []string{domain.DefaultTenant}
+}
+func (s *staticDeviceService) AddDevice(context.Context, string, string) (*ports.Stats, error) {
+	return nil, coreerrors.ErrDeviceExists
+}
+func (s *staticDeviceService) RemoveDevice(context.Context, string) error { return nil }
+func (s *staticDeviceService) GetOutages(context.Context, string, time.Time) ([]domain.Outage, error) {
+	return nil, nil
+}
+func (s *staticDeviceService) SetLabels(context.Context, string, map[string]string) (*ports.Stats, error) {
+	return nil, nil
+}
+
+func newTestAlertService(devices *staticDeviceService) (*AlertServiceImpl, *fakeAlertRepo, *recordingPublisher, *time.Time) {
+	repo := newFakeAlertRepo()
+	pub := &recordingPublisher{}
+	now := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	svc := NewAlertService(devices, repo, pub)
+	svc.now = func() time.Time { return now }
+	return svc, repo, pub, &now
+}
+
+func TestCreateRule_ValidatesExpressionAndScope(t *testing.T) {
+	svc, _, _, _ := newTestAlertService(&staticDeviceService{})
+
+	if _, err := svc.CreateRule(ports.AlertRuleInput{Expr: "bogus > 1"}); !errors.Is(err, coreerrors.ErrInvalidAlertRule) {
+		t.Fatalf("expected ErrInvalidAlertRule for bad expr, got %v", err)
+	}
+	if _, err := svc.CreateRule(ports.AlertRuleInput{Expr: "uptime < 95", Scope: domain.ScopeSite}); !errors.Is(err, coreerrors.ErrInvalidAlertRule) {
+		t.Fatalf("expected ErrInvalidAlertRule for site scope without target, got %v", err)
+	}
+
+	rule, err := svc.CreateRule(ports.AlertRuleInput{Expr: "p95_upload > 5m"})
+	if err != nil {
+		t.Fatalf("CreateRule returned error: %v", err)
+	}
+	if rule.Scope != domain.ScopeFleet || rule.Threshold != 300 || rule.Severity != "warning" {
+		t.Fatalf("unexpected rule defaults: %+v", rule)
+	}
+}
+
+func TestEvaluate_PendingFiringResolvedWithDedup(t *testing.T) {
+	devices := &staticDeviceService{stats: map[string]*ports.Stats{
+		"dev-1": {HeartbeatCount: 10, Uptime24h: 90},
+	}}
+	svc, repo, pub, now := newTestAlertService(devices)
+
+	if _, err := svc.CreateRule(ports.AlertRuleInput{Expr: "uptime_24h < 95", For: 5 * time.Minute}); err != nil {
+		t.Fatalf("CreateRule returned error: %v", err)
+	}
+
+	svc.Evaluate(context.Background())
+	if got := svc.ListAlerts(domain.AlertPending); len(got) != 1 {
+		t.Fatalf("expected 1 pending alert, got %+v", repo.alerts)
+	}
+
+	*now = now.Add(5 * time.Minute)
+	svc.Evaluate(context.Background())
+	svc.Evaluate(context.Background())
+	firing := svc.ListAlerts(domain.AlertFiring)
+	if len(firing) != 1 || len(repo.alerts) != 1 {
+		t.Fatalf("expected exactly 1 firing alert, got %+v", repo.alerts)
+	}
+	if len(pub.events) != 1 || pub.events[0].Type != domain.EventAlertFiring {
+		t.Fatalf("expected a single firing notification, got %+v", pub.events)
+	}
+
+	devices.stats["dev-1"].Uptime24h = 99
+	*now = now.Add(time.Minute)
+	svc.Evaluate(context.Background())
+	resolved := svc.ListAlerts(domain.AlertResolved)
+	if len(resolved) != 1 || resolved[0].ResolvedAt != *now {
+		t.Fatalf("expected resolved alert, got %+v", repo.alerts)
+	}
+	if len(pub.events) != 2 || pub.events[1].Type != domain.EventAlertResolved {
+		t.Fatalf("expected resolved notification, got %+v", pub.events)
+	}
+}
+
+func TestEvaluate_PendingThatClearsIsDropped(t *testing.T) {
+	devices := &staticDeviceService{stats: map[string]*ports.Stats{
+		"dev-1": {UploadCount: 1, P95Upload: 10 * time.Minute},
+	}}
+	svc, repo, pub, now := newTestAlertService(devices)
+	_, _ = svc.CreateRule(ports.AlertRuleInput{Expr: "p95_upload > 5m", For: time.Hour})
+
+	svc.Evaluate(context.Background())
+	devices.stats["dev-1"].P95Upload = time.Minute
+	*now = now.Add(time.Minute)
+	svc.Evaluate(context.Background())
+
+	if len(repo.alerts) != 0 || len(pub.events) != 0 {
+		t.Fatalf("expected no alerts or events, got %+v %+v", repo.alerts, pub.events)
+	}
+}
+
+func TestEvaluate_RemovedDeviceResolvesThenDrops(t *testing.T) {
+	devices := &staticDeviceService{stats: map[string]*ports.Stats{
+		"dev-1": {HeartbeatCount: 1, Uptime: 10},
+		"dev-2": {HeartbeatCount: 1, Uptime: 10},
+	}}
+	svc, repo, pub, now := newTestAlertService(devices)
+	rule, _ := svc.CreateRule(ports.AlertRuleInput{Expr: "uptime < 95", For: time.Minute})
+
+	svc.Evaluate(context.Background())
+	*now = now.Add(time.Minute)
+	devices.stats["dev-3"] = &ports.Stats{HeartbeatCount: 1, Uptime: 10}
+	svc.Evaluate(context.Background())
+	if len(svc.ListAlerts(domain.AlertFiring)) != 2 || len(svc.ListAlerts(domain.AlertPending)) != 1 {
+		t.Fatalf("expected 2 firing and 1 pending alert, got %+v", repo.alerts)
+	}
+
+	delete(devices.stats, "dev-1")
+	delete(devices.stats, "dev-3")
+	*now = now.Add(time.Minute)
+	svc.Evaluate(context.Background())
+
+	gone := repo.alerts[domain.AlertID(domain.DefaultTenant, rule.ID, "dev-1")]
+	if gone.State != domain.AlertResolved || gone.ResolvedAt != *now {
+		t.Fatalf("expected removed device's alert to resolve, got %+v", gone)
+	}
+	if _, ok := repo.alerts[domain.AlertID(domain.DefaultTenant, rule.ID, "dev-3")]; ok {
+		t.Fatalf("expected removed device's pending alert to be dropped")
+	}
+	if last := pub.events[len(pub.events)-1]; last.Type != domain.EventAlertResolved || last.DeviceID != "dev-1" {
+		t.Fatalf("expected resolved notification for dev-1, got %+v", last)
+	}
+
+	svc.Evaluate(context.Background())
+	if len(repo.alerts) != 1 || repo.alerts[domain.AlertID(domain.DefaultTenant, rule.ID, "dev-2")].State != domain.AlertFiring {
+		t.Fatalf("expected only dev-2's firing alert to remain, got %+v", repo.alerts)
+	}
+}
+
+func TestEvaluate_ScopeAndMissingData(t *testing.T) {
+	now := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	devices := &staticDeviceService{stats: map[string]*ports.Stats{
+		"dev-a":  {Site: "north", LastSeenAt: now.Add(-time.Hour)},
+		"dev-b":  {Site: "south", LastSeenAt: now.Add(-time.Hour)},
+		"dev-c":  {Site: "north"}, // never seen: no data
+		"dev-ok": {Site: "north", LastSeenAt: now},
+	}}
+	svc, repo, _, _ := newTestAlertService(devices)
+	_, _ = svc.CreateRule(ports.AlertRuleInput{
+		Expr:   "no_heartbeat_for > 10m",
+		Scope:  domain.ScopeSite,
+		Target: "north",
+	})
+
+	svc.Evaluate(context.Background())
+
+	if len(repo.alerts) != 1 {
+		t.Fatalf("expected only dev-a to alert, got %+v", repo.alerts)
+	}
+	for _, a := range repo.alerts {
+		if a.DeviceID != "dev-a" || a.State != domain.AlertFiring {
+			t.Fatalf("unexpected alert: %+v", a)
+		}
+	}
+}
+
+func TestEvaluate_SilencedAlertFiresWithoutNotification(t *testing.T) {
+	devices := &staticDeviceService{stats: map[string]*ports.Stats{
+		"dev-1": {HeartbeatCount: 1, Uptime: 10},
+	}}
+	svc, repo, pub, now := newTestAlertService(devices)
+	rule, _ := svc.CreateRule(ports.AlertRuleInput{Expr: "uptime < 95"})
+
+	if _, err := svc.CreateSilence(domain.Silence{RuleID: rule.ID, EndsAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("CreateSilence returned error: %v", err)
+	}
+	if _, err := svc.CreateSilence(domain.Silence{EndsAt: now.Add(-time.Hour)}); !errors.Is(err, coreerrors.ErrInvalidSilence) {
+		t.Fatalf("expected ErrInvalidSilence for silence ending in the past, got %v", err)
+	}
+
+	svc.Evaluate(context.Background())
+
+	a := repo.alerts[domain.AlertID(domain.DefaultTenant, rule.ID, "dev-1")]
+	if a.State != domain.AlertFiring || !a.Silenced {
+		t.Fatalf("expected silenced firing alert, got %+v", a)
+	}
+	if len(pub.events) != 0 {
+		t.Fatalf("expected no notification for silenced alert, got %+v", pub.events)
+	}
+}
+
+func TestCreateSilence_DeviceDefaultsToDefaultTenant(t *testing.T) {
+	svc, _, _, now := newTestAlertService(&staticDeviceService{})
+
+	s, err := svc.CreateSilence(domain.Silence{DeviceID: "dev-1", EndsAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("CreateSilence returned error: %v", err)
+	}
+	if s.Tenant != domain.DefaultTenant {
+		t.Fatalf("expected device silence to default to the default tenant, got %q", s.Tenant)
+	}
+	s, err = svc.CreateSilence(domain.Silence{RuleID: "r1", EndsAt: now.Add(time.Hour)})
+	if err != nil || s.Tenant != "" {
+		t.Fatalf("expected rule silence to span tenants, got %+v, %v", s, err)
+	}
+	if _, err := svc.CreateSilence(domain.Silence{Tenant: "Not Valid", EndsAt: now.Add(time.Hour)}); !errors.Is(err, coreerrors.ErrInvalidSilence) {
+		t.Fatalf("expected ErrInvalidSilence for invalid tenant, got %v", err)
+	}
+}
+
+func TestEvaluate_CoversEveryTenant(t *testing.T) {
+	repo := newFakeDeviceRepo()
+	acmeDevice := domain.NewDeviceStats("dev-1")
+	acmeDevice.Tenant = "acme"
+	repo.devices["dev-1"] = domain.NewDeviceStats("dev-1")
+	_ = repo.Add(context.Background(), acmeDevice)
+	devices := NewDeviceService(repo)
+	acme := ports.WithTenant(context.Background(), "acme")
+	if err := devices.RecordHeartbeat(acme, "dev-1", time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := newFakeAlertRepo()
+	pub := &recordingPublisher{}
+	svc := NewAlertService(devices, alerts, pub)
+	rule, _ := svc.CreateRule(ports.AlertRuleInput{Expr: "uptime > 50"})
+
+	svc.Evaluate(context.Background())
+
+	a, ok := alerts.alerts[domain.AlertID("acme", rule.ID, "dev-1")]
+	if !ok || a.Tenant != "acme" || a.State != domain.AlertFiring {
+		t.Fatalf("expected a firing alert for acme's device, got %+v", alerts.alerts)
+	}
+	if _, ok := alerts.alerts[domain.AlertID(domain.DefaultTenant, rule.ID, "dev-1")]; ok || len(alerts.alerts) != 1 {
+		t.Fatalf("expected the default tenant's dev-1, without heartbeats, not to alert: %+v", alerts.alerts)
+	}
+	if len(pub.events) != 1 || pub.events[0].Tenant != "acme" {
+		t.Fatalf("expected the notification to carry the tenant, got %+v", pub.events)
+	}
+}
+
+func TestEvaluate_DeviceRuleCoversItsTenantOnly(t *testing.T) {
+	repo := newFakeDeviceRepo()
+	acmeDevice := domain.NewDeviceStats("dev-1")
+	acmeDevice.Tenant = "acme"
+	repo.devices["dev-1"] = domain.NewDeviceStats("dev-1")
+	_ = repo.Add(context.Background(), acmeDevice)
+	devices := NewDeviceService(repo)
+	at := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	for _, ctx := range []context.Context{context.Background(), ports.WithTenant(context.Background(), "acme")} {
+		if err := devices.RecordHeartbeat(ctx, "dev-1", at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alerts := newFakeAlertRepo()
+	svc := NewAlertService(devices, alerts, nil)
+	defaultRule, err := svc.CreateRule(ports.AlertRuleInput{Expr: "uptime > 50", Scope: domain.ScopeDevice, Target: "dev-1"})
+	if err != nil || defaultRule.Tenant != domain.DefaultTenant {
+		t.Fatalf("expected a device rule to default to the default tenant, got %+v, %v", defaultRule, err)
+	}
+	acmeRule, _ := svc.CreateRule(ports.AlertRuleInput{Expr: "uptime > 50", Scope: domain.ScopeDevice, Tenant: "acme", Target: "dev-1"})
+	if _, err := svc.CreateRule(ports.AlertRuleInput{Expr: "uptime > 50", Tenant: "Not Valid"}); !errors.Is(err, coreerrors.ErrInvalidAlertRule) {
+		t.Fatalf("expected ErrInvalidAlertRule for invalid tenant, got %v", err)
+	}
+
+	svc.Evaluate(context.Background())
+
+	if len(alerts.alerts) != 2 {
+		t.Fatalf("expected one alert per rule, got %+v", alerts.alerts)
+	}
+	if _, ok := alerts.alerts[domain.AlertID(domain.DefaultTenant, defaultRule.ID, "dev-1")]; !ok {
+		t.Errorf("expected the default tenant's dev-1 to alert, got %+v", alerts.alerts)
+	}
+	if _, ok := alerts.alerts[domain.AlertID("acme", acmeRule.ID, "dev-1")]; !ok {
+		t.Errorf("expected acme's dev-1 to alert, got %+v", alerts.alerts)
+	}
+}
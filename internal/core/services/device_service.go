@@ -1,72 +1,405 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"safelyyou/internal/core/domain"
 	coreerrors "safelyyou/internal/core/errors"
 	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/utils"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer is resolved against the global provider, which is a no-op until
+// telemetry is configured.
+var tracer = otel.Tracer("safelyyou/internal/core/services")
+
 // DeviceServiceImpl is the default implementation of DeviceService.
 type DeviceServiceImpl struct {
-	repo ports.DeviceRepository
+	repo            ports.DeviceRepository
+	events          ports.EventPublisher
+	uploadThreshold time.Duration
+	quotas          map[string]int
+	timestamps      domain.TimestampPolicy
+	skewThreshold   time.Duration
+	now             func() time.Time
+
+	// addMu serializes AddDevice so quota checks cannot race.
+	addMu sync.Mutex
+}
+
+// Option configures optional DeviceServiceImpl behaviour.
+type Option func(*DeviceServiceImpl)
+
+// WithEventPublisher makes the service announce device events
+// (device back online, upload threshold breached) to p.
+func WithEventPublisher(p ports.EventPublisher) Option {
+	return func(s *DeviceServiceImpl) { s.events = p }
+}
+
+// WithUploadThreshold publishes EventUploadThresholdBreached for every
+// upload slower than d. Zero disables the check.
+func WithUploadThreshold(d time.Duration) Option {
+	return func(s *DeviceServiceImpl) { s.uploadThreshold = d }
+}
+
+// WithTenantQuotas caps the number of devices each listed tenant may own;
+// AddDevice fails with ErrQuotaExceeded beyond it. Devices loaded from a
+// device CSV count towards the quota but are never rejected.
+func WithTenantQuotas(quotas map[string]int) Option {
+	return func(s *DeviceServiceImpl) { s.quotas = quotas }
+}
+
+// WithTimestampPolicy sets the time device reports are recorded at, and the
+// estimated clock offset beyond which a device is flagged as skewed, and
+// its reports refused under TimestampReject. Zero skew disables flagging.
+func WithTimestampPolicy(p domain.TimestampPolicy, skew time.Duration) Option {
+	return func(s *DeviceServiceImpl) {
+		s.timestamps = p
+		s.skewThreshold = skew
+	}
 }
 
 // NewDeviceService constructs a new DeviceServiceImpl.
-func NewDeviceService(repo ports.DeviceRepository) *DeviceServiceImpl {
-	return &DeviceServiceImpl{repo: repo}
+func NewDeviceService(repo ports.DeviceRepository, opts ...Option) *DeviceServiceImpl {
+	s := &DeviceServiceImpl{repo: repo, timestamps: domain.TimestampTrustDevice, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// RecordHeartbeat updates heartbeat-related fields for a device.
-func (s *DeviceServiceImpl) RecordHeartbeat(id string, sentAt time.Time) error {
-	if !s.repo.Exists(id) {
+// RecordHeartbeat updates heartbeat-related fields for a device. A
+// backfilled heartbeat (see ports.WithBackfill) only adds to the history:
+// it leaves the last seen time and offline state alone and publishes no
+// event. A dry run (see ports.WithDryRun) only runs the checks.
+func (s *DeviceServiceImpl) RecordHeartbeat(ctx context.Context, id string, sentAt time.Time) (err error) {
+	ctx, span := startSpan(ctx, "DeviceService.RecordHeartbeat", id)
+	defer func() { endSpan(span, err) }()
+
+	tenant := ports.TenantFrom(ctx)
+	if !s.repo.Exists(ctx, tenant, id) {
 		return coreerrors.ErrDeviceNotFound
 	}
 
-	return s.repo.WithDevice(id, func(d *domain.DeviceStats) error {
+	receivedAt := s.now()
+	if ports.IsDryRun(ctx) {
+		return s.checkReport(ctx, tenant, id, sentAt, receivedAt)
+	}
+	backfill := ports.IsBackfill(ctx)
+	backOnline := false
+	var rejected error
+	err = s.repo.WithDevice(ctx, tenant, id, func(d *domain.DeviceStats) error {
+		at, err := s.reportTime(ctx, d, sentAt, receivedAt)
+		if err != nil {
+			// The clock sample is kept, so the device is accepted again
+			// once its clock is fixed.
+			rejected = err
+			return nil
+		}
 		// First heartbeat, or out-of-order timestamps (use min/max)
-		if d.HeartbeatCount == 0 || sentAt.Before(d.FirstHeartbeat) {
-			d.FirstHeartbeat = sentAt
+		if d.HeartbeatCount == 0 || at.Before(d.FirstHeartbeat) {
+			d.FirstHeartbeat = at
 		}
-		if d.HeartbeatCount == 0 || sentAt.After(d.LastHeartbeat) {
-			d.LastHeartbeat = sentAt
+		if d.HeartbeatCount == 0 || at.After(d.LastHeartbeat) {
+			d.LastHeartbeat = at
 		}
 		d.HeartbeatCount++
+		d.AddHeartbeatSample(at, receivedAt)
+		if backfill {
+			return nil
+		}
+		d.LastSeenAt = receivedAt
+		d.LastSentAt = sentAt
+		if d.Offline {
+			d.Offline = false
+			backOnline = true
+		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	if rejected != nil {
+		return rejected
+	}
+
+	if backOnline {
+		s.publish(domain.EventDeviceOnline, tenant, id, receivedAt, map[string]any{
+			"sent_at": sentAt,
+		})
+	}
+	return nil
 }
 
-func (s *DeviceServiceImpl) RecordStats(id string, sentAt time.Time, uploadMs int64) error {
+// RecordStats adds an upload to a device's stats. Backfilled uploads
+// publish no event; dry runs (see ports.WithDryRun) only run the checks.
+func (s *DeviceServiceImpl) RecordStats(ctx context.Context, id string, sentAt time.Time, uploadMs int64) (err error) {
+	ctx, span := startSpan(ctx, "DeviceService.RecordStats", id)
+	defer func() { endSpan(span, err) }()
+
 	if uploadMs < 0 {
-		return fmt.Errorf("upload_time must be >= 0")
+		return coreerrors.Invalid("upload_time", "must be >= 0")
 	}
 
 	// Enforce that only known devices (from devices.csv) are valid.
-	if !s.repo.Exists(id) {
+	tenant := ports.TenantFrom(ctx)
+	if !s.repo.Exists(ctx, tenant, id) {
 		return coreerrors.ErrDeviceNotFound
 	}
 
+	receivedAt := s.now()
+	if ports.IsDryRun(ctx) {
+		return s.checkReport(ctx, tenant, id, sentAt, receivedAt)
+	}
+	var rejected error
 	// Only update upload stats;
-	return s.repo.WithDevice(id, func(d *domain.DeviceStats) error {
+	err = s.repo.WithDevice(ctx, tenant, id, func(d *domain.DeviceStats) error {
+		at, err := s.reportTime(ctx, d, sentAt, receivedAt)
+		if err != nil {
+			rejected = err
+			return nil
+		}
 		d.UploadCount++
 		d.UploadSumMs += uploadMs // this is actually ns from upload_time, name aside
+		d.AddUploadSample(at, receivedAt, uploadMs)
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	if rejected != nil {
+		return rejected
+	}
+
+	if s.uploadThreshold > 0 && time.Duration(uploadMs) > s.uploadThreshold && !ports.IsBackfill(ctx) {
+		s.publish(domain.EventUploadThresholdBreached, tenant, id, receivedAt, map[string]any{
+			"sent_at":        sentAt,
+			"upload_time_ns": uploadMs,
+			"threshold_ns":   s.uploadThreshold.Nanoseconds(),
+		})
+	}
+	return nil
 }
 
-func (s *DeviceServiceImpl) GetStats(id string) (*ports.Stats, error) {
+func (s *DeviceServiceImpl) GetStats(ctx context.Context, id string) (_ *ports.Stats, err error) {
+	ctx, span := startSpan(ctx, "DeviceService.GetStats", id)
+	defer func() { endSpan(span, err) }()
 
-	deviceStats, err := s.repo.GetSnapshot(id)
+	deviceStats, err := s.repo.GetSnapshot(ctx, ports.TenantFrom(ctx), id)
 	if err != nil {
 		return nil, err
 	}
+	stats := statsOf(deviceStats)
+	stats.ClockSkewed = s.skewed(deviceStats)
+	return stats, nil
+}
 
+// reportTime records a clock sample for a report received at receivedAt
+// and returns the time the timestamp policy records it at. It fails with
+// ErrClockSkewed when the policy refuses the report. Reports without a
+// sent_at are recorded at receivedAt, and backfilled reports at sentAt;
+// neither touches the clock estimate.
+func (s *DeviceServiceImpl) reportTime(ctx context.Context, d *domain.DeviceStats, sentAt, receivedAt time.Time) (time.Time, error) {
+	if sentAt.IsZero() {
+		return receivedAt, nil
+	}
+	if ports.IsBackfill(ctx) {
+		return sentAt, nil
+	}
+	d.AddClockSample(sentAt, receivedAt)
+	switch s.timestamps {
+	case domain.TimestampTrustServer:
+		return receivedAt, nil
+	case domain.TimestampCorrect:
+		if offset, ok := d.ClockOffset(); ok {
+			return sentAt.Add(-offset), nil
+		}
+	case domain.TimestampReject:
+		if s.skewed(d) {
+			offset, _ := d.ClockOffset()
+			return time.Time{}, coreerrors.Wrap(coreerrors.ErrClockSkewed,
+				fmt.Sprintf("device clock is off by %s", offset.Round(time.Second)),
+				coreerrors.FieldError{Field: "sent_at", Reason: "device clock skewed beyond " + s.skewThreshold.String()})
+		}
+	}
+	return sentAt, nil
+}
+
+// checkReport applies the timestamp policy to a copy of the device, for
+// dry runs (see ports.WithDryRun), so the device is left as it was.
+func (s *DeviceServiceImpl) checkReport(ctx context.Context, tenant, id string, sentAt, receivedAt time.Time) error {
+	d, err := s.repo.GetSnapshot(ctx, tenant, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.reportTime(ctx, d, sentAt, receivedAt)
+	return err
+}
+
+// skewed reports whether d's estimated clock offset exceeds the threshold.
+func (s *DeviceServiceImpl) skewed(d *domain.DeviceStats) bool {
+	offset, ok := d.ClockOffset()
+	return ok && s.skewThreshold > 0 && offset.Abs() > s.skewThreshold
+}
+
+// statsOf computes the reported stats of a device snapshot.
+func statsOf(deviceStats *domain.DeviceStats) *ports.Stats {
 	uptime := deviceStats.UptimePercent()
 	avgUpload := deviceStats.AvgUploadDuration()
+	clockOffset, _ := deviceStats.ClockOffset()
 
 	return &ports.Stats{
-		Uptime:        uptime,
-		AvgUploadTime: avgUpload.String(),
-	}, nil
+		ID:             deviceStats.ID,
+		Uptime:         uptime,
+		AvgUploadTime:  avgUpload.String(),
+		Site:           deviceStats.Site,
+		Labels:         deviceStats.Labels,
+		Uptime24h:      deviceStats.UptimePercentWithin(24 * time.Hour),
+		AvgUpload:      avgUpload,
+		P95Upload:      deviceStats.UploadPercentile(95),
+		HeartbeatCount: deviceStats.HeartbeatCount,
+		UploadCount:    deviceStats.UploadCount,
+		FirstHeartbeat: deviceStats.FirstHeartbeat,
+		LastHeartbeat:  deviceStats.LastHeartbeat,
+		LastSeenAt:     deviceStats.LastSeenAt,
+		LastSentAt:     deviceStats.LastSentAt,
+		ClockOffset:    clockOffset,
+		Version:        deviceStats.Version,
+		UpdatedAt:      deviceStats.UpdatedAt,
+	}
+}
+
+// ListStats returns the stats of every known device, ordered by ID.
+func (s *DeviceServiceImpl) ListStats(ctx context.Context) (_ []ports.Stats, err error) {
+	ctx, span := tracer.Start(ctx, "DeviceService.ListStats")
+	defer func() { endSpan(span, err) }()
+
+	ids := s.repo.IDs(ports.TenantFrom(ctx))
+	out := make([]ports.Stats, 0, len(ids))
+	for _, id := range ids {
+		stats, err := s.GetStats(ctx, id)
+		if errors.Is(err, coreerrors.ErrDeviceNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *stats)
+	}
+	return out, nil
+}
+
+// AddDevice registers a new device with no history, within the tenant's
+// quota.
+func (s *DeviceServiceImpl) AddDevice(ctx context.Context, id, site string) (_ *ports.Stats, err error) {
+	ctx, span := startSpan(ctx, "DeviceService.AddDevice", id)
+	defer func() { endSpan(span, err) }()
+
+	if !utils.IsId(id) {
+		return nil, coreerrors.Invalid("device_id", "is invalid")
+	}
+	tenant := ports.TenantFrom(ctx)
+	d := domain.NewDeviceStats(id)
+	d.Tenant = tenant
+	d.Site = site
+
+	s.addMu.Lock()
+	defer s.addMu.Unlock()
+	if quota, ok := s.quotas[tenant]; ok && len(s.repo.IDs(tenant)) >= quota {
+		return nil, coreerrors.Wrapf(coreerrors.ErrQuotaExceeded, "tenant %s may own at most %d devices", tenant, quota)
+	}
+	if err := s.repo.Add(ctx, d); err != nil {
+		return nil, err
+	}
+	return s.GetStats(ctx, id)
+}
+
+// RemoveDevice forgets a device and its history.
+func (s *DeviceServiceImpl) RemoveDevice(ctx context.Context, id string) (err error) {
+	ctx, span := startSpan(ctx, "DeviceService.RemoveDevice", id)
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.Remove(ctx, ports.TenantFrom(ctx), id)
+}
+
+// GetOutages returns the device's outages since the given time.
+func (s *DeviceServiceImpl) GetOutages(ctx context.Context, id string, since time.Time) (_ []domain.Outage, err error) {
+	ctx, span := startSpan(ctx, "DeviceService.GetOutages", id)
+	defer func() { endSpan(span, err) }()
+
+	d, err := s.repo.GetSnapshot(ctx, ports.TenantFrom(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	return d.Outages(since), nil
+}
+
+// SetLabels validates and replaces the device labels.
+func (s *DeviceServiceImpl) SetLabels(ctx context.Context, id string, labels map[string]string) (_ *ports.Stats, err error) {
+	ctx, span := startSpan(ctx, "DeviceService.SetLabels", id)
+	defer func() { endSpan(span, err) }()
+
+	for k, v := range labels {
+		if err := domain.ValidateLabel(k, v); err != nil {
+			return nil, coreerrors.Invalid("labels", err.Error())
+		}
+	}
+	if err := s.repo.SetLabels(ctx, ports.TenantFrom(ctx), id, labels); err != nil {
+		return nil, err
+	}
+	return s.GetStats(ctx, id)
+}
+
+// ListDevices returns the IDs of the tenant's devices.
+func (s *DeviceServiceImpl) ListDevices(ctx context.Context) []string {
+	_, span := tracer.Start(ctx, "DeviceService.ListDevices")
+	defer span.End()
+
+	return s.repo.IDs(ports.TenantFrom(ctx))
+}
+
+// Tenants lists the tenants owning devices, in order.
+func (s *DeviceServiceImpl) Tenants(ctx context.Context) []string {
+	_, span := tracer.Start(ctx, "DeviceService.Tenants")
+	defer span.End()
+
+	return s.repo.Tenants()
+}
+
+func (s *DeviceServiceImpl) publish(t domain.EventType, tenant, deviceID string, at time.Time, data map[string]any) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(domain.Event{
+		ID:         utils.NewID(),
+		Type:       t,
+		DeviceID:   deviceID,
+		Tenant:     tenant,
+		OccurredAt: at,
+		Data:       data,
+	})
+}
+
+// startSpan opens a service span tagged with the tenant and device it
+// operates on.
+func startSpan(ctx context.Context, name, deviceID string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("tenant", ports.TenantFrom(ctx)), attribute.String("device.id", deviceID)))
+}
+
+// endSpan records err, if any, and ends the span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
 }
@@ -1,45 +1,61 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"math"
+	"sort"
 	"testing"
 	"time"
 
 	"safelyyou/internal/core/domain"
 	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
 )
 
 // fakeDeviceRepo is a tiny in-memory DeviceRepository used only for tests.
+// devices holds the default tenant's devices.
 type fakeDeviceRepo struct {
 	devices map[string]*domain.DeviceStats
+	tenants map[string]map[string]*domain.DeviceStats
 }
 
 func newFakeDeviceRepo() *fakeDeviceRepo {
+	devices := make(map[string]*domain.DeviceStats)
 	return &fakeDeviceRepo{
-		devices: make(map[string]*domain.DeviceStats),
+		devices: devices,
+		tenants: map[string]map[string]*domain.DeviceStats{domain.DefaultTenant: devices},
 	}
 }
 
-// WithDevice runs fn on the device, creating it if missing.
-func (r *fakeDeviceRepo) WithDevice(id string, fn func(d *domain.DeviceStats) error) error {
-	d, ok := r.devices[id]
+// tenant returns the devices of tenant, creating the map if needed.
+func (r *fakeDeviceRepo) tenant(tenant string) map[string]*domain.DeviceStats {
+	devices, ok := r.tenants[tenant]
 	if !ok {
-		d = domain.NewDeviceStats(id)
-		r.devices[id] = d
+		devices = make(map[string]*domain.DeviceStats)
+		r.tenants[tenant] = devices
+	}
+	return devices
+}
+
+// WithDevice runs fn on the device, or fails with ErrDeviceNotFound.
+func (r *fakeDeviceRepo) WithDevice(_ context.Context, tenant, id string, fn func(d *domain.DeviceStats) error) error {
+	d, ok := r.tenants[tenant][id]
+	if !ok {
+		return coreerrors.ErrDeviceNotFound
 	}
 	return fn(d)
 }
 
 // Exists reports whether a device with the given id is present.
-func (r *fakeDeviceRepo) Exists(id string) bool {
-	_, ok := r.devices[id]
+func (r *fakeDeviceRepo) Exists(_ context.Context, tenant, id string) bool {
+	_, ok := r.tenants[tenant][id]
 	return ok
 }
 
 // GetSnapshot returns a copy of the device stats or ErrDeviceNotFound.
-func (r *fakeDeviceRepo) GetSnapshot(id string) (*domain.DeviceStats, error) {
-	d, ok := r.devices[id]
+func (r *fakeDeviceRepo) GetSnapshot(_ context.Context, tenant, id string) (*domain.DeviceStats, error) {
+	d, ok := r.tenants[tenant][id]
 	if !ok {
 		return nil, coreerrors.ErrDeviceNotFound
 	}
@@ -47,6 +63,68 @@ func (r *fakeDeviceRepo) GetSnapshot(id string) (*domain.DeviceStats, error) {
 	return &deviceCopy, nil
 }
 
+// IDs returns the ids of the tenant's devices in the fake repo.
+func (r *fakeDeviceRepo) IDs(tenant string) []string {
+	ids := make([]string, 0, len(r.tenants[tenant]))
+	for id := range r.tenants[tenant] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Tenants returns the tenants owning devices.
+func (r *fakeDeviceRepo) Tenants() []string {
+	var tenants []string
+	for t, devices := range r.tenants {
+		if len(devices) > 0 {
+			tenants = append(tenants, t)
+		}
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
+// Add stores a new device or returns ErrDeviceExists.
+func (r *fakeDeviceRepo) Add(_ context.Context, d *domain.DeviceStats) error {
+	devices := r.tenant(d.Tenant)
+	if _, ok := devices[d.ID]; ok {
+		return coreerrors.ErrDeviceExists
+	}
+	devices[d.ID] = d
+	return nil
+}
+
+// SetLabels replaces the labels of a device or returns ErrDeviceNotFound.
+func (r *fakeDeviceRepo) SetLabels(_ context.Context, tenant, id string, labels map[string]string) error {
+	d, ok := r.tenants[tenant][id]
+	if !ok {
+		return coreerrors.ErrDeviceNotFound
+	}
+	d.SetLabels(labels)
+	return nil
+}
+
+// Select scans every device of the tenant.
+func (r *fakeDeviceRepo) Select(_ context.Context, tenant string, sel domain.Selector) []string {
+	var ids []string
+	for _, id := range r.IDs(tenant) {
+		if sel.Matches(r.tenants[tenant][id]) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Remove deletes a device or returns ErrDeviceNotFound.
+func (r *fakeDeviceRepo) Remove(_ context.Context, tenant, id string) error {
+	if _, ok := r.tenants[tenant][id]; !ok {
+		return coreerrors.ErrDeviceNotFound
+	}
+	delete(r.tenants[tenant], id)
+	return nil
+}
+
 // -----------------------------------------------------------------------------
 // Tests for RecordHeartbeat
 // -----------------------------------------------------------------------------
@@ -61,11 +139,11 @@ func TestRecordHeartbeat_FirstHeartbeatInitializesStats(t *testing.T) {
 
 	t1 := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
 
-	if err := svc.RecordHeartbeat(id, t1); err != nil {
+	if err := svc.RecordHeartbeat(context.Background(), id, t1); err != nil {
 		t.Fatalf("RecordHeartbeat returned error: %v", err)
 	}
 
-	device, err := repo.GetSnapshot(id)
+	device, err := repo.GetSnapshot(context.Background(), domain.DefaultTenant, id)
 	if err != nil {
 		t.Fatalf("GetSnapshot returned error: %v", err)
 	}
@@ -95,17 +173,17 @@ func TestRecordHeartbeat_MultipleHeartbeatsUpdatesFirstAndLast(t *testing.T) {
 	t2 := t1.Add(5 * time.Minute)
 	t3 := t2.Add(10 * time.Minute)
 
-	if err := svc.RecordHeartbeat(id, t1); err != nil {
+	if err := svc.RecordHeartbeat(context.Background(), id, t1); err != nil {
 		t.Fatalf("RecordHeartbeat(t1) returned error: %v", err)
 	}
-	if err := svc.RecordHeartbeat(id, t2); err != nil {
+	if err := svc.RecordHeartbeat(context.Background(), id, t2); err != nil {
 		t.Fatalf("RecordHeartbeat(t2) returned error: %v", err)
 	}
-	if err := svc.RecordHeartbeat(id, t3); err != nil {
+	if err := svc.RecordHeartbeat(context.Background(), id, t3); err != nil {
 		t.Fatalf("RecordHeartbeat(t3) returned error: %v", err)
 	}
 
-	device, err := repo.GetSnapshot(id)
+	device, err := repo.GetSnapshot(context.Background(), domain.DefaultTenant, id)
 	if err != nil {
 		t.Fatalf("GetSnapshot returned error: %v", err)
 	}
@@ -135,17 +213,17 @@ func TestRecordHeartbeat_OutOfOrderEarlierUpdatesFirst(t *testing.T) {
 	tLate := tMiddle.Add(30 * time.Minute)
 	tEarly := tMiddle.Add(-1 * time.Hour)
 
-	if err := svc.RecordHeartbeat(id, tMiddle); err != nil {
+	if err := svc.RecordHeartbeat(context.Background(), id, tMiddle); err != nil {
 		t.Fatalf("RecordHeartbeat(tNiddle) returned error: %v", err)
 	}
-	if err := svc.RecordHeartbeat(id, tLate); err != nil {
+	if err := svc.RecordHeartbeat(context.Background(), id, tLate); err != nil {
 		t.Fatalf("RecordHeartbeat(tLate) returned error: %v", err)
 	}
-	if err := svc.RecordHeartbeat(id, tEarly); err != nil {
+	if err := svc.RecordHeartbeat(context.Background(), id, tEarly); err != nil {
 		t.Fatalf("RecordHeartbeat returned error: %v", err)
 	}
 
-	device, err := repo.GetSnapshot(id)
+	device, err := repo.GetSnapshot(context.Background(), domain.DefaultTenant, id)
 	if err != nil {
 		t.Fatalf("GetSnapshot returned error: %v", err)
 	}
@@ -169,7 +247,7 @@ func TestRecordHeartbeat_UnknownDeviceReturnsErrDeviceNotFound(t *testing.T) {
 	svc := NewDeviceService(repo)
 
 	id := "does-not-exist"
-	err := svc.RecordHeartbeat(id, time.Now())
+	err := svc.RecordHeartbeat(context.Background(), id, time.Now())
 	if !errors.Is(err, coreerrors.ErrDeviceNotFound) {
 		t.Fatalf("expected ErrDeviceNotFound, got %v", err)
 	}
@@ -195,11 +273,11 @@ func TestRecordStats_UpdatesUploadFieldsOnly(t *testing.T) {
 	svc := NewDeviceService(repo)
 
 	uploadNs := int64(30 * time.Second)
-	if err := svc.RecordStats(id, time.Time{}, uploadNs); err != nil {
+	if err := svc.RecordStats(context.Background(), id, time.Time{}, uploadNs); err != nil {
 		t.Fatalf("RecordStats returned error: %v", err)
 	}
 
-	device, err := repo.GetSnapshot(id)
+	device, err := repo.GetSnapshot(context.Background(), domain.DefaultTenant, id)
 	if err != nil {
 		t.Fatalf("GetSnapshot returned error: %v", err)
 	}
@@ -230,7 +308,7 @@ func TestRecordStats_NegativeUploadReturnsError(t *testing.T) {
 
 	svc := NewDeviceService(repo)
 
-	err := svc.RecordStats(id, time.Time{}, -1)
+	err := svc.RecordStats(context.Background(), id, time.Time{}, -1)
 	if err == nil {
 		t.Fatalf("expected error for negative upload_time, got nil")
 	}
@@ -241,7 +319,7 @@ func TestRecordStats_UnknownDeviceReturnsErrDeviceNotFound(t *testing.T) {
 	svc := NewDeviceService(repo)
 
 	id := "does-not-exist"
-	err := svc.RecordStats(id, time.Time{}, 123)
+	err := svc.RecordStats(context.Background(), id, time.Time{}, 123)
 	if !errors.Is(err, coreerrors.ErrDeviceNotFound) {
 		t.Fatalf("expected ErrDeviceNotFound, got %v", err)
 	}
@@ -255,7 +333,7 @@ func TestGetStats_DeviceNotFoundReturnsError(t *testing.T) {
 	repo := newFakeDeviceRepo()
 	svc := NewDeviceService(repo)
 
-	stats, err := svc.GetStats("missing-id")
+	stats, err := svc.GetStats(context.Background(), "missing-id")
 
 	if !errors.Is(err, coreerrors.ErrDeviceNotFound) {
 		t.Fatalf("expected ErrDeviceNotFound, got %v", err)
@@ -287,7 +365,7 @@ func TestGetStats_ComputesUptimeAndAvgUpload(t *testing.T) {
 	repo.devices[id] = d
 	svc := NewDeviceService(repo)
 
-	stats, err := svc.GetStats(id)
+	stats, err := svc.GetStats(context.Background(), id)
 	if err != nil {
 		t.Fatalf("GetStats returned error: %v", err)
 	}
@@ -299,4 +377,202 @@ func TestGetStats_ComputesUptimeAndAvgUpload(t *testing.T) {
 	if stats.AvgUploadTime != "1m0s" {
 		t.Errorf("expected AvgUploadTime=1m0s, got %q", stats.AvgUploadTime)
 	}
+	if !stats.FirstHeartbeat.Equal(t1) || !stats.LastHeartbeat.Equal(t2) {
+		t.Errorf("expected window %v..%v, got %v..%v", t1, t2, stats.FirstHeartbeat, stats.LastHeartbeat)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Tests for AddDevice, RemoveDevice and GetOutages
+// -----------------------------------------------------------------------------
+
+func TestAddDevice_ValidatesAndRejectsDuplicates(t *testing.T) {
+	repo := newFakeDeviceRepo()
+	svc := NewDeviceService(repo)
+	ctx := context.Background()
+
+	if _, err := svc.AddDevice(ctx, "not-an-id", ""); coreerrors.KindOf(err) != coreerrors.KindValidation {
+		t.Fatalf("expected invalid error, got %v", err)
+	}
+
+	stats, err := svc.AddDevice(ctx, "60-6b-44-84-dc-64", "north")
+	if err != nil {
+		t.Fatalf("AddDevice returned error: %v", err)
+	}
+	if stats.ID != "60-6b-44-84-dc-64" || stats.Site != "north" || stats.HeartbeatCount != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if _, err := svc.AddDevice(ctx, "60-6b-44-84-dc-64", ""); !errors.Is(err, coreerrors.ErrDeviceExists) {
+		t.Fatalf("expected ErrDeviceExists, got %v", err)
+	}
+
+	if err := svc.RemoveDevice(ctx, "60-6b-44-84-dc-64"); err != nil {
+		t.Fatalf("RemoveDevice returned error: %v", err)
+	}
+	if err := svc.RecordHeartbeat(ctx, "60-6b-44-84-dc-64", time.Now()); !errors.Is(err, coreerrors.ErrDeviceNotFound) {
+		t.Fatalf("expected removed device to be unknown, got %v", err)
+	}
+}
+
+func TestTenants_AreIsolatedAndQuotaLimited(t *testing.T) {
+	repo := newFakeDeviceRepo()
+	repo.devices["60-6b-44-84-dc-64"] = domain.NewDeviceStats("60-6b-44-84-dc-64")
+	svc := NewDeviceService(repo, WithTenantQuotas(map[string]int{"acme": 1}))
+	acme := ports.WithTenant(context.Background(), "acme")
+	sentAt := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+
+	if err := svc.RecordHeartbeat(acme, "60-6b-44-84-dc-64", sentAt); !errors.Is(err, coreerrors.ErrDeviceNotFound) {
+		t.Fatalf("expected the default tenant's device to be unknown to acme, got %v", err)
+	}
+	if ids := svc.ListDevices(acme); len(ids) != 0 {
+		t.Fatalf("expected acme to see no devices, got %v", ids)
+	}
+
+	if _, err := svc.AddDevice(acme, "60-6b-44-84-dc-64", ""); err != nil {
+		t.Fatalf("AddDevice for acme returned error: %v", err)
+	}
+	if err := svc.RecordHeartbeat(acme, "60-6b-44-84-dc-64", sentAt); err != nil {
+		t.Fatalf("RecordHeartbeat for acme returned error: %v", err)
+	}
+	if st, _ := svc.GetStats(context.Background(), "60-6b-44-84-dc-64"); st.HeartbeatCount != 0 {
+		t.Fatalf("acme heartbeat leaked into the default tenant: %+v", st)
+	}
+	if _, err := svc.AddDevice(acme, "b4-45-52-a2-f1-3c", ""); !errors.Is(err, coreerrors.ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if _, err := svc.AddDevice(context.Background(), "b4-45-52-a2-f1-3c", ""); err != nil {
+		t.Fatalf("default tenant has no quota, got %v", err)
+	}
+}
+
+func TestGetOutages_ReportsGaps(t *testing.T) {
+	repo := newFakeDeviceRepo()
+	repo.devices["dev-1"] = domain.NewDeviceStats("dev-1")
+	svc := NewDeviceService(repo)
+	ctx := context.Background()
+
+	base := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	for _, m := range []int{0, 1, 5} {
+		if err := svc.RecordHeartbeat(ctx, "dev-1", base.Add(time.Duration(m)*time.Minute)); err != nil {
+			t.Fatalf("RecordHeartbeat returned error: %v", err)
+		}
+	}
+
+	outages, err := svc.GetOutages(ctx, "dev-1", base)
+	if err != nil {
+		t.Fatalf("GetOutages returned error: %v", err)
+	}
+	if len(outages) != 1 || !outages[0].Start.Equal(base.Add(2*time.Minute)) || !outages[0].End.Equal(base.Add(5*time.Minute)) {
+		t.Fatalf("unexpected outages %+v", outages)
+	}
+	if _, err := svc.GetOutages(ctx, "missing", base); !errors.Is(err, coreerrors.ErrDeviceNotFound) {
+		t.Fatalf("expected ErrDeviceNotFound, got %v", err)
+	}
+}
+
+func TestTimestampPolicy_AppliesTheEstimatedClockOffset(t *testing.T) {
+	server := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	// The device clock runs 10 minutes ahead of the server's.
+	heartbeats := func(t *testing.T, svc *DeviceServiceImpl, n int) error {
+		t.Helper()
+		var err error
+		for i := range n {
+			now := server.Add(time.Duration(i) * time.Minute)
+			svc.now = func() time.Time { return now }
+			if err = svc.RecordHeartbeat(context.Background(), "dev-1", now.Add(10*time.Minute)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	newService := func(p domain.TimestampPolicy) (*DeviceServiceImpl, *fakeDeviceRepo) {
+		repo := newFakeDeviceRepo()
+		repo.devices["dev-1"] = domain.NewDeviceStats("dev-1")
+		return NewDeviceService(repo, WithTimestampPolicy(p, 2*time.Minute)), repo
+	}
+
+	svc, repo := newService(domain.TimestampTrustDevice)
+	if err := heartbeats(t, svc, 3); err != nil {
+		t.Fatal(err)
+	}
+	st, _ := svc.GetStats(context.Background(), "dev-1")
+	if !st.LastHeartbeat.Equal(server.Add(12*time.Minute)) || st.ClockOffset != 10*time.Minute || !st.ClockSkewed {
+		t.Fatalf("trust_device: unexpected stats %+v", st)
+	}
+
+	svc, _ = newService(domain.TimestampTrustServer)
+	if err := heartbeats(t, svc, 3); err != nil {
+		t.Fatal(err)
+	}
+	st, _ = svc.GetStats(context.Background(), "dev-1")
+	if !st.LastHeartbeat.Equal(server.Add(2*time.Minute)) || !st.LastSentAt.Equal(server.Add(12*time.Minute)) {
+		t.Fatalf("trust_server: unexpected stats %+v", st)
+	}
+
+	// Corrections start once the estimate has enough samples.
+	svc, repo = newService(domain.TimestampCorrect)
+	if err := heartbeats(t, svc, 4); err != nil {
+		t.Fatal(err)
+	}
+	if first, last := repo.devices["dev-1"].FirstHeartbeat, repo.devices["dev-1"].LastHeartbeat; !first.Equal(server.Add(2*time.Minute)) || !last.Equal(server.Add(11*time.Minute)) {
+		t.Fatalf("correct: expected two reports as sent and two corrected, got %v to %v", first, last)
+	}
+
+	svc, repo = newService(domain.TimestampReject)
+	if err := heartbeats(t, svc, 2); err != nil {
+		t.Fatalf("reject: expected reports accepted before an estimate, got %v", err)
+	}
+	err := heartbeats(t, svc, 3)
+	if !errors.Is(err, coreerrors.ErrClockSkewed) || coreerrors.KindOf(err) != coreerrors.KindValidation {
+		t.Fatalf("reject: expected ErrClockSkewed, got %v", err)
+	}
+	if n := repo.devices["dev-1"].HeartbeatCount; n != 2 {
+		t.Fatalf("reject: expected the skewed report not recorded, got %d heartbeats", n)
+	}
+	samples := len(repo.devices["dev-1"].ClockSamples)
+	err = svc.RecordHeartbeat(ports.WithDryRun(context.Background()), "dev-1", server.Add(13*time.Minute))
+	if !errors.Is(err, coreerrors.ErrClockSkewed) {
+		t.Fatalf("dry run: expected ErrClockSkewed, got %v", err)
+	}
+	if d := repo.devices["dev-1"]; d.HeartbeatCount != 2 || len(d.ClockSamples) != samples {
+		t.Fatalf("dry run: expected the device left as it was, got %+v", d)
+	}
+
+	// Backfilled reports are historical, not skewed.
+	svc, repo = newService(domain.TimestampReject)
+	for range 3 {
+		if err := svc.RecordHeartbeat(ports.WithBackfill(context.Background()), "dev-1", server.Add(-time.Hour)); err != nil {
+			t.Fatalf("backfill: %v", err)
+		}
+	}
+	if len(repo.devices["dev-1"].ClockSamples) != 0 {
+		t.Fatalf("backfill: expected no clock samples")
+	}
+}
+
+func TestTimestampPolicy_StatsWithoutSentAtUseReceiveTime(t *testing.T) {
+	server := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	for _, p := range []domain.TimestampPolicy{domain.TimestampTrustDevice, domain.TimestampTrustServer, domain.TimestampCorrect, domain.TimestampReject} {
+		repo := newFakeDeviceRepo()
+		repo.devices["dev-1"] = domain.NewDeviceStats("dev-1")
+		svc := NewDeviceService(repo, WithTimestampPolicy(p, 2*time.Minute))
+		for i := range 5 {
+			now := server.Add(time.Duration(i) * time.Minute)
+			svc.now = func() time.Time { return now }
+			if err := svc.RecordStats(context.Background(), "dev-1", time.Time{}, int64(time.Second)); err != nil {
+				t.Fatalf("%s: RecordStats returned error: %v", p, err)
+			}
+		}
+
+		d := repo.devices["dev-1"]
+		if len(d.ClockSamples) != 0 || d.UploadCount != 5 {
+			t.Fatalf("%s: expected 5 uploads and no clock samples, got %+v", p, d)
+		}
+		if len(d.Buckets) != 5 || !d.Buckets[0].Minute.Equal(server) {
+			t.Fatalf("%s: expected uploads in the minutes received, got %+v", p, d.Buckets)
+		}
+		if st, _ := svc.GetStats(context.Background(), "dev-1"); st.ClockSkewed || st.ClockOffset != 0 {
+			t.Fatalf("%s: expected no skew, got %+v", p, st)
+		}
+	}
 }
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"slices"
+	"time"
+)
+
+// ExportServiceImpl streams device data from the repository one device
+// snapshot at a time, so an export never holds the whole fleet in memory.
+type ExportServiceImpl struct {
+	repo ports.DeviceRepository
+}
+
+// NewExportService constructs a new ExportServiceImpl.
+func NewExportService(repo ports.DeviceRepository) *ExportServiceImpl {
+	return &ExportServiceImpl{repo: repo}
+}
+
+// ExportStats emits the stats of every selected device whose heartbeats
+// span overlaps [f.From, f.To).
+func (s *ExportServiceImpl) ExportStats(ctx context.Context, f ports.ExportFilter, emit func(ports.Stats) error) (err error) {
+	ctx, span := tracer.Start(ctx, "ExportService.ExportStats")
+	defer func() { endSpan(span, err) }()
+
+	return s.eachDevice(ctx, f, func(st *ports.Stats, _ []ports.SeriesPoint) error {
+		if !f.From.IsZero() && (st.HeartbeatCount == 0 || st.LastHeartbeat.Before(f.From)) {
+			return nil
+		}
+		if !f.To.IsZero() && (st.HeartbeatCount == 0 || !st.FirstHeartbeat.Before(f.To)) {
+			return nil
+		}
+		return emit(*st)
+	})
+}
+
+// ExportSeries emits the minute buckets of every selected device within
+// [f.From, f.To), oldest first. Only buckets kept by SeriesRetention exist.
+func (s *ExportServiceImpl) ExportSeries(ctx context.Context, f ports.ExportFilter, emit func(ports.SeriesPoint) error) (err error) {
+	ctx, span := tracer.Start(ctx, "ExportService.ExportSeries")
+	defer func() { endSpan(span, err) }()
+
+	return s.eachDevice(ctx, f, func(_ *ports.Stats, points []ports.SeriesPoint) error {
+		for _, p := range points {
+			if !f.From.IsZero() && p.Minute.Before(f.From) {
+				continue
+			}
+			if !f.To.IsZero() && !p.Minute.Before(f.To) {
+				continue
+			}
+			if err := emit(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// eachDevice calls fn with the stats and series of each selected device of
+// the ctx tenant in ID order, stopping early when ctx is cancelled. Unknown
+// requested devices fail the export before fn is first called, so that the
+// caller can still report the error instead of a truncated file.
+func (s *ExportServiceImpl) eachDevice(ctx context.Context, f ports.ExportFilter, fn func(*ports.Stats, []ports.SeriesPoint) error) error {
+	tenant := ports.TenantFrom(ctx)
+	var ids []string
+	if len(f.DeviceIDs) > 0 {
+		ids = slices.Compact(slices.Sorted(slices.Values(f.DeviceIDs)))
+		for _, id := range ids {
+			if !s.repo.Exists(ctx, tenant, id) {
+				return coreerrors.Wrap(coreerrors.ErrDeviceNotFound, id)
+			}
+		}
+	} else {
+		ids = s.repo.IDs(tenant)
+	}
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		d, err := s.repo.GetSnapshot(ctx, tenant, id)
+		if errors.Is(err, coreerrors.ErrDeviceNotFound) {
+			continue // removed since it was looked up
+		}
+		if err != nil {
+			return err
+		}
+
+		points := make([]ports.SeriesPoint, 0, len(d.Buckets))
+		for _, b := range d.Buckets {
+			p := ports.SeriesPoint{DeviceID: d.ID, Minute: b.Minute, Heartbeats: b.Heartbeats, Uploads: b.Uploads}
+			if b.Uploads > 0 {
+				p.AvgUpload = time.Duration(b.UploadSumNs / b.Uploads)
+			}
+			points = append(points, p)
+		}
+		if err := fn(statsOf(d), points); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+)
+
+// newExportFixture has device "a" active 10:00-10:02 and "b" at 12:00.
+func newExportFixture() (*ExportServiceImpl, time.Time) {
+	repo := newFakeDeviceRepo()
+	base := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	for id, minutes := range map[string][]int{"a": {0, 1, 2}, "b": {120}} {
+		d := domain.NewDeviceStats(id)
+		for _, m := range minutes {
+			at := base.Add(time.Duration(m) * time.Minute)
+			if d.HeartbeatCount == 0 {
+				d.FirstHeartbeat = at
+			}
+			d.LastHeartbeat = at
+			d.HeartbeatCount++
+			d.AddHeartbeatSample(at, at)
+		}
+		repo.devices[id] = d
+	}
+	repo.devices["a"].AddUploadSample(base, base, int64(4*time.Second))
+	repo.devices["a"].AddUploadSample(base, base, int64(2*time.Second))
+	return NewExportService(repo), base
+}
+
+func TestExportStats_FiltersByDeviceAndTimeRange(t *testing.T) {
+	svc, base := newExportFixture()
+	collect := func(f ports.ExportFilter) []string {
+		var ids []string
+		if err := svc.ExportStats(context.Background(), f, func(s ports.Stats) error {
+			ids = append(ids, s.ID)
+			return nil
+		}); err != nil {
+			t.Fatalf("ExportStats: %v", err)
+		}
+		return ids
+	}
+
+	if got := collect(ports.ExportFilter{}); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("expected all devices in ID order, got %v", got)
+	}
+	if got := collect(ports.ExportFilter{From: base.Add(time.Hour)}); len(got) != 1 || got[0] != "b" {
+		t.Errorf("expected only b after 11:00, got %v", got)
+	}
+	if got := collect(ports.ExportFilter{DeviceIDs: []string{"b", "a", "b"}, To: base.Add(time.Minute)}); len(got) != 1 || got[0] != "a" {
+		t.Errorf("expected only a before 10:01, got %v", got)
+	}
+}
+
+func TestExportSeries_EmitsBucketsInRange(t *testing.T) {
+	svc, base := newExportFixture()
+
+	var points []ports.SeriesPoint
+	err := svc.ExportSeries(context.Background(), ports.ExportFilter{DeviceIDs: []string{"a"}, To: base.Add(2 * time.Minute)},
+		func(p ports.SeriesPoint) error {
+			points = append(points, p)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("ExportSeries: %v", err)
+	}
+	if len(points) != 2 || !points[0].Minute.Equal(base) || !points[1].Minute.Equal(base.Add(time.Minute)) {
+		t.Fatalf("expected the 10:00 and 10:01 buckets, got %+v", points)
+	}
+	if p := points[0]; p.Heartbeats != 1 || p.Uploads != 2 || p.AvgUpload != 3*time.Second {
+		t.Errorf("unexpected first bucket %+v", p)
+	}
+}
+
+func TestExport_UnknownDeviceAndEmitErrors(t *testing.T) {
+	svc, _ := newExportFixture()
+	noop := func(ports.Stats) error { return nil }
+
+	if err := svc.ExportStats(context.Background(), ports.ExportFilter{DeviceIDs: []string{"zz"}}, noop); !errors.Is(err, coreerrors.ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound for an unknown device, got %v", err)
+	}
+	emitted := 0
+	err := svc.ExportSeries(context.Background(), ports.ExportFilter{DeviceIDs: []string{"a", "zz"}}, func(ports.SeriesPoint) error {
+		emitted++
+		return nil
+	})
+	if !errors.Is(err, coreerrors.ErrDeviceNotFound) || emitted != 0 {
+		t.Errorf("expected ErrDeviceNotFound before any row, got %v after %d rows", err, emitted)
+	}
+
+	stop := errors.New("client gone")
+	calls := 0
+	err = svc.ExportStats(context.Background(), ports.ExportFilter{}, func(ports.Stats) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("expected the export to stop at the first emit error, got %v after %d calls", err, calls)
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GroupServiceImpl manages device groups and aggregates their stats from
+// the device repository.
+type GroupServiceImpl struct {
+	devices ports.DeviceRepository
+	groups  ports.GroupRepository
+	now     func() time.Time
+}
+
+// NewGroupService constructs a new GroupServiceImpl.
+func NewGroupService(devices ports.DeviceRepository, groups ports.GroupRepository) *GroupServiceImpl {
+	return &GroupServiceImpl{devices: devices, groups: groups, now: time.Now}
+}
+
+// CreateGroup validates and stores a group selecting devices by selector.
+func (s *GroupServiceImpl) CreateGroup(ctx context.Context, name, selector string) (*domain.Group, error) {
+	if !domain.ValidGroupName(name) {
+		return nil, coreerrors.Wrap(coreerrors.ErrInvalidGroup, "invalid name",
+			coreerrors.FieldError{Field: "name", Reason: "must be lowercase letters, digits, '_' or '-', at most 63 characters"})
+	}
+	sel, err := domain.ParseSelector(selector)
+	if err != nil {
+		return nil, coreerrors.Wrap(coreerrors.ErrInvalidGroup, err.Error(), coreerrors.FieldError{Field: "selector", Reason: err.Error()})
+	}
+	if len(sel) == 0 {
+		return nil, coreerrors.Wrap(coreerrors.ErrInvalidGroup, "empty selector", coreerrors.FieldError{Field: "selector", Reason: "is required"})
+	}
+
+	g := domain.Group{Name: name, Tenant: ports.TenantFrom(ctx), Selector: sel, CreatedAt: s.now()}
+	if err := s.groups.SaveGroup(g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (s *GroupServiceImpl) ListGroups(ctx context.Context) []domain.Group {
+	return s.groups.ListGroups(ports.TenantFrom(ctx))
+}
+
+func (s *GroupServiceImpl) DeleteGroup(ctx context.Context, name string) error {
+	return s.groups.DeleteGroup(ports.TenantFrom(ctx), name)
+}
+
+// GroupStats aggregates the stats of the devices the group currently
+// selects.
+func (s *GroupServiceImpl) GroupStats(ctx context.Context, name string) (_ *ports.GroupStats, err error) {
+	ctx, span := tracer.Start(ctx, "GroupService.GroupStats", trace.WithAttributes(attribute.String("group.name", name)))
+	defer func() { endSpan(span, err) }()
+
+	tenant := ports.TenantFrom(ctx)
+	g, err := s.groups.GetGroup(tenant, name)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ports.GroupStats{Group: *g, DeviceIDs: []string{}}
+	var samples []int64
+	var uploadSum int64
+	for _, id := range s.devices.Select(ctx, tenant, g.Selector) {
+		d, err := s.devices.GetSnapshot(ctx, tenant, id)
+		if errors.Is(err, coreerrors.ErrDeviceNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		out.Devices++
+		out.DeviceIDs = append(out.DeviceIDs, id)
+		if d.Offline {
+			out.OfflineDevices++
+		}
+		if d.HeartbeatCount > 0 {
+			uptime24h := d.UptimePercentWithin(24 * time.Hour)
+			if out.ReportingDevices == 0 || uptime24h < out.MinUptime24h {
+				out.MinUptime24h = uptime24h
+			}
+			out.ReportingDevices++
+			out.Uptime += d.UptimePercent()
+			out.Uptime24h += uptime24h
+		}
+		out.UploadCount += d.UploadCount
+		uploadSum += d.UploadSumMs
+		samples = append(samples, d.UploadSamples...)
+	}
+
+	if out.ReportingDevices > 0 {
+		out.Uptime /= float64(out.ReportingDevices)
+		out.Uptime24h /= float64(out.ReportingDevices)
+	}
+	if out.UploadCount > 0 {
+		out.AvgUpload = time.Duration(max(uploadSum/out.UploadCount, 0))
+	}
+	out.P50Upload = domain.Percentile(samples, 50)
+	out.P95Upload = domain.Percentile(samples, 95)
+	out.P99Upload = domain.Percentile(samples, 99)
+	return out, nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+)
+
+// fakeGroupRepo is a map-backed GroupRepository used only for tests,
+// keyed by "tenant/name".
+type fakeGroupRepo struct {
+	groups map[string]domain.Group
+}
+
+func (r *fakeGroupRepo) SaveGroup(g domain.Group) error {
+	key := g.Tenant + "/" + g.Name
+	if _, ok := r.groups[key]; ok {
+		return coreerrors.ErrGroupExists
+	}
+	r.groups[key] = g
+	return nil
+}
+
+func (r *fakeGroupRepo) GetGroup(tenant, name string) (*domain.Group, error) {
+	g, ok := r.groups[tenant+"/"+name]
+	if !ok {
+		return nil, coreerrors.ErrGroupNotFound
+	}
+	return &g, nil
+}
+
+func (r *fakeGroupRepo) ListGroups(tenant string) []domain.Group {
+	var out []domain.Group
+	for _, g := range r.groups {
+		if g.Tenant == tenant {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+func (r *fakeGroupRepo) DeleteGroup(tenant, name string) error {
+	if _, ok := r.groups[tenant+"/"+name]; !ok {
+		return coreerrors.ErrGroupNotFound
+	}
+	delete(r.groups, tenant+"/"+name)
+	return nil
+}
+
+func TestCreateGroup_Validates(t *testing.T) {
+	svc := NewGroupService(newFakeDeviceRepo(), &fakeGroupRepo{groups: map[string]domain.Group{}})
+	ctx := context.Background()
+
+	for _, tc := range []struct{ name, selector string }{
+		{"North Wing", "site=north"},
+		{"north", ""},
+		{"north", "Site=north"},
+	} {
+		if _, err := svc.CreateGroup(ctx, tc.name, tc.selector); !errors.Is(err, coreerrors.ErrInvalidGroup) {
+			t.Errorf("%q %q: expected ErrInvalidGroup, got %v", tc.name, tc.selector, err)
+		}
+	}
+	if _, err := svc.CreateGroup(ctx, "north", "site=north"); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if _, err := svc.CreateGroup(ctx, "north", "site=north"); !errors.Is(err, coreerrors.ErrGroupExists) {
+		t.Fatalf("expected ErrGroupExists, got %v", err)
+	}
+}
+
+func TestGroupStats_AggregatesSelectedDevices(t *testing.T) {
+	repo := newFakeDeviceRepo()
+	devices := NewDeviceService(repo)
+	svc := NewGroupService(repo, &fakeGroupRepo{groups: map[string]domain.Group{}})
+	ctx := context.Background()
+
+	for id, site := range map[string]string{"a": "north", "b": "north", "c": "north", "d": "south"} {
+		repo.devices[id] = domain.NewDeviceStats(id)
+		if _, err := devices.SetLabels(ctx, id, map[string]string{"site": site, "floor": "1"}); err != nil {
+			t.Fatalf("SetLabels(%s): %v", id, err)
+		}
+	}
+
+	base := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	// a: every minute for 10 minutes (110%), b: every other minute (60%).
+	for m := 0; m <= 10; m++ {
+		_ = devices.RecordHeartbeat(ctx, "a", base.Add(time.Duration(m)*time.Minute))
+		if m%2 == 0 {
+			_ = devices.RecordHeartbeat(ctx, "b", base.Add(time.Duration(m)*time.Minute))
+		}
+		_ = devices.RecordHeartbeat(ctx, "d", base.Add(time.Duration(m)*time.Minute))
+	}
+	for i, d := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		_ = devices.RecordStats(ctx, "a", base, int64(d))
+		if i == 0 {
+			_ = devices.RecordStats(ctx, "b", base, int64(10*time.Second))
+		}
+	}
+	repo.devices["c"].Offline = true
+
+	if _, err := svc.CreateGroup(ctx, "north", "site=north,floor=1"); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := svc.GroupStats(ctx, "north")
+	if err != nil {
+		t.Fatalf("GroupStats: %v", err)
+	}
+	if stats.Devices != 3 || stats.ReportingDevices != 2 || stats.OfflineDevices != 1 {
+		t.Fatalf("unexpected device counts %+v", stats)
+	}
+	if stats.Uptime != (110.0+60.0)/2 || stats.MinUptime24h != 60 {
+		t.Errorf("unexpected uptime %v (min %v)", stats.Uptime, stats.MinUptime24h)
+	}
+	if stats.UploadCount != 4 || stats.AvgUpload != 4*time.Second || stats.P50Upload != 2*time.Second || stats.P99Upload != 10*time.Second {
+		t.Errorf("unexpected uploads %+v", stats)
+	}
+
+	if _, err := svc.GroupStats(ctx, "missing"); !errors.Is(err, coreerrors.ErrGroupNotFound) {
+		t.Errorf("expected ErrGroupNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxImportErrors caps the rejected rows listed in an ImportReport.
+const maxImportErrors = 100
+
+// maxImportLine bounds a single NDJSON line.
+const maxImportLine = 1 << 20
+
+const (
+	importHeartbeat = "heartbeat"
+	importStats     = "stats"
+)
+
+// ImportServiceImpl backfills history by replaying each row through the
+// DeviceService, so imported data goes through the same validation and
+// aggregation as live traffic.
+type ImportServiceImpl struct {
+	devices ports.DeviceService
+}
+
+// NewImportService constructs a new ImportServiceImpl.
+func NewImportService(devices ports.DeviceService) *ImportServiceImpl {
+	return &ImportServiceImpl{devices: devices}
+}
+
+// importRow is one decoded row. Err is set when the row could not be
+// decoded; the import continues with the next one.
+type importRow struct {
+	Line       int
+	Type       string
+	DeviceID   string
+	SentAt     time.Time
+	UploadTime int64
+	Err        error
+}
+
+// Import applies the rows of r in order and reports which were rejected.
+func (s *ImportServiceImpl) Import(ctx context.Context, r io.Reader, opts ports.ImportOptions) (_ *ports.ImportReport, err error) {
+	ctx, span := tracer.Start(ctx, "ImportService.Import")
+	defer func() { endSpan(span, err) }()
+
+	var next func() (*importRow, error)
+	switch opts.Format {
+	case ports.ImportCSV:
+		next, err = csvImportRows(r)
+	case ports.ImportNDJSON:
+		next = ndjsonImportRows(r)
+	default:
+		err = coreerrors.Invalid("format", "must be csv or ndjson")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		ctx = ports.WithDryRun(ctx)
+	}
+
+	report := &ports.ImportReport{DryRun: opts.DryRun}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		row, err := next()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		report.Rows++
+
+		rowErr := row.Err
+		if rowErr == nil {
+			rowErr = validateImportRow(row)
+		}
+		if rowErr == nil {
+			rowErr = s.apply(ctx, row)
+		}
+		if rowErr != nil {
+			// Only the row's own fault rejects it; anything else (a
+			// cancelled context, an unavailable store) aborts the import.
+			if k := coreerrors.KindOf(rowErr); k != coreerrors.KindValidation && k != coreerrors.KindNotFound {
+				return nil, rowErr
+			}
+			report.Rejected++
+			if len(report.Errors) < maxImportErrors {
+				report.Errors = append(report.Errors, ports.ImportRowError{Line: row.Line, DeviceID: row.DeviceID, Reason: rowErr.Error()})
+			} else {
+				report.ErrorsTruncated = true
+			}
+			continue
+		}
+
+		report.Accepted++
+		if row.Type == importHeartbeat {
+			report.Heartbeats++
+		} else {
+			report.Stats++
+		}
+	}
+}
+
+func (s *ImportServiceImpl) apply(ctx context.Context, row *importRow) error {
+	ctx = ports.WithBackfill(ctx)
+	if row.Type == importHeartbeat {
+		return s.devices.RecordHeartbeat(ctx, row.DeviceID, row.SentAt)
+	}
+	return s.devices.RecordStats(ctx, row.DeviceID, row.SentAt, row.UploadTime)
+}
+
+// validateImportRow checks what the ingestion endpoints' request binding
+// would have checked for a live request.
+func validateImportRow(row *importRow) error {
+	var fields []coreerrors.FieldError
+	if row.Type != importHeartbeat && row.Type != importStats {
+		fields = append(fields, coreerrors.FieldError{Field: "type", Reason: "must be heartbeat or stats"})
+	}
+	if !utils.IsId(row.DeviceID) {
+		fields = append(fields, coreerrors.FieldError{Field: "device_id", Reason: "is invalid"})
+	}
+	if row.SentAt.IsZero() {
+		fields = append(fields, coreerrors.FieldError{Field: "sent_at", Reason: "is required"})
+	}
+	if row.Type == importStats && row.UploadTime < 0 {
+		fields = append(fields, coreerrors.FieldError{Field: "upload_time", Reason: "must be >= 0"})
+	}
+	if len(fields) > 0 {
+		return coreerrors.New(coreerrors.KindValidation, coreerrors.Join(fields), fields...)
+	}
+	return nil
+}
+
+// csvImportRows reads a CSV file whose header names its columns. type may
+// be omitted, in which case rows with an upload_time are stats.
+func csvImportRows(r io.Reader) (func() (*importRow, error), error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return func() (*importRow, error) { return nil, io.EOF }, nil
+	}
+	if err != nil {
+		return nil, coreerrors.New(coreerrors.KindValidation, "invalid CSV header: "+err.Error())
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"device_id", "sent_at"} {
+		if _, ok := cols[required]; !ok {
+			return nil, coreerrors.Invalid("header", "is missing the "+required+" column")
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	return func() (*importRow, error) {
+		record, err := cr.Read()
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				// The reader resyncs on the next line, so only this row is lost.
+				return &importRow{Line: parseErr.StartLine, Err: coreerrors.New(coreerrors.KindValidation, parseErr.Err.Error())}, nil
+			}
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		row := &importRow{Line: line, Type: field(record, "type"), DeviceID: field(record, "device_id")}
+		upload := field(record, "upload_time")
+		if row.Type == "" {
+			row.Type = importHeartbeat
+			if upload != "" {
+				row.Type = importStats
+			}
+		}
+		row.Err = parseImportFields(row, field(record, "sent_at"), upload)
+		return row, nil
+	}, nil
+}
+
+// ndjsonRecord is the shape of one NDJSON line. upload_time is a
+// json.Number so that "1.5" is reported rather than silently truncated.
+type ndjsonRecord struct {
+	Type       string      `json:"type"`
+	DeviceID   string      `json:"device_id"`
+	SentAt     string      `json:"sent_at"`
+	UploadTime json.Number `json:"upload_time"`
+}
+
+// ndjsonImportRows reads one JSON object per line, skipping blank lines.
+func ndjsonImportRows(r io.Reader) func() (*importRow, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	line := 0
+
+	return func() (*importRow, error) {
+		for sc.Scan() {
+			line++
+			text := strings.TrimSpace(sc.Text())
+			if text == "" {
+				continue
+			}
+			var rec ndjsonRecord
+			if err := json.Unmarshal([]byte(text), &rec); err != nil {
+				return &importRow{Line: line, Err: coreerrors.New(coreerrors.KindValidation, "invalid JSON: "+err.Error())}, nil
+			}
+			row := &importRow{Line: line, Type: rec.Type, DeviceID: rec.DeviceID}
+			if row.Type == "" {
+				row.Type = importHeartbeat
+				if rec.UploadTime != "" {
+					row.Type = importStats
+				}
+			}
+			row.Err = parseImportFields(row, rec.SentAt, rec.UploadTime.String())
+			return row, nil
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+}
+
+// parseImportFields parses the textual sent_at and upload_time of row.
+func parseImportFields(row *importRow, sentAt, upload string) error {
+	if sentAt != "" {
+		t, err := time.Parse(time.RFC3339Nano, sentAt)
+		if err != nil {
+			return coreerrors.Invalid("sent_at", "must be an RFC 3339 timestamp")
+		}
+		row.SentAt = t
+	}
+	if row.Type == importStats {
+		if upload == "" {
+			return coreerrors.Invalid("upload_time", "is required")
+		}
+		n, err := strconv.ParseInt(upload, 10, 64)
+		if err != nil {
+			return coreerrors.Invalid("upload_time", fmt.Sprintf("must be an integer number of nanoseconds, got %q", upload))
+		}
+		row.UploadTime = n
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+)
+
+const importDeviceID = "60-6b-44-84-dc-64"
+
+func newImportFixture() (*ImportServiceImpl, *fakeDeviceRepo) {
+	repo := newFakeDeviceRepo()
+	repo.devices[importDeviceID] = domain.NewDeviceStats(importDeviceID)
+	return NewImportService(NewDeviceService(repo)), repo
+}
+
+func TestImport_CSVAppliesRowsAndReportsRejections(t *testing.T) {
+	svc, repo := newImportFixture()
+	in := strings.Join([]string{
+		"device_id,sent_at,upload_time,type",
+		importDeviceID + ",2025-11-09T10:00:00Z,,heartbeat",
+		importDeviceID + ",2025-11-09T10:01:00Z,,",
+		importDeviceID + ",2025-11-09T10:01:30Z,2000000000,",
+		importDeviceID + ",yesterday,,",
+		"60-6b-44-84-dc-99,2025-11-09T10:02:00Z,,",
+		importDeviceID + ",2025-11-09T10:02:00Z,-1,stats",
+	}, "\n")
+
+	report, err := svc.Import(context.Background(), strings.NewReader(in), ports.ImportOptions{Format: ports.ImportCSV})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.Rows != 6 || report.Accepted != 3 || report.Heartbeats != 2 || report.Stats != 1 || report.Rejected != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	wantLines := []int{5, 6, 7}
+	for i, e := range report.Errors {
+		if e.Line != wantLines[i] {
+			t.Errorf("error %d: expected line %d, got %+v", i, wantLines[i], e)
+		}
+	}
+	if !strings.Contains(report.Errors[1].Reason, "not found") {
+		t.Errorf("expected an unknown device to be reported, got %q", report.Errors[1].Reason)
+	}
+
+	d := repo.devices[importDeviceID]
+	if d.HeartbeatCount != 2 || d.UploadCount != 1 || !d.LastHeartbeat.Equal(time.Date(2025, 11, 9, 10, 1, 0, 0, time.UTC)) {
+		t.Errorf("rows not applied as expected: %+v", d)
+	}
+}
+
+func TestImport_IsNotLiveTraffic(t *testing.T) {
+	repo := newFakeDeviceRepo()
+	d := domain.NewDeviceStats(importDeviceID)
+	d.Offline = true
+	repo.devices[importDeviceID] = d
+	events := &recordingPublisher{}
+	svc := NewImportService(NewDeviceService(repo, WithEventPublisher(events), WithUploadThreshold(time.Second)))
+
+	in := `{"type":"heartbeat","device_id":"` + importDeviceID + `","sent_at":"2025-11-09T10:00:00Z"}
+{"type":"stats","device_id":"` + importDeviceID + `","sent_at":"2025-11-09T10:00:30Z","upload_time":60000000000}`
+	report, err := svc.Import(context.Background(), strings.NewReader(in), ports.ImportOptions{Format: ports.ImportNDJSON})
+	if err != nil || report.Accepted != 2 {
+		t.Fatalf("Import: %+v, %v", report, err)
+	}
+
+	if len(events.events) != 0 {
+		t.Fatalf("expected an import to publish no events, got %+v", events.events)
+	}
+	if d.HeartbeatCount != 1 || d.UploadCount != 1 || !d.Offline || !d.LastSeenAt.IsZero() {
+		t.Fatalf("expected history recorded without touching liveness, got %+v", d)
+	}
+}
+
+func TestImport_NDJSONDryRunRecordsNothing(t *testing.T) {
+	svc, repo := newImportFixture()
+	in := `{"type":"heartbeat","device_id":"` + importDeviceID + `","sent_at":"2025-11-09T10:00:00Z"}
+
+{"device_id":"` + importDeviceID + `","sent_at":"2025-11-09T10:00:30Z","upload_time":1.5}
+{"device_id":"60-6b-44-84-dc-99","sent_at":"2025-11-09T10:00:00Z"}
+not json
+`
+
+	report, err := svc.Import(context.Background(), strings.NewReader(in), ports.ImportOptions{Format: ports.ImportNDJSON, DryRun: true})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if !report.DryRun || report.Rows != 4 || report.Accepted != 1 || report.Rejected != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.Errors[0].Line != 3 || report.Errors[2].Line != 5 {
+		t.Errorf("expected blank lines to count towards line numbers, got %+v", report.Errors)
+	}
+	if d := repo.devices[importDeviceID]; d.HeartbeatCount != 0 || d.Version != 0 {
+		t.Errorf("dry run changed the device: %+v", d)
+	}
+}
+
+// rejectingDeviceService refuses every report, remembering whether it was
+// asked for a dry run.
+type rejectingDeviceService struct {
+	staticDeviceService
+	dryRuns int
+}
+
+func (s *rejectingDeviceService) RecordHeartbeat(ctx context.Context, _ string, _ time.Time) error {
+	if ports.IsDryRun(ctx) {
+		s.dryRuns++
+	}
+	return coreerrors.Invalid("sent_at", "refused by the service")
+}
+
+func TestImport_DryRunAppliesTheServiceChecks(t *testing.T) {
+	devices := &rejectingDeviceService{}
+	in := `{"type":"heartbeat","device_id":"` + importDeviceID + `","sent_at":"2025-11-09T10:00:00Z"}`
+
+	report, err := NewImportService(devices).Import(context.Background(), strings.NewReader(in), ports.ImportOptions{Format: ports.ImportNDJSON, DryRun: true})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.Rejected != 1 || devices.dryRuns != 1 || !strings.Contains(report.Errors[0].Reason, "refused by the service") {
+		t.Fatalf("expected the service to reject the dry-run row, got %+v after %d dry runs", report, devices.dryRuns)
+	}
+}
+
+func TestImport_InvalidInputs(t *testing.T) {
+	svc, _ := newImportFixture()
+
+	_, err := svc.Import(context.Background(), strings.NewReader("id,when\n"), ports.ImportOptions{Format: ports.ImportCSV})
+	if coreerrors.KindOf(err) != coreerrors.KindValidation {
+		t.Errorf("expected a validation error for a header without device_id, got %v", err)
+	}
+	_, err = svc.Import(context.Background(), strings.NewReader(""), ports.ImportOptions{Format: "xml"})
+	if coreerrors.KindOf(err) != coreerrors.KindValidation {
+		t.Errorf("expected a validation error for an unknown format, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = svc.Import(ctx, strings.NewReader("device_id,sent_at\n"), ports.ImportOptions{Format: ports.ImportCSV})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled import to fail, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/ratelimit"
+	"sync/atomic"
+	"time"
+)
+
+// ingestLimitIdle is how long an unused device bucket is kept.
+const ingestLimitIdle = 10 * time.Minute
+
+// IngestLimits configures NewRateLimitedDeviceService.
+type IngestLimits struct {
+	Heartbeat ratelimit.Limit
+	Stats     ratelimit.Limit
+	// DeviceOverride, when set, returns a per-device limit that replaces
+	// Heartbeat and Stats for that device.
+	DeviceOverride func(ctx context.Context, deviceID string) (ratelimit.Limit, bool)
+}
+
+// IngestLimitCounters are the limiter's decisions since start.
+type IngestLimitCounters struct {
+	Allowed   uint64 `json:"allowed"`
+	Throttled uint64 `json:"throttled"`
+}
+
+// RateLimitedDeviceService applies per-device token buckets to
+// RecordHeartbeat and RecordStats before delegating to the wrapped
+// service, failing throttled reports with coreerrors.RateLimited. It is
+// meant for the adapters without a limiter of their own (gRPC, MQTT, UDP);
+// HTTP ingestion is limited by its middleware, per device and per IP.
+type RateLimitedDeviceService struct {
+	ports.DeviceService
+	limits  IngestLimits
+	buckets *ratelimit.Limiter
+	now     func() time.Time
+
+	allowed   atomic.Uint64
+	throttled atomic.Uint64
+}
+
+// NewRateLimitedDeviceService wraps svc with the given limits.
+func NewRateLimitedDeviceService(svc ports.DeviceService, limits IngestLimits) *RateLimitedDeviceService {
+	return &RateLimitedDeviceService{
+		DeviceService: svc,
+		limits:        limits,
+		buckets:       ratelimit.NewLimiter(ingestLimitIdle),
+		now:           time.Now,
+	}
+}
+
+func (s *RateLimitedDeviceService) RecordHeartbeat(ctx context.Context, id string, sentAt time.Time) error {
+	if err := s.allow(ctx, "heartbeat", s.limits.Heartbeat, id); err != nil {
+		return err
+	}
+	return s.DeviceService.RecordHeartbeat(ctx, id, sentAt)
+}
+
+func (s *RateLimitedDeviceService) RecordStats(ctx context.Context, id string, sentAt time.Time, uploadTime int64) error {
+	if err := s.allow(ctx, "stats", s.limits.Stats, id); err != nil {
+		return err
+	}
+	return s.DeviceService.RecordStats(ctx, id, sentAt, uploadTime)
+}
+
+// Counters returns a snapshot of the limiter's decisions.
+func (s *RateLimitedDeviceService) Counters() IngestLimitCounters {
+	return IngestLimitCounters{Allowed: s.allowed.Load(), Throttled: s.throttled.Load()}
+}
+
+func (s *RateLimitedDeviceService) allow(ctx context.Context, kind string, limit ratelimit.Limit, id string) error {
+	if s.limits.DeviceOverride != nil {
+		if override, ok := s.limits.DeviceOverride(ctx, id); ok {
+			limit = override
+		}
+	}
+	key := kind + "|" + ports.TenantFrom(ctx) + "/" + id
+	if allowed, wait := s.buckets.Allow(key, limit, s.now()); !allowed {
+		s.throttled.Add(1)
+		return coreerrors.RateLimited(wait)
+	}
+	s.allowed.Add(1)
+	return nil
+}
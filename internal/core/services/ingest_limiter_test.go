@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/ratelimit"
+)
+
+func TestRateLimitedDeviceService_ThrottlesPerDeviceAndTenant(t *testing.T) {
+	svc := NewRateLimitedDeviceService(&staticDeviceService{}, IngestLimits{
+		Heartbeat: ratelimit.Limit{Rate: 1, Burst: 2},
+		DeviceOverride: func(_ context.Context, id string) (ratelimit.Limit, bool) {
+			return ratelimit.Limit{}, id == "dev-unlimited"
+		},
+	})
+	now := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := svc.RecordHeartbeat(ctx, "dev-1", now); err != nil {
+			t.Fatalf("heartbeat %d within burst returned error: %v", i, err)
+		}
+	}
+	err := svc.RecordHeartbeat(ctx, "dev-1", now)
+	var ce *coreerrors.Error
+	if !errors.As(err, &ce) || ce.Kind != coreerrors.KindRateLimited || ce.RetryAfter <= 0 {
+		t.Fatalf("expected rate limited error with retry-after, got %v", err)
+	}
+
+	if err := svc.RecordHeartbeat(ports.WithTenant(ctx, "acme"), "dev-1", now); err != nil {
+		t.Fatalf("expected another tenant's dev-1 to have its own bucket, got %v", err)
+	}
+	if err := svc.RecordStats(ctx, "dev-1", now, 1); err != nil {
+		t.Fatalf("expected stats to be unlimited, got %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := svc.RecordHeartbeat(ctx, "dev-unlimited", now); err != nil {
+			t.Fatalf("expected override to lift the limit, got %v", err)
+		}
+	}
+
+	now = now.Add(time.Second)
+	if err := svc.RecordHeartbeat(ctx, "dev-1", now); err != nil {
+		t.Fatalf("expected a token after refill, got %v", err)
+	}
+	if c := svc.Counters(); c.Throttled != 1 || c.Allowed != 10 {
+		t.Fatalf("unexpected counters: %+v", c)
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"safelyyou/internal/core/domain"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/utils"
+	"time"
+)
+
+// OfflineMonitor periodically flags devices that have not sent a heartbeat
+// for longer than a threshold and publishes EventDeviceOffline for them.
+// The matching EventDeviceOnline is published by RecordHeartbeat.
+type OfflineMonitor struct {
+	repo   ports.DeviceRepository
+	events ports.EventPublisher
+	after  time.Duration
+	now    func() time.Time
+}
+
+// NewOfflineMonitor constructs a monitor considering a device offline when
+// no heartbeat has been received for the given duration.
+func NewOfflineMonitor(repo ports.DeviceRepository, events ports.EventPublisher, after time.Duration) *OfflineMonitor {
+	return &OfflineMonitor{repo: repo, events: events, after: after, now: time.Now}
+}
+
+// Run calls Check every interval until ctx is cancelled.
+func (m *OfflineMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// Check flags newly offline devices of every tenant and returns how many
+// were flagged. Devices that never sent a heartbeat are ignored.
+func (m *OfflineMonitor) Check(ctx context.Context) int {
+	flagged := 0
+	for _, tenant := range m.repo.Tenants() {
+		flagged += m.checkTenant(ctx, tenant)
+	}
+	return flagged
+}
+
+func (m *OfflineMonitor) checkTenant(ctx context.Context, tenant string) int {
+	now := m.now()
+	flagged := 0
+	for _, id := range m.repo.IDs(tenant) {
+		var lastSeen time.Time
+		wentOffline := false
+		_ = m.repo.WithDevice(ctx, tenant, id, func(d *domain.DeviceStats) error {
+			if d.Offline || d.LastSeenAt.IsZero() || now.Sub(d.LastSeenAt) <= m.after {
+				return ports.ErrUnchanged
+			}
+			d.Offline = true
+			wentOffline = true
+			lastSeen = d.LastSeenAt
+			return nil
+		})
+		if !wentOffline {
+			continue
+		}
+		flagged++
+		m.events.Publish(domain.Event{
+			ID:         utils.NewID(),
+			Type:       domain.EventDeviceOffline,
+			DeviceID:   id,
+			Tenant:     tenant,
+			OccurredAt: now,
+			Data: map[string]any{
+				"last_seen_at": lastSeen,
+				"offline_for":  now.Sub(lastSeen).String(),
+			},
+		})
+	}
+	return flagged
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/logging"
+	"time"
+)
+
+// DispatcherConfig controls retry behaviour of the WebhookDispatcher.
+type DispatcherConfig struct {
+	// MaxAttempts is the number of sends before a delivery is dead-lettered.
+	MaxAttempts int
+	// BaseBackoff is the delay after the first failure; it doubles on each
+	// subsequent failure up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// BatchSize caps how many due deliveries are sent per tick.
+	BatchSize int
+}
+
+// DefaultDispatcherConfig returns the retry settings used when none are given.
+func DefaultDispatcherConfig() DispatcherConfig {
+	return DispatcherConfig{
+		MaxAttempts: 8,
+		BaseBackoff: 5 * time.Second,
+		MaxBackoff:  10 * time.Minute,
+		BatchSize:   100,
+	}
+}
+
+// WebhookDispatcher drains the delivery queue, sending due deliveries and
+// rescheduling failures with exponential backoff.
+type WebhookDispatcher struct {
+	hooks  ports.WebhookRepository
+	queue  ports.DeliveryQueue
+	sender ports.WebhookSender
+	cfg    DispatcherConfig
+	now    func() time.Time
+}
+
+// NewWebhookDispatcher constructs a new WebhookDispatcher.
+func NewWebhookDispatcher(hooks ports.WebhookRepository, queue ports.DeliveryQueue, sender ports.WebhookSender, cfg DispatcherConfig) *WebhookDispatcher {
+	def := DefaultDispatcherConfig()
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = def.MaxAttempts
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = def.BaseBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = def.MaxBackoff
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = def.BatchSize
+	}
+	return &WebhookDispatcher{hooks: hooks, queue: queue, sender: sender, cfg: cfg, now: time.Now}
+}
+
+// Run calls DispatchDue every interval until ctx is cancelled.
+func (d *WebhookDispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.DispatchDue(ctx)
+		}
+	}
+}
+
+// DispatchDue sends every delivery that is due now and returns how many
+// were attempted.
+func (d *WebhookDispatcher) DispatchDue(ctx context.Context) int {
+	due := d.queue.Due(d.now(), d.cfg.BatchSize)
+	for _, del := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		d.attempt(ctx, del)
+	}
+	return len(due)
+}
+
+func (d *WebhookDispatcher) attempt(ctx context.Context, del domain.Delivery) {
+	now := d.now()
+	hook, err := d.hooks.Get(del.WebhookID)
+	if err != nil {
+		del.Status = domain.DeliveryDead
+		if errors.Is(err, coreerrors.ErrWebhookNotFound) {
+			del.LastError = "webhook removed"
+		} else {
+			del.LastError = err.Error()
+		}
+		d.update(del)
+		return
+	}
+
+	code, err := d.sender.Send(ctx, *hook, del)
+	del.Attempts++
+	del.LastAttemptAt = now
+	del.LastStatusCode = code
+
+	if err == nil && code >= 200 && code < 300 {
+		del.Status = domain.DeliveryDelivered
+		del.LastError = ""
+		d.update(del)
+		return
+	}
+
+	if err != nil {
+		del.LastError = err.Error()
+	} else {
+		del.LastError = fmt.Sprintf("unexpected status %d", code)
+	}
+	if del.Attempts >= d.cfg.MaxAttempts {
+		del.Status = domain.DeliveryDead
+		logging.For("webhook").Warn("delivery dead-lettered",
+			"webhook_id", del.WebhookID, "delivery_id", del.ID,
+			"attempts", del.Attempts, "error", del.LastError)
+	} else {
+		del.NextAttemptAt = now.Add(d.backoff(del.Attempts))
+	}
+	d.update(del)
+}
+
+// backoff returns the wait before the next attempt after n failures.
+func (d *WebhookDispatcher) backoff(n int) time.Duration {
+	wait := d.cfg.BaseBackoff
+	for i := 1; i < n; i++ {
+		wait *= 2
+		if wait >= d.cfg.MaxBackoff {
+			return d.cfg.MaxBackoff
+		}
+	}
+	return wait
+}
+
+func (d *WebhookDispatcher) update(del domain.Delivery) {
+	if err := d.queue.Update(del); err != nil {
+		logging.For("webhook").Error("failed to update delivery",
+			"webhook_id", del.WebhookID, "delivery_id", del.ID, "error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+	"safelyyou/internal/core/ports"
+	"safelyyou/pkg/logging"
+	"safelyyou/pkg/utils"
+	"time"
+)
+
+// WebhookServiceImpl manages webhook registrations and turns published
+// events into queued deliveries. It implements both ports.WebhookService
+// and ports.EventPublisher.
+type WebhookServiceImpl struct {
+	hooks ports.WebhookRepository
+	queue ports.DeliveryQueue
+	now   func() time.Time
+}
+
+// NewWebhookService constructs a new WebhookServiceImpl.
+func NewWebhookService(hooks ports.WebhookRepository, queue ports.DeliveryQueue) *WebhookServiceImpl {
+	return &WebhookServiceImpl{hooks: hooks, queue: queue, now: time.Now}
+}
+
+// Register validates and stores a new webhook.
+func (s *WebhookServiceImpl) Register(rawURL, secret string, events []domain.EventType) (*domain.Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, coreerrors.Wrap(coreerrors.ErrInvalidWebhook, "url must be an absolute http(s) URL",
+			coreerrors.FieldError{Field: "url", Reason: "must be an absolute http(s) URL"})
+	}
+	for _, e := range events {
+		if !domain.ValidEventType(e) {
+			reason := fmt.Sprintf("unknown event type %q", e)
+			return nil, coreerrors.Wrap(coreerrors.ErrInvalidWebhook, reason, coreerrors.FieldError{Field: "events", Reason: reason})
+		}
+	}
+
+	w := domain.Webhook{
+		ID:        utils.NewID(),
+		URL:       rawURL,
+		Secret:    secret,
+		Events:    events,
+		CreatedAt: s.now(),
+	}
+	if err := s.hooks.Save(w); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (s *WebhookServiceImpl) List() []domain.Webhook {
+	return s.hooks.List()
+}
+
+func (s *WebhookServiceImpl) Delete(id string) error {
+	return s.hooks.Delete(id)
+}
+
+// Deliveries returns the delivery log of a webhook, optionally filtered by
+// status. An empty webhookID returns deliveries for all webhooks.
+func (s *WebhookServiceImpl) Deliveries(webhookID string, status domain.DeliveryStatus) ([]domain.Delivery, error) {
+	if webhookID != "" {
+		if _, err := s.hooks.Get(webhookID); err != nil {
+			return nil, err
+		}
+	}
+
+	all := s.queue.List(webhookID)
+	if status == "" {
+		return all, nil
+	}
+	filtered := make([]domain.Delivery, 0, len(all))
+	for _, d := range all {
+		if d.Status == status {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered, nil
+}
+
+// Publish enqueues one delivery per webhook subscribed to the event type.
+func (s *WebhookServiceImpl) Publish(e domain.Event) {
+	now := s.now()
+	for _, w := range s.hooks.List() {
+		if !w.Subscribes(e.Type) {
+			continue
+		}
+		err := s.queue.Enqueue(domain.Delivery{
+			ID:            utils.NewID(),
+			WebhookID:     w.ID,
+			Event:         e,
+			Status:        domain.DeliveryPending,
+			CreatedAt:     now,
+			NextAttemptAt: now,
+		})
+		if err != nil {
+			logging.For("webhook").Error("failed to enqueue event",
+				"webhook_id", w.ID, "event", e.Type, "device_id", e.DeviceID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"safelyyou/internal/core/domain"
+	coreerrors "safelyyou/internal/core/errors"
+)
+
+// fakeWebhookRepo is a map-backed WebhookRepository used only for tests.
+type fakeWebhookRepo struct {
+	hooks []domain.Webhook
+}
+
+func (r *fakeWebhookRepo) Save(w domain.Webhook) error {
+	r.hooks = append(r.hooks, w)
+	return nil
+}
+
+func (r *fakeWebhookRepo) Get(id string) (*domain.Webhook, error) {
+	for i := range r.hooks {
+		if r.hooks[i].ID == id {
+			w := r.hooks[i]
+			return &w, nil
+		}
+	}
+	return nil, coreerrors.ErrWebhookNotFound
+}
+
+func (r *fakeWebhookRepo) List() []domain.Webhook {
+	return append([]domain.Webhook(nil), r.hooks...)
+}
+
+func (r *fakeWebhookRepo) Delete(id string) error {
+	for i := range r.hooks {
+		if r.hooks[i].ID == id {
+			r.hooks = append(r.hooks[:i], r.hooks[i+1:]...)
+			return nil
+		}
+	}
+	return coreerrors.ErrWebhookNotFound
+}
+
+// fakeDeliveryQueue is a slice-backed DeliveryQueue used only for tests.
+type fakeDeliveryQueue struct {
+	deliveries []domain.Delivery
+}
+
+func (q *fakeDeliveryQueue) Enqueue(d domain.Delivery) error {
+	q.deliveries = append(q.deliveries, d)
+	return nil
+}
+
+func (q *fakeDeliveryQueue) Due(now time.Time, limit int) []domain.Delivery {
+	var due []domain.Delivery
+	for _, d := range q.deliveries {
+		if d.Due(now) && len(due) < limit {
+			due = append(due, d)
+		}
+	}
+	return due
+}
+
+func (q *fakeDeliveryQueue) Update(d domain.Delivery) error {
+	for i := range q.deliveries {
+		if q.deliveries[i].ID == d.ID {
+			q.deliveries[i] = d
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (q *fakeDeliveryQueue) List(webhookID string) []domain.Delivery {
+	var out []domain.Delivery
+	for _, d := range q.deliveries {
+		if webhookID == "" || d.WebhookID == webhookID {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// fakeSender returns the queued status codes in order and records calls.
+type fakeSender struct {
+	codes []int
+	calls int
+}
+
+func (s *fakeSender) Send(_ context.Context, _ domain.Webhook, _ domain.Delivery) (int, error) {
+	code := s.codes[s.calls]
+	s.calls++
+	if code == 0 {
+		return 0, errors.New("connection refused")
+	}
+	return code, nil
+}
+
+// recordingPublisher collects published events.
+type recordingPublisher struct {
+	events []domain.Event
+}
+
+func (p *recordingPublisher) Publish(e domain.Event) {
+	p.events = append(p.events, e)
+}
+
+// -----------------------------------------------------------------------------
+// Tests for WebhookServiceImpl
+// -----------------------------------------------------------------------------
+
+func TestRegister_RejectsInvalidURLAndEvents(t *testing.T) {
+	svc := NewWebhookService(&fakeWebhookRepo{}, &fakeDeliveryQueue{})
+
+	if _, err := svc.Register("ftp://example.com", "", nil); !errors.Is(err, coreerrors.ErrInvalidWebhook) {
+		t.Fatalf("expected ErrInvalidWebhook for ftp url, got %v", err)
+	}
+	if _, err := svc.Register("http://example.com", "", []domain.EventType{"device.exploded"}); !errors.Is(err, coreerrors.ErrInvalidWebhook) {
+		t.Fatalf("expected ErrInvalidWebhook for unknown event, got %v", err)
+	}
+	if len(svc.List()) != 0 {
+		t.Fatalf("expected no webhooks to be stored")
+	}
+}
+
+func TestPublish_EnqueuesOnlyForSubscribedWebhooks(t *testing.T) {
+	queue := &fakeDeliveryQueue{}
+	svc := NewWebhookService(&fakeWebhookRepo{}, queue)
+
+	all, err := svc.Register("http://all.example.com", "", nil)
+	if err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	_, err = svc.Register("http://offline.example.com", "", []domain.EventType{domain.EventDeviceOffline})
+	if err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+
+	svc.Publish(domain.Event{ID: "e1", Type: domain.EventDeviceOnline, DeviceID: "dev"})
+
+	if len(queue.deliveries) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(queue.deliveries))
+	}
+	d := queue.deliveries[0]
+	if d.WebhookID != all.ID || d.Status != domain.DeliveryPending || d.Event.ID != "e1" {
+		t.Fatalf("unexpected delivery: %+v", d)
+	}
+}
+
+func TestDeliveries_UnknownWebhookReturnsNotFound(t *testing.T) {
+	svc := NewWebhookService(&fakeWebhookRepo{}, &fakeDeliveryQueue{})
+
+	if _, err := svc.Deliveries("missing", ""); !errors.Is(err, coreerrors.ErrWebhookNotFound) {
+		t.Fatalf("expected ErrWebhookNotFound, got %v", err)
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Tests for WebhookDispatcher
+// -----------------------------------------------------------------------------
+
+func newTestDispatcher(sender *fakeSender, maxAttempts int) (*WebhookDispatcher, *WebhookServiceImpl, *fakeDeliveryQueue, *time.Time) {
+	hooks := &fakeWebhookRepo{}
+	queue := &fakeDeliveryQueue{}
+	now := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	svc := NewWebhookService(hooks, queue)
+	svc.now = clock
+	d := NewWebhookDispatcher(hooks, queue, sender, DispatcherConfig{
+		MaxAttempts: maxAttempts,
+		BaseBackoff: time.Second,
+		MaxBackoff:  3 * time.Second,
+	})
+	d.now = clock
+	return d, svc, queue, &now
+}
+
+func TestDispatchDue_RetriesWithBackoffThenDelivers(t *testing.T) {
+	sender := &fakeSender{codes: []int{500, 0, 200}}
+	d, svc, queue, now := newTestDispatcher(sender, 5)
+
+	if _, err := svc.Register("http://example.com", "", nil); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	svc.Publish(domain.Event{ID: "e1", Type: domain.EventDeviceOffline})
+
+	// 1st attempt fails with 500 → retry after 1s.
+	d.DispatchDue(context.Background())
+	del := queue.deliveries[0]
+	if del.Status != domain.DeliveryPending || del.Attempts != 1 || del.LastStatusCode != 500 {
+		t.Fatalf("unexpected delivery after first attempt: %+v", del)
+	}
+	if want := now.Add(time.Second); !del.NextAttemptAt.Equal(want) {
+		t.Fatalf("expected next attempt at %v, got %v", want, del.NextAttemptAt)
+	}
+
+	// Not yet due.
+	if n := d.DispatchDue(context.Background()); n != 0 {
+		t.Fatalf("expected nothing due before backoff elapsed, got %d", n)
+	}
+
+	// 2nd attempt: transport error → backoff doubles to 2s.
+	*now = now.Add(time.Second)
+	d.DispatchDue(context.Background())
+	del = queue.deliveries[0]
+	if del.Attempts != 2 || del.LastError == "" {
+		t.Fatalf("unexpected delivery after second attempt: %+v", del)
+	}
+	if want := now.Add(2 * time.Second); !del.NextAttemptAt.Equal(want) {
+		t.Fatalf("expected next attempt at %v, got %v", want, del.NextAttemptAt)
+	}
+
+	// 3rd attempt succeeds.
+	*now = now.Add(2 * time.Second)
+	d.DispatchDue(context.Background())
+	del = queue.deliveries[0]
+	if del.Status != domain.DeliveryDelivered || del.Attempts != 3 || del.LastError != "" {
+		t.Fatalf("expected delivered after third attempt, got %+v", del)
+	}
+}
+
+func TestDispatchDue_DeadLettersAfterMaxAttempts(t *testing.T) {
+	sender := &fakeSender{codes: []int{503, 503}}
+	d, svc, queue, now := newTestDispatcher(sender, 2)
+
+	if _, err := svc.Register("http://example.com", "", nil); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	svc.Publish(domain.Event{ID: "e1", Type: domain.EventDeviceOffline})
+
+	d.DispatchDue(context.Background())
+	*now = now.Add(time.Minute)
+	d.DispatchDue(context.Background())
+
+	dead, err := svc.Deliveries("", domain.DeliveryDead)
+	if err != nil {
+		t.Fatalf("Deliveries returned error: %v", err)
+	}
+	if len(dead) != 1 || dead[0].Attempts != 2 {
+		t.Fatalf("expected 1 dead delivery after 2 attempts, got %+v", queue.deliveries)
+	}
+
+	*now = now.Add(time.Hour)
+	if n := d.DispatchDue(context.Background()); n != 0 {
+		t.Fatalf("dead deliveries must not be retried, got %d attempted", n)
+	}
+}
+
+func TestDispatchDue_RemovedWebhookIsDeadLettered(t *testing.T) {
+	sender := &fakeSender{}
+	d, svc, queue, _ := newTestDispatcher(sender, 3)
+
+	w, _ := svc.Register("http://example.com", "", nil)
+	svc.Publish(domain.Event{ID: "e1", Type: domain.EventDeviceOffline})
+	if err := svc.Delete(w.ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	d.DispatchDue(context.Background())
+
+	if sender.calls != 0 {
+		t.Fatalf("expected no send for removed webhook, got %d", sender.calls)
+	}
+	if queue.deliveries[0].Status != domain.DeliveryDead {
+		t.Fatalf("expected dead delivery, got %+v", queue.deliveries[0])
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Tests for device events
+// -----------------------------------------------------------------------------
+
+func TestRecordStats_PublishesWhenUploadExceedsThreshold(t *testing.T) {
+	repo := newFakeDeviceRepo()
+	id := "device-123"
+	repo.devices[id] = domain.NewDeviceStats(id)
+	pub := &recordingPublisher{}
+
+	svc := NewDeviceService(repo, WithEventPublisher(pub), WithUploadThreshold(time.Minute))
+
+	if err := svc.RecordStats(context.Background(), id, time.Now(), int64(30*time.Second)); err != nil {
+		t.Fatalf("RecordStats returned error: %v", err)
+	}
+	if len(pub.events) != 0 {
+		t.Fatalf("expected no event below threshold, got %d", len(pub.events))
+	}
+
+	if err := svc.RecordStats(context.Background(), id, time.Now(), int64(2*time.Minute)); err != nil {
+		t.Fatalf("RecordStats returned error: %v", err)
+	}
+	if len(pub.events) != 1 || pub.events[0].Type != domain.EventUploadThresholdBreached {
+		t.Fatalf("expected one threshold event, got %+v", pub.events)
+	}
+}
+
+func TestOfflineMonitor_FlagsOnceAndHeartbeatBringsBackOnline(t *testing.T) {
+	repo := newFakeDeviceRepo()
+	id := "device-123"
+	repo.devices[id] = domain.NewDeviceStats(id)
+	repo.devices["never-seen"] = domain.NewDeviceStats("never-seen")
+	pub := &recordingPublisher{}
+
+	now := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	svc := NewDeviceService(repo, WithEventPublisher(pub))
+	svc.now = clock
+	monitor := NewOfflineMonitor(repo, pub, 10*time.Minute)
+	monitor.now = clock
+
+	if err := svc.RecordHeartbeat(context.Background(), id, now); err != nil {
+		t.Fatalf("RecordHeartbeat returned error: %v", err)
+	}
+
+	now = now.Add(5 * time.Minute)
+	if n := monitor.Check(context.Background()); n != 0 {
+		t.Fatalf("expected no device offline after 5m, got %d", n)
+	}
+
+	now = now.Add(10 * time.Minute)
+	if n := monitor.Check(context.Background()); n != 1 {
+		t.Fatalf("expected 1 device offline after 15m, got %d", n)
+	}
+	if n := monitor.Check(context.Background()); n != 0 {
+		t.Fatalf("expected offline device not to be re-flagged, got %d", n)
+	}
+
+	if err := svc.RecordHeartbeat(context.Background(), id, now); err != nil {
+		t.Fatalf("RecordHeartbeat returned error: %v", err)
+	}
+
+	if len(pub.events) != 2 {
+		t.Fatalf("expected offline and online events, got %+v", pub.events)
+	}
+	if pub.events[0].Type != domain.EventDeviceOffline || pub.events[1].Type != domain.EventDeviceOnline {
+		t.Fatalf("unexpected event sequence: %s, %s", pub.events[0].Type, pub.events[1].Type)
+	}
+	if pub.events[0].DeviceID != id {
+		t.Fatalf("expected offline event for %s, got %s", id, pub.events[0].DeviceID)
+	}
+}
@@ -0,0 +1,293 @@
+// Package client is a typed Go client for the fleet server HTTP API.
+//
+//	c := client.New("http://localhost:8080", client.WithToken(os.Getenv("API_TOKEN")))
+//	err := c.RecordHeartbeat(ctx, "60-6b-44-84-dc-64", time.Now())
+//	stats, err := c.GetStats(ctx, "60-6b-44-84-dc-64")
+//
+// Errors returned by the server are reported as *Error, carrying the
+// problem details of the response. Throttled and unavailable responses
+// are retried with exponential backoff (see WithRetry), and
+// pkg/client/clienttest serves the API in memory for tests.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxRetries       = 3
+	defaultRetryDelay       = 200 * time.Millisecond
+	maxRetryDelay           = 10 * time.Second
+	defaultBatchConcurrency = 8
+)
+
+// Client calls a fleet server. It is safe for concurrent use.
+type Client struct {
+	baseURL          string
+	token            string
+	signingSecret    string
+	http             *http.Client
+	maxRetries       int
+	retryDelay       time.Duration
+	batchConcurrency int
+	now              func() time.Time
+}
+
+// Option configures optional Client behaviour.
+type Option func(*Client)
+
+// WithToken sends token as a bearer token with every request.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) { c.http = h }
+}
+
+// WithSigningSecret signs every request with an HMAC-SHA256 of its
+// timestamp, method, URI and body, for servers started with
+// API_SIGNING_SECRET.
+func WithSigningSecret(secret string) Option {
+	return func(c *Client) { c.signingSecret = secret }
+}
+
+// WithRetry sets how many times a failed request is retried and the delay
+// before the first retry, which doubles with each attempt (with jitter, up
+// to 10s). A Retry-After header takes precedence. Zero retries disables
+// retrying; the default is 3 retries from 200ms.
+//
+// 429, 502, 503 and 504 responses are retried for every request, since the
+// server did not apply them. Other 5xx responses and network errors are
+// retried only for GETs: a heartbeat retried after one may be counted
+// twice.
+func WithRetry(maxRetries int, delay time.Duration) Option {
+	return func(c *Client) { c.maxRetries, c.retryDelay = maxRetries, delay }
+}
+
+// WithBatchConcurrency sets how many requests RecordBatch and ListStats
+// keep in flight. The default is 8.
+func WithBatchConcurrency(n int) Option {
+	return func(c *Client) { c.batchConcurrency = n }
+}
+
+// New returns a client for the server at baseURL, e.g. http://localhost:8080.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:          strings.TrimRight(baseURL, "/"),
+		http:             http.DefaultClient,
+		maxRetries:       defaultMaxRetries,
+		retryDelay:       defaultRetryDelay,
+		batchConcurrency: defaultBatchConcurrency,
+		now:              time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Error is a non-2xx response, decoded from its problem details when the
+// server sent them.
+type Error struct {
+	StatusCode int
+	Title      string
+	Detail     string
+	RequestID  string
+	Fields     []FieldError
+}
+
+// FieldError is one rejected input field.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("server returned %d", e.StatusCode)
+	if e.Title != "" {
+		msg += " " + e.Title
+	}
+	if e.Detail != "" {
+		return msg + ": " + e.Detail
+	}
+	for i, f := range e.Fields {
+		sep := "; "
+		if i == 0 {
+			sep = ": "
+		}
+		msg += fmt.Sprintf("%s%s %s", sep, f.Field, f.Reason)
+	}
+	return msg
+}
+
+// StatusCode returns the HTTP status of err when it is an *Error, or 0.
+func StatusCode(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.StatusCode
+	}
+	return 0
+}
+
+// do sends a request and decodes a JSON response into out, when out is not
+// nil. body, when not nil, is sent as JSON.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	resp, err := c.send(ctx, method, path, query, body, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// send sends a request, retrying it as configured, and returns the
+// response when its status is 2xx. The caller closes the body.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body any, accept string) (*http.Response, error) {
+	uri := path
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, method, uri, payload, body != nil, accept)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		var wait time.Duration
+		if err == nil {
+			wait = retryAfter(resp)
+			err = decodeError(resp)
+			_ = resp.Body.Close()
+		}
+		if attempt >= c.maxRetries || !c.retryable(ctx, method, err) {
+			return nil, err
+		}
+		if wait == 0 {
+			wait = c.backoff(attempt)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method, uri string, payload []byte, hasBody bool, accept string) (*http.Response, error) {
+	var reader io.Reader
+	if hasBody {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+uri, reader)
+	if err != nil {
+		return nil, err
+	}
+	if hasBody {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", accept)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.signingSecret != "" {
+		ts := strconv.FormatInt(c.now().Unix(), 10)
+		req.Header.Set("X-Signature-Timestamp", ts)
+		req.Header.Set("X-Signature", Sign(c.signingSecret, ts, method, req.URL.RequestURI(), payload))
+	}
+	return c.http.Do(req)
+}
+
+// retryable reports whether a failed request may be sent again.
+func (c *Client) retryable(ctx context.Context, method string, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	idempotent := method == http.MethodGet || method == http.MethodHead
+	var e *Error
+	if !errors.As(err, &e) {
+		return idempotent
+	}
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return idempotent && e.StatusCode >= 500
+}
+
+// backoff returns the delay before retry attempt+1: the retry delay
+// doubled per attempt, capped, with the upper half randomized.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.retryDelay << attempt
+	if d <= 0 || d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryAfter reads a Retry-After header in seconds, or returns 0.
+func retryAfter(resp *http.Response) time.Duration {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return 0
+}
+
+// Sign returns the X-Signature value of a request:
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + method + "." + uri + "." + body)),
+// where timestamp is in Unix seconds and uri is the path and query.
+func Sign(secret, timestamp, method, uri string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + method + "." + uri + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func decodeError(resp *http.Response) error {
+	e := &Error{StatusCode: resp.StatusCode}
+	var problem struct {
+		Title     string       `json:"title"`
+		Detail    string       `json:"detail"`
+		RequestID string       `json:"request_id"`
+		Errors    []FieldError `json:"errors"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(data, &problem) == nil {
+		e.Title, e.Detail, e.RequestID, e.Fields = problem.Title, problem.Detail, problem.RequestID, problem.Errors
+	}
+	if e.Title == "" {
+		e.Title = http.StatusText(resp.StatusCode)
+	}
+	return e
+}
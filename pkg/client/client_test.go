@@ -0,0 +1,111 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	httpadapter "safelyyou/internal/adapters/http"
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/services"
+)
+
+const testDevice = "60-6b-44-84-dc-64"
+
+func newFleetServer(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := memory.NewDeviceRepository()
+	svc := services.NewDeviceService(repo)
+	base := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	if _, err := svc.AddDevice(context.Background(), testDevice, "north"); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []int{0, 1, 2, 6, 7} {
+		if err := svc.RecordHeartbeat(context.Background(), testDevice, base.Add(time.Duration(m)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := gin.New()
+	r.Use(httpadapter.BearerAuth(token, nil))
+	httpadapter.RegisterRoutes(r, svc)
+	httpadapter.RegisterExportRoutes(r, services.NewExportService(repo))
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient_AgainstTheServer(t *testing.T) {
+	srv := newFleetServer(t, "secret")
+	c := New(srv.URL+"/", WithToken("secret"), WithHTTPClient(srv.Client()))
+	ctx := context.Background()
+
+	stats, err := c.GetStats(ctx, testDevice)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.Site != "north" || stats.Counts.Heartbeats != 5 || stats.Window.Minutes != 7 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	outages, err := c.Outages(ctx, testDevice, time.Date(2025, 11, 9, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Outages: %v", err)
+	}
+	if len(outages) != 1 || outages[0].DurationMs != 3*60000 || outages[0].Ongoing {
+		t.Errorf("unexpected outages %+v", outages)
+	}
+
+	added, err := c.AddDevice(ctx, "b4-45-52-a2-f1-3c", "")
+	if err != nil || added.ID != "b4-45-52-a2-f1-3c" {
+		t.Fatalf("AddDevice: %+v, %v", added, err)
+	}
+	if _, err := c.AddDevice(ctx, "b4-45-52-a2-f1-3c", ""); StatusCode(err) != http.StatusConflict {
+		t.Errorf("expected 409 on duplicate add, got %v", err)
+	}
+	labelled, err := c.SetLabels(ctx, "b4-45-52-a2-f1-3c", map[string]string{"site": "south", "floor": "2"})
+	if err != nil || labelled.Site != "south" || labelled.Labels["floor"] != "2" {
+		t.Fatalf("SetLabels: %+v, %v", labelled, err)
+	}
+	devices, err := c.ListDevices(ctx)
+	if err != nil || len(devices) != 2 || devices[0].ID != testDevice {
+		t.Fatalf("ListDevices: %+v, %v", devices, err)
+	}
+	if err := c.RemoveDevice(ctx, "b4-45-52-a2-f1-3c"); err != nil {
+		t.Fatalf("RemoveDevice: %v", err)
+	}
+	if _, err := c.GetStats(ctx, "b4-45-52-a2-f1-3c"); StatusCode(err) != http.StatusNotFound {
+		t.Errorf("expected 404 after remove, got %v", err)
+	}
+
+	var out bytes.Buffer
+	n, err := c.Export(ctx, ExportRequest{Dataset: "stats", Format: "csv"}, &out)
+	if err != nil || n == 0 || !strings.Contains(out.String(), testDevice) {
+		t.Fatalf("Export: %d bytes, %v: %q", n, err, out.String())
+	}
+}
+
+func TestClient_ReportsProblemDetails(t *testing.T) {
+	srv := newFleetServer(t, "secret")
+	ctx := context.Background()
+
+	_, err := New(srv.URL, WithHTTPClient(srv.Client())).ListDevices(ctx)
+	if StatusCode(err) != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %v", err)
+	}
+
+	_, err = New(srv.URL, WithToken("secret")).AddDevice(ctx, "not-an-id", "")
+	e, ok := err.(*Error)
+	if !ok || e.StatusCode != http.StatusBadRequest || len(e.Fields) != 1 || e.Fields[0].Field != "device_id" {
+		t.Fatalf("unexpected error %#v", err)
+	}
+	if !strings.Contains(e.Error(), "device_id is invalid") {
+		t.Errorf("unexpected message %q", e.Error())
+	}
+}
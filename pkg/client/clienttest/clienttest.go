@@ -0,0 +1,102 @@
+// Package clienttest serves the fleet HTTP API in memory, for testing code
+// that uses pkg/client without starting a server.
+//
+//	fleet := clienttest.New("60-6b-44-84-dc-64")
+//	c := fleet.Client()
+//	_ = c.RecordHeartbeat(ctx, "60-6b-44-84-dc-64", time.Now())
+//
+// Requests go through the real routes and device service, backed by an
+// in-memory repository, so stats are computed as the server computes them.
+package clienttest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	httpadapter "safelyyou/internal/adapters/http"
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/domain"
+	"safelyyou/internal/core/services"
+	"safelyyou/pkg/client"
+)
+
+// BaseURL is the base URL of clients returned by Server.Client.
+const BaseURL = "http://fleet.test"
+
+// Server is an in-memory fleet server. It implements http.RoundTripper.
+type Server struct {
+	engine *gin.Engine
+
+	mu       sync.Mutex
+	requests []string
+	failures []int
+}
+
+// New returns a server that knows the given devices.
+func New(deviceIDs ...string) *Server {
+	gin.SetMode(gin.TestMode)
+	repo := memory.NewDeviceRepository()
+	for _, id := range deviceIDs {
+		_ = repo.Add(context.Background(), domain.NewDeviceStats(id))
+	}
+	r := gin.New()
+	httpadapter.RegisterRoutes(r, services.NewDeviceService(repo))
+	httpadapter.RegisterExportRoutes(r, services.NewExportService(repo))
+	return &Server{engine: r}
+}
+
+// Client returns a client talking to the server. opts are applied after
+// the server's transport, so they must not replace the HTTP client.
+func (s *Server) Client(opts ...client.Option) *client.Client {
+	opts = append([]client.Option{client.WithHTTPClient(&http.Client{Transport: s})}, opts...)
+	return client.New(BaseURL, opts...)
+}
+
+// Fail makes the next n requests fail with status, before reaching the
+// routes, e.g. to exercise retries.
+func (s *Server) Fail(status, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for range n {
+		s.failures = append(s.failures, status)
+	}
+}
+
+// Requests returns the requests received so far as "METHOD /path?query",
+// failed ones included.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// RoundTrip serves req.
+func (s *Server) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, req.Method+" "+req.URL.RequestURI())
+	status := 0
+	if len(s.failures) > 0 {
+		status, s.failures = s.failures[0], s.failures[1:]
+	}
+	s.mu.Unlock()
+
+	w := httptest.NewRecorder()
+	if status != 0 {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(status)
+		_, _ = w.WriteString(`{"type":"about:blank","title":` + strconv.Quote(http.StatusText(status)) + `,"status":` + strconv.Itoa(status) + `}`)
+	} else {
+		s.engine.ServeHTTP(w, req)
+	}
+	resp := w.Result()
+	resp.Request = req
+	return resp, nil
+}
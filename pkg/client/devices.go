@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Device is a device as listed by the server.
+type Device struct {
+	ID             string            `json:"device_id" yaml:"device_id"`
+	Site           string            `json:"site,omitempty" yaml:"site,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	HeartbeatCount int64             `json:"heartbeat_count" yaml:"heartbeat_count"`
+	UploadCount    int64             `json:"upload_count" yaml:"upload_count"`
+	LastSeenAt     *time.Time        `json:"last_seen_at" yaml:"last_seen_at,omitempty"`
+	ClockSkewed    bool              `json:"clock_skewed,omitempty" yaml:"clock_skewed,omitempty"`
+	Version        uint64            `json:"version" yaml:"version"`
+}
+
+// Stats are a device's stats, in the /api/v2 shape: ratios rather than
+// percentages and durations in milliseconds.
+type Stats struct {
+	DeviceID        string            `json:"device_id" yaml:"device_id"`
+	Site            string            `json:"site,omitempty" yaml:"site,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	UptimeRatio     float64           `json:"uptime_ratio" yaml:"uptime_ratio"`
+	Uptime24hRatio  float64           `json:"uptime_24h_ratio" yaml:"uptime_24h_ratio"`
+	AvgUploadMs     float64           `json:"avg_upload_ms" yaml:"avg_upload_ms"`
+	P95UploadMs     float64           `json:"p95_upload_ms" yaml:"p95_upload_ms"`
+	Window          StatsWindow       `json:"window" yaml:"window"`
+	Counts          StatsCounts       `json:"counts" yaml:"counts"`
+	LastHeartbeatAt *time.Time        `json:"last_heartbeat_at" yaml:"last_heartbeat_at,omitempty"`
+	LastSentAt      *time.Time        `json:"last_sent_at" yaml:"last_sent_at,omitempty"`
+	LastSeenAt      *time.Time        `json:"last_seen_at" yaml:"last_seen_at,omitempty"`
+	ClockOffsetMs   float64           `json:"clock_offset_ms" yaml:"clock_offset_ms"`
+	ClockSkewed     bool              `json:"clock_skewed" yaml:"clock_skewed"`
+}
+
+// StatsWindow is the heartbeat span the uptime is computed over.
+type StatsWindow struct {
+	Start   *time.Time `json:"start" yaml:"start,omitempty"`
+	End     *time.Time `json:"end" yaml:"end,omitempty"`
+	Minutes float64    `json:"minutes" yaml:"minutes"`
+}
+
+type StatsCounts struct {
+	Heartbeats int64 `json:"heartbeats" yaml:"heartbeats"`
+	Uploads    int64 `json:"uploads" yaml:"uploads"`
+}
+
+// Outage is a run of minutes without a heartbeat, in device time. End is
+// nil while the outage is ongoing.
+type Outage struct {
+	Start      time.Time  `json:"start" yaml:"start"`
+	End        *time.Time `json:"end" yaml:"end,omitempty"`
+	DurationMs float64    `json:"duration_ms,omitempty" yaml:"duration_ms,omitempty"`
+	Ongoing    bool       `json:"ongoing" yaml:"ongoing"`
+}
+
+// ListDevices returns every known device, ordered by ID.
+func (c *Client) ListDevices(ctx context.Context) ([]Device, error) {
+	var out []Device
+	if err := c.do(ctx, http.MethodGet, "/api/v1/devices", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AddDevice registers a device. site may be empty.
+func (c *Client) AddDevice(ctx context.Context, id, site string) (*Device, error) {
+	body := map[string]string{"device_id": id, "site": site}
+	var out Device
+	if err := c.do(ctx, http.MethodPost, "/api/v1/devices", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveDevice forgets a device and its history.
+func (c *Client) RemoveDevice(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/devices/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// SetLabels replaces a device's labels. A "site" label sets its site.
+func (c *Client) SetLabels(ctx context.Context, id string, labels map[string]string) (*Device, error) {
+	body := map[string]map[string]string{"labels": labels}
+	var out Device
+	if err := c.do(ctx, http.MethodPut, "/api/v1/devices/"+url.PathEscape(id)+"/labels", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStats returns a device's stats.
+func (c *Client) GetStats(ctx context.Context, id string) (*Stats, error) {
+	var out Stats
+	if err := c.do(ctx, http.MethodGet, "/api/v2/devices/"+url.PathEscape(id)+"/stats", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Outages returns a device's outages since the given time, oldest first.
+// The zero time leaves the server default, the last 24 hours.
+func (c *Client) Outages(ctx context.Context, id string, since time.Time) ([]Outage, error) {
+	query := url.Values{}
+	if !since.IsZero() {
+		query.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	var out struct {
+		Outages []Outage `json:"outages"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/devices/"+url.PathEscape(id)+"/outages", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Outages, nil
+}
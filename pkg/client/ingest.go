@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// RecordHeartbeat reports a heartbeat sent by the device at sentAt.
+func (c *Client) RecordHeartbeat(ctx context.Context, id string, sentAt time.Time) error {
+	body := map[string]time.Time{"sent_at": sentAt}
+	return c.do(ctx, http.MethodPost, "/api/v1/devices/"+url.PathEscape(id)+"/heartbeat", nil, body, nil)
+}
+
+// RecordStats reports an upload that took uploadTime. A zero sentAt is
+// accepted, but keeps the upload out of the per-minute series.
+func (c *Client) RecordStats(ctx context.Context, id string, sentAt time.Time, uploadTime time.Duration) error {
+	body := struct {
+		SentAt     *time.Time `json:"sent_at,omitempty"`
+		UploadTime int64      `json:"upload_time"`
+	}{UploadTime: uploadTime.Nanoseconds()}
+	if !sentAt.IsZero() {
+		body.SentAt = &sentAt
+	}
+	return c.do(ctx, http.MethodPost, "/api/v1/devices/"+url.PathEscape(id)+"/stats", nil, body, nil)
+}
+
+// RecordKind tells heartbeats from uploads in a batch.
+type RecordKind string
+
+const (
+	HeartbeatRecord RecordKind = "heartbeat"
+	StatsRecord     RecordKind = "stats"
+)
+
+// Record is one heartbeat or upload of a batch. UploadTime is used by
+// StatsRecord only.
+type Record struct {
+	Kind       RecordKind
+	DeviceID   string
+	SentAt     time.Time
+	UploadTime time.Duration
+}
+
+// RecordBatch sends records with up to the batch concurrency in flight,
+// keeping each device's records in order. It returns one error per
+// record, nil for those the server accepted.
+func (c *Client) RecordBatch(ctx context.Context, records []Record) []error {
+	errs := make([]error, len(records))
+	workers := max(1, min(c.batchConcurrency, len(records)))
+	shards := make([][]int, workers)
+	for i, r := range records {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(r.DeviceID))
+		shard := int(h.Sum32() % uint32(workers))
+		shards[shard] = append(shards[shard], i)
+	}
+
+	var wg sync.WaitGroup
+	for _, shard := range shards {
+		wg.Go(func() {
+			for _, i := range shard {
+				r := records[i]
+				if r.Kind == StatsRecord {
+					errs[i] = c.RecordStats(ctx, r.DeviceID, r.SentAt, r.UploadTime)
+				} else {
+					errs[i] = c.RecordHeartbeat(ctx, r.DeviceID, r.SentAt)
+				}
+			}
+		})
+	}
+	wg.Wait()
+	return errs
+}
+
+// ListStats returns the stats of every known device, ordered by ID.
+// Devices removed while it runs are left out.
+func (c *Client) ListStats(ctx context.Context) ([]Stats, error) {
+	devices, err := c.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]*Stats, len(devices))
+	errs := make([]error, len(devices))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range max(1, min(c.batchConcurrency, len(devices))) {
+		wg.Go(func() {
+			for i := range next {
+				stats[i], errs[i] = c.GetStats(ctx, devices[i].ID)
+			}
+		})
+	}
+	for i := range devices {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	out := make([]Stats, 0, len(devices))
+	for i, s := range stats {
+		if StatusCode(errs[i]) == http.StatusNotFound {
+			continue
+		}
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		out = append(out, *s)
+	}
+	return out, nil
+}
@@ -0,0 +1,134 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	httpadapter "safelyyou/internal/adapters/http"
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/services"
+	"safelyyou/pkg/client"
+	"safelyyou/pkg/client/clienttest"
+)
+
+var devices = []string{"60-6b-44-84-dc-64", "b4-45-52-a2-f1-3c", "26-9a-66-01-33-83"}
+
+func TestRecordBatch_AndListStats(t *testing.T) {
+	fleet := clienttest.New(devices...)
+	c := fleet.Client()
+	ctx := context.Background()
+
+	base := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+	var records []client.Record
+	for _, id := range devices {
+		for m := range 10 {
+			records = append(records, client.Record{Kind: client.HeartbeatRecord, DeviceID: id, SentAt: base.Add(time.Duration(m) * time.Minute)})
+		}
+		records = append(records, client.Record{Kind: client.StatsRecord, DeviceID: id, SentAt: base, UploadTime: 3 * time.Second})
+	}
+	records = append(records, client.Record{Kind: client.HeartbeatRecord, DeviceID: "aa-bb-cc-dd-ee-ff", SentAt: base})
+
+	errs := c.RecordBatch(ctx, records)
+	for i, err := range errs[:len(errs)-1] {
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+	}
+	if client.StatusCode(errs[len(errs)-1]) != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown device, got %v", errs[len(errs)-1])
+	}
+
+	stats, err := c.ListStats(ctx)
+	if err != nil || len(stats) != len(devices) {
+		t.Fatalf("ListStats: %+v, %v", stats, err)
+	}
+	for _, s := range stats {
+		if s.Counts.Heartbeats != 10 || s.Counts.Uploads != 1 || s.AvgUploadMs != 3000 || s.Window.Minutes != 9 {
+			t.Errorf("unexpected stats %+v", s)
+		}
+	}
+	if !slices.IsSortedFunc(stats, func(a, b client.Stats) int { return strings.Compare(a.DeviceID, b.DeviceID) }) {
+		t.Error("expected stats ordered by device ID")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	sentAt := time.Date(2025, 11, 9, 10, 0, 0, 0, time.UTC)
+
+	fleet := clienttest.New(devices[0])
+	c := fleet.Client(client.WithRetry(3, time.Millisecond))
+	fleet.Fail(http.StatusServiceUnavailable, 1)
+	fleet.Fail(http.StatusTooManyRequests, 1)
+	if err := c.RecordHeartbeat(ctx, devices[0], sentAt); err != nil {
+		t.Fatalf("expected the heartbeat to succeed after retries, got %v", err)
+	}
+	if n := len(fleet.Requests()); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+
+	fleet = clienttest.New(devices[0])
+	c = fleet.Client(client.WithRetry(3, time.Millisecond))
+	fleet.Fail(http.StatusInternalServerError, 1)
+	if err := c.RecordHeartbeat(ctx, devices[0], sentAt); client.StatusCode(err) != http.StatusInternalServerError {
+		t.Fatalf("expected a POST to fail on 500 without retry, got %v", err)
+	}
+	fleet.Fail(http.StatusInternalServerError, 1)
+	if _, err := c.GetStats(ctx, devices[0]); err != nil {
+		t.Fatalf("expected a GET to be retried on 500, got %v", err)
+	}
+	if n := len(fleet.Requests()); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+
+	fleet.Fail(http.StatusTooManyRequests, 5)
+	if err := fleet.Client(client.WithRetry(1, time.Millisecond)).RecordHeartbeat(ctx, devices[0], sentAt); client.StatusCode(err) != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once retries are exhausted, got %v", err)
+	}
+}
+
+func TestRetry_StopsWhenContextIsDone(t *testing.T) {
+	fleet := clienttest.New(devices[0])
+	fleet.Fail(http.StatusServiceUnavailable, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := fleet.Client(client.WithRetry(10, time.Second)).GetStats(ctx, devices[0])
+	if client.StatusCode(err) != http.StatusServiceUnavailable || time.Since(start) > time.Second {
+		t.Fatalf("expected the last error soon after the deadline, got %v after %s", err, time.Since(start))
+	}
+}
+
+func TestSigning_AgainstTheServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := memory.NewDeviceRepository()
+	r := gin.New()
+	r.Use(httpadapter.RequestSignature("s3cret"))
+	httpadapter.RegisterRoutes(r, services.NewDeviceService(repo))
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	ctx := context.Background()
+
+	signed := client.New(srv.URL, client.WithSigningSecret("s3cret"), client.WithRetry(0, 0))
+	if _, err := signed.AddDevice(ctx, devices[0], "north"); err != nil {
+		t.Fatalf("signed AddDevice: %v", err)
+	}
+	if err := signed.RecordStats(ctx, devices[0], time.Now(), time.Second); err != nil {
+		t.Fatalf("signed RecordStats: %v", err)
+	}
+	if _, err := signed.Outages(ctx, devices[0], time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("signed Outages with a query: %v", err)
+	}
+	unsigned := client.New(srv.URL, client.WithRetry(0, 0))
+	if _, err := unsigned.ListDevices(ctx); client.StatusCode(err) != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a signature, got %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+// Package logging configures the process-wide slog setup: JSON output,
+// per-component levels, request-scoped attributes carried in the context
+// and sampling for high-volume messages.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config controls the root handler.
+type Config struct {
+	// Level applies to components without an override.
+	Level slog.Level
+	// Components overrides the level per component, e.g. {"mqtt": WARN}.
+	Components map[string]slog.Level
+	// Format is "json" (default) or "text".
+	Format string
+	// Output defaults to os.Stderr.
+	Output io.Writer
+}
+
+var (
+	mu      sync.RWMutex
+	cfg     = Config{Level: slog.LevelInfo}
+	root    slog.Handler
+	loggers = map[string]*slog.Logger{}
+)
+
+func init() {
+	root = newRootHandler(cfg)
+}
+
+// Setup replaces the root handler and component levels, and makes the
+// root logger the slog (and log package) default. Loggers returned by For
+// before Setup keep their old configuration, so call it first in main.
+func Setup(c Config) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cfg = c
+	root = newRootHandler(c)
+	loggers = map[string]*slog.Logger{}
+	slog.SetDefault(slog.New(&levelHandler{min: c.Level, next: root}))
+}
+
+// For returns the logger for a component (e.g. "http", "mqtt"). Records
+// carry a "component" attribute and are filtered at that component's level.
+func For(component string) *slog.Logger {
+	mu.RLock()
+	l, ok := loggers[component]
+	mu.RUnlock()
+	if ok {
+		return l
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if l, ok := loggers[component]; ok {
+		return l
+	}
+	level, ok := cfg.Components[component]
+	if !ok {
+		level = cfg.Level
+	}
+	l = slog.New(&levelHandler{min: level, next: root}).With("component", component)
+	loggers[component] = l
+	return l
+}
+
+// ParseLevels parses a level spec such as "info" or "info,http=debug,mqtt=warn".
+// The bare entry sets the default level; key=value entries set component
+// overrides.
+func ParseLevels(spec string) (slog.Level, map[string]slog.Level, error) {
+	def := slog.LevelInfo
+	components := map[string]slog.Level{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, scoped := strings.Cut(part, "=")
+		if !scoped {
+			value = name
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			return 0, nil, fmt.Errorf("invalid log level %q: %w", part, err)
+		}
+		if scoped {
+			components[strings.TrimSpace(name)] = level
+		} else {
+			def = level
+		}
+	}
+	return def, components, nil
+}
+
+type ctxKey struct{}
+
+// WithAttrs returns a context whose log records carry attrs in addition to
+// any attributes already attached to ctx.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, ctxKey{}, merged)
+}
+
+// Sampler lets through the first and then every Nth event.
+type Sampler struct {
+	every uint64
+	n     atomic.Uint64
+}
+
+// NewSampler keeps one event in every; values below 2 keep everything.
+func NewSampler(every uint64) *Sampler {
+	return &Sampler{every: every}
+}
+
+// Allow reports whether the current event should be logged.
+func (s *Sampler) Allow() bool {
+	if s == nil || s.every < 2 {
+		return true
+	}
+	return (s.n.Add(1)-1)%s.every == 0
+}
+
+func newRootHandler(c Config) slog.Handler {
+	out := c.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	// Filtering happens in levelHandler; the root accepts everything.
+	opts := &slog.HandlerOptions{Level: slog.Level(-8)}
+	var h slog.Handler
+	if c.Format == "text" {
+		h = slog.NewTextHandler(out, opts)
+	} else {
+		h = slog.NewJSONHandler(out, opts)
+	}
+	return contextHandler{h}
+}
+
+// levelHandler gates records below min.
+type levelHandler struct {
+	min  slog.Level
+	next slog.Handler
+}
+
+func (h *levelHandler) Enabled(_ context.Context, l slog.Level) bool { return l >= h.min }
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{min: h.min, next: h.next.WithAttrs(attrs)}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{min: h.min, next: h.next.WithGroup(name)}
+}
+
+// contextHandler adds the attributes stored by WithAttrs and the active
+// trace and span IDs to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(ctxKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid JSON log line %q: %v", line, err)
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+func TestParseLevels(t *testing.T) {
+	def, components, err := ParseLevels("warn, http=debug,mqtt=error")
+	if err != nil {
+		t.Fatalf("ParseLevels returned error: %v", err)
+	}
+	if def != slog.LevelWarn {
+		t.Fatalf("expected default WARN, got %v", def)
+	}
+	if components["http"] != slog.LevelDebug || components["mqtt"] != slog.LevelError {
+		t.Fatalf("unexpected component levels: %v", components)
+	}
+
+	if def, _, err := ParseLevels(""); err != nil || def != slog.LevelInfo {
+		t.Fatalf("expected INFO for empty spec, got %v, %v", def, err)
+	}
+	if _, _, err := ParseLevels("http=loud"); err == nil {
+		t.Fatalf("expected error for unknown level")
+	}
+}
+
+func TestFor_AppliesComponentLevelsAndContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	Setup(Config{
+		Level:      slog.LevelInfo,
+		Components: map[string]slog.Level{"mqtt": slog.LevelWarn, "http": slog.LevelDebug},
+		Output:     &buf,
+	})
+
+	ctx := WithAttrs(context.Background(), slog.String("request_id", "req-1"))
+	ctx = WithAttrs(ctx, slog.String("device_id", "60-6b-44-84-dc-64"))
+
+	For("mqtt").Info("dropped by level")
+	For("mqtt").Warn("kept")
+	For("http").DebugContext(ctx, "debug kept")
+	For("udp").Debug("dropped by default level")
+
+	recs := decodeLines(t, &buf)
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 records, got %d: %s", len(recs), buf.String())
+	}
+	if recs[0]["msg"] != "kept" || recs[0]["component"] != "mqtt" {
+		t.Fatalf("unexpected first record: %v", recs[0])
+	}
+	if recs[1]["request_id"] != "req-1" || recs[1]["device_id"] != "60-6b-44-84-dc-64" {
+		t.Fatalf("expected context attributes on record: %v", recs[1])
+	}
+}
+
+func TestSampler(t *testing.T) {
+	s := NewSampler(3)
+	var kept int
+	for i := 0; i < 9; i++ {
+		if s.Allow() {
+			kept++
+		}
+	}
+	if kept != 3 {
+		t.Fatalf("expected 3 of 9 events kept, got %d", kept)
+	}
+
+	all := NewSampler(0)
+	for i := 0; i < 5; i++ {
+		if !all.Allow() {
+			t.Fatalf("sampler with every=0 should keep all events")
+		}
+	}
+}
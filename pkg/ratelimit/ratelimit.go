@@ -0,0 +1,162 @@
+// Package ratelimit provides keyed token buckets and the "rate:burst"
+// notation used to configure them.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second holding at
+// most Burst tokens. A zero Rate means unlimited.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Unlimited reports whether the limit never throttles.
+func (l Limit) Unlimited() bool { return l.Rate <= 0 }
+
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "0"
+	}
+	return strconv.FormatFloat(l.Rate, 'f', -1, 64) + ":" + strconv.Itoa(l.Burst)
+}
+
+// ParseLimit parses "rate[:burst]", e.g. "5:20" or "0.5". Burst defaults
+// to the rate rounded up (at least 1); "0" disables limiting.
+func ParseLimit(s string) (Limit, error) {
+	rateStr, burstStr, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+	r, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil || r < 0 || math.IsInf(r, 0) || math.IsNaN(r) {
+		return Limit{}, fmt.Errorf("invalid rate %q", rateStr)
+	}
+	if r == 0 {
+		return Limit{}, nil
+	}
+	burst := int(math.Max(1, math.Ceil(r)))
+	if hasBurst {
+		burst, err = strconv.Atoi(burstStr)
+		if err != nil || burst < 1 {
+			return Limit{}, fmt.Errorf("invalid burst %q", burstStr)
+		}
+	}
+	return Limit{Rate: r, Burst: burst}, nil
+}
+
+// Rule limits a route per device and per client IP.
+type Rule struct {
+	Device Limit
+	IP     Limit
+}
+
+// ParseRule parses "device=<limit>,ip=<limit>"; either part may be
+// omitted, leaving that key unlimited.
+func ParseRule(spec string) (Rule, error) {
+	var rule Rule
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Rule{}, fmt.Errorf("invalid rate limit %q: want device=<rate:burst> or ip=<rate:burst>", part)
+		}
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid rate limit %q: %w", part, err)
+		}
+		switch strings.TrimSpace(key) {
+		case "device":
+			rule.Device = limit
+		case "ip":
+			rule.IP = limit
+		default:
+			return Rule{}, fmt.Errorf("invalid rate limit %q: unknown key %q", part, key)
+		}
+	}
+	return rule, nil
+}
+
+// Limiter holds one token bucket per key. Buckets idle for longer than the
+// idle timeout are dropped so the key space cannot grow without bound.
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	idle      time.Duration
+	lastSweep time.Time
+}
+
+type bucket struct {
+	lim      *rate.Limiter
+	limit    Limit
+	lastUsed time.Time
+}
+
+// NewLimiter creates a limiter that forgets buckets idle for idle.
+func NewLimiter(idle time.Duration) *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket), idle: idle}
+}
+
+// Allow takes one token from key's bucket, creating it with limit if
+// needed (or if its limit changed). When the bucket is empty it returns
+// false and how long until a token is available.
+func (l *Limiter) Allow(key string, limit Limit, now time.Time) (bool, time.Duration) {
+	_, allowed, wait := l.Reserve(key, limit, now)
+	return allowed, wait
+}
+
+// Reserve is Allow for callers checking several buckets: cancel gives the
+// token back, so that a request refused by a later bucket does not spend
+// this one. cancel is never nil.
+func (l *Limiter) Reserve(key string, limit Limit, now time.Time) (cancel func(), allowed bool, wait time.Duration) {
+	if limit.Unlimited() {
+		return func() {}, true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{lim: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst), limit: limit}
+		l.buckets[key] = b
+	}
+	b.lastUsed = now
+
+	r := b.lim.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return func() {}, false, delay
+	}
+	return func() { r.CancelAt(now) }, true, 0
+}
+
+// Len returns the number of live buckets.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// sweep must be called with l.mu held.
+func (l *Limiter) sweep(now time.Time) {
+	if l.idle <= 0 || now.Sub(l.lastSweep) < l.idle {
+		return
+	}
+	l.lastSweep = now
+	for k, b := range l.buckets {
+		if now.Sub(b.lastUsed) > l.idle {
+			delete(l.buckets, k)
+		}
+	}
+}
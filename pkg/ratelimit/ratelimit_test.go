@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Limit
+	}{
+		{"5:20", Limit{Rate: 5, Burst: 20}},
+		{"0.5", Limit{Rate: 0.5, Burst: 1}},
+		{"3", Limit{Rate: 3, Burst: 3}},
+		{"0", Limit{}},
+	}
+	for _, tc := range cases {
+		got, err := ParseLimit(tc.in)
+		if err != nil {
+			t.Fatalf("ParseLimit(%q) returned error: %v", tc.in, err)
+		}
+		if got != tc.want {
+			t.Fatalf("ParseLimit(%q) = %+v, want %+v", tc.in, got, tc.want)
+		}
+	}
+	for _, bad := range []string{"", "fast", "-1", "5:0", "5:x"} {
+		if _, err := ParseLimit(bad); err == nil {
+			t.Fatalf("ParseLimit(%q) should fail", bad)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("device=2:4, ip=100:200")
+	if err != nil {
+		t.Fatalf("ParseRule returned error: %v", err)
+	}
+	if rule.Device != (Limit{Rate: 2, Burst: 4}) || rule.IP != (Limit{Rate: 100, Burst: 200}) {
+		t.Fatalf("unexpected rule: %+v", rule)
+	}
+	if rule, err := ParseRule(""); err != nil || !rule.Device.Unlimited() || !rule.IP.Unlimited() {
+		t.Fatalf("empty rule should be unlimited, got %+v, %v", rule, err)
+	}
+	for _, bad := range []string{"device", "user=1:1", "ip=fast"} {
+		if _, err := ParseRule(bad); err == nil {
+			t.Fatalf("ParseRule(%q) should fail", bad)
+		}
+	}
+}
+
+func TestLimiter_AllowsBurstThenThrottles(t *testing.T) {
+	l := NewLimiter(time.Minute)
+	limit := Limit{Rate: 1, Burst: 2}
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("dev", limit, now); !ok {
+			t.Fatalf("request %d within burst was throttled", i)
+		}
+	}
+	ok, retry := l.Allow("dev", limit, now)
+	if ok {
+		t.Fatalf("request beyond burst should be throttled")
+	}
+	if retry <= 0 || retry > time.Second {
+		t.Fatalf("expected retry within 1s, got %v", retry)
+	}
+
+	if ok, _ := l.Allow("other", limit, now); !ok {
+		t.Fatalf("keys must not share buckets")
+	}
+	if ok, _ := l.Allow("dev", limit, now.Add(time.Second)); !ok {
+		t.Fatalf("bucket should refill after a second")
+	}
+}
+
+func TestLimiter_ReserveCancelGivesTheTokenBack(t *testing.T) {
+	l := NewLimiter(time.Minute)
+	limit := Limit{Rate: 1, Burst: 1}
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	cancel, ok, _ := l.Reserve("ip", limit, now)
+	if !ok {
+		t.Fatalf("first reservation was throttled")
+	}
+	cancel()
+	if ok, _ := l.Allow("ip", limit, now); !ok {
+		t.Fatalf("expected the cancelled token to be available again")
+	}
+	if cancel, ok, _ := l.Reserve("ip", limit, now); ok {
+		t.Fatalf("expected the bucket to be empty")
+	} else {
+		cancel() // no-op when refused
+	}
+	if ok, _ := l.Allow("ip", limit, now); ok {
+		t.Fatalf("cancelling a refused reservation must not add a token")
+	}
+}
+
+func TestLimiter_DropsIdleBuckets(t *testing.T) {
+	l := NewLimiter(time.Minute)
+	limit := Limit{Rate: 1, Burst: 1}
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	l.Allow("a", limit, now)
+	l.Allow("b", limit, now)
+	if l.Len() != 2 {
+		t.Fatalf("expected 2 buckets, got %d", l.Len())
+	}
+	l.Allow("c", limit, now.Add(2*time.Minute))
+	if l.Len() != 1 {
+		t.Fatalf("expected idle buckets to be dropped, got %d", l.Len())
+	}
+}
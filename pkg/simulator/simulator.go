@@ -0,0 +1,352 @@
+// Package simulator generates device traffic, sends it to a fleet server
+// over HTTP and checks the stats the server reports against values it
+// computes on its own from what it sent.
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config shapes the generated traffic.
+type Config struct {
+	// Start is the sent_at of the first minute; heartbeats are sent once a
+	// minute for Minutes minutes, except during outages.
+	Start   time.Time
+	Minutes int
+	// OutageChance is the probability that an outage of 1 to MaxOutage
+	// minutes starts at any given minute.
+	OutageChance float64
+	MaxOutage    int
+	// Uploads is the number of upload stats sent per device, at random
+	// times within the window. Their durations center on MeanUpload.
+	Uploads    int
+	MeanUpload time.Duration
+	Seed       uint64
+}
+
+// DefaultConfig mirrors the reference device simulator: eight hours of
+// heartbeats and 100 uploads per device.
+func DefaultConfig() Config {
+	return Config{
+		Start:        time.Now().UTC().Truncate(time.Minute).Add(-8 * time.Hour),
+		Minutes:      480,
+		OutageChance: 0.005,
+		MaxOutage:    15,
+		Uploads:      100,
+		MeanUpload:   3 * time.Minute,
+		Seed:         1,
+	}
+}
+
+// Upload is one upload stats report.
+type Upload struct {
+	SentAt   time.Time
+	Duration time.Duration
+}
+
+// DevicePlan is the traffic generated for one device.
+type DevicePlan struct {
+	ID         string
+	Heartbeats []time.Time
+	Uploads    []Upload
+}
+
+// NewPlan generates traffic for each device. The same Config and devices
+// always yield the same plan.
+func NewPlan(cfg Config, devices []string) []DevicePlan {
+	plans := make([]DevicePlan, 0, len(devices))
+	for i, id := range devices {
+		rng := rand.New(rand.NewPCG(cfg.Seed, uint64(i)))
+		p := DevicePlan{ID: id}
+		for m := 0; m < cfg.Minutes; m++ {
+			if cfg.MaxOutage > 0 && rng.Float64() < cfg.OutageChance {
+				m += rng.IntN(cfg.MaxOutage)
+				continue
+			}
+			p.Heartbeats = append(p.Heartbeats, cfg.Start.Add(time.Duration(m)*time.Minute))
+		}
+		window := time.Duration(max(cfg.Minutes, 1)) * time.Minute
+		for range cfg.Uploads {
+			// Exponentially distributed around the mean, with a floor: most
+			// uploads are quick, a few are very slow.
+			d := time.Second + time.Duration(rng.ExpFloat64()*float64(cfg.MeanUpload-time.Second))
+			p.Uploads = append(p.Uploads, Upload{
+				SentAt:   cfg.Start.Add(time.Duration(rng.Int64N(int64(window)))).Truncate(time.Millisecond),
+				Duration: d,
+			})
+		}
+		slices.SortFunc(p.Uploads, func(a, b Upload) int { return a.SentAt.Compare(b.SentAt) })
+		plans = append(plans, p)
+	}
+	return plans
+}
+
+// ExpectedUptime is the uptime the server should report: heartbeats per
+// minute between the first and last heartbeat, as a percentage.
+func (p DevicePlan) ExpectedUptime() float64 {
+	if len(p.Heartbeats) == 0 {
+		return 0
+	}
+	first, last := slices.MinFunc(p.Heartbeats, time.Time.Compare), slices.MaxFunc(p.Heartbeats, time.Time.Compare)
+	minutes := last.Sub(first).Minutes()
+	if minutes <= 0 {
+		minutes = 1
+	}
+	return float64(len(p.Heartbeats)) / minutes * 100
+}
+
+// ExpectedAvgUpload is the mean upload duration, truncated to the
+// nanosecond.
+func (p DevicePlan) ExpectedAvgUpload() time.Duration {
+	if len(p.Uploads) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, u := range p.Uploads {
+		sum += u.Duration
+	}
+	return sum / time.Duration(len(p.Uploads))
+}
+
+// Client sends the plans to a server.
+type Client struct {
+	// BaseURL is the server address, e.g. http://localhost:8080.
+	BaseURL string
+	// Token, when set, is sent as a bearer token.
+	Token string
+	// HTTP defaults to http.DefaultClient.
+	HTTP *http.Client
+	// Concurrency is the number of devices simulated at once (default 8).
+	Concurrency int
+	// MaxRetries bounds the retries of a throttled (429) request.
+	MaxRetries int
+}
+
+// Run sends every plan's heartbeats and uploads in sent_at order, then
+// fetches each device's stats and compares them with the expected values.
+// A device whose traffic could not be delivered fails with Err set.
+func (c *Client) Run(ctx context.Context, plans []DevicePlan) *Report {
+	report := &Report{Results: make([]Result, len(plans))}
+	n := c.Concurrency
+	if n <= 0 {
+		n = 8
+	}
+	sem := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for i, p := range plans {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			report.Results[i] = c.simulate(ctx, p)
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+func (c *Client) simulate(ctx context.Context, p DevicePlan) Result {
+	res := Result{
+		DeviceID:          p.ID,
+		Heartbeats:        len(p.Heartbeats),
+		Uploads:           len(p.Uploads),
+		ExpectedUptime:    p.ExpectedUptime(),
+		ExpectedAvgUpload: p.ExpectedAvgUpload(),
+	}
+	base := strings.TrimRight(c.BaseURL, "/") + "/api/v1/devices/" + p.ID
+
+	h, u := 0, 0
+	for h < len(p.Heartbeats) || u < len(p.Uploads) {
+		var err error
+		if u == len(p.Uploads) || (h < len(p.Heartbeats) && !p.Heartbeats[h].After(p.Uploads[u].SentAt)) {
+			err = c.post(ctx, base+"/heartbeat", map[string]any{"sent_at": p.Heartbeats[h]})
+			h++
+		} else {
+			err = c.post(ctx, base+"/stats", map[string]any{"sent_at": p.Uploads[u].SentAt, "upload_time": p.Uploads[u].Duration.Nanoseconds()})
+			u++
+		}
+		if err != nil {
+			res.Err = err
+			return res
+		}
+	}
+
+	var stats struct {
+		Uptime        float64 `json:"uptime"`
+		AvgUploadTime string  `json:"avg_upload_time"`
+	}
+	if err := c.get(ctx, base+"/stats", &stats); err != nil {
+		res.Err = err
+		return res
+	}
+	res.ActualUptime = stats.Uptime
+	avg, err := time.ParseDuration(stats.AvgUploadTime)
+	if err != nil {
+		res.Err = fmt.Errorf("invalid avg_upload_time %q", stats.AvgUploadTime)
+		return res
+	}
+	res.ActualAvgUpload = avg
+	return res
+}
+
+func (c *Client) post(ctx context.Context, url string, body any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := c.do(ctx, http.MethodPost, url, payload)
+		if err != nil {
+			return err
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		switch {
+		case resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests && attempt < c.MaxRetries:
+			if err := sleep(ctx, retryAfter(resp)); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("POST %s: %s", url, resp.Status)
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context, url string, out any) error {
+	resp, err := c.do(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Client) do(ctx context.Context, method, url string, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// retryAfter reads the Retry-After seconds of a 429, defaulting to 1s.
+func retryAfter(resp *http.Response) time.Duration {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return time.Second
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// uptimeTolerance absorbs float formatting differences; uptime is printed
+// with five decimals.
+const uptimeTolerance = 1e-5
+
+// Result compares one device's expected and reported stats.
+type Result struct {
+	DeviceID            string
+	Heartbeats, Uploads int
+
+	ExpectedUptime    float64
+	ActualUptime      float64
+	ExpectedAvgUpload time.Duration
+	ActualAvgUpload   time.Duration
+	// Err is set when the device's traffic or stats request failed.
+	Err error
+}
+
+// Pass reports whether the server's stats match the expected ones.
+func (r Result) Pass() bool {
+	return r.Err == nil &&
+		math.Abs(r.ExpectedUptime-r.ActualUptime) < uptimeTolerance &&
+		r.ExpectedAvgUpload == r.ActualAvgUpload
+}
+
+// Report is the outcome of a simulation, one Result per device.
+type Report struct {
+	Results []Result
+}
+
+// Passed reports whether every device passed.
+func (r *Report) Passed() bool {
+	for _, res := range r.Results {
+		if !res.Pass() {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteText writes the report in the layout of the reference simulator's
+// results.
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("############ RESULTS #################\n")
+	passed := 0
+	for _, res := range r.Results {
+		fmt.Fprintf(&b, "\nDeviceID: %s (%d heartbeats, %d uploads)\n", res.DeviceID, res.Heartbeats, res.Uploads)
+		if res.Err != nil {
+			fmt.Fprintf(&b, "\tError: %v\n\tFAIL\n", res.Err)
+			continue
+		}
+		fmt.Fprintf(&b, "\tUptime\n\t\tExpected: %.5f\n\t\tActual: %.5f\n\n", res.ExpectedUptime, res.ActualUptime)
+		fmt.Fprintf(&b, "\tAvgUploadTime\n\t\tExpected: %s\n\t\tActual: %s\n", res.ExpectedAvgUpload, res.ActualAvgUpload)
+		if res.Pass() {
+			passed++
+			b.WriteString("\tPASS\n")
+		} else {
+			b.WriteString("\tFAIL\n")
+		}
+	}
+	verdict := "PASS"
+	if passed != len(r.Results) {
+		verdict = "FAIL"
+	}
+	fmt.Fprintf(&b, "\n%s: %d/%d devices passed\n", verdict, passed, len(r.Results))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,103 @@
+package simulator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	httpadapter "safelyyou/internal/adapters/http"
+	"safelyyou/internal/adapters/repository/memory"
+	"safelyyou/internal/core/domain"
+	"safelyyou/internal/core/services"
+)
+
+var testDevices = []string{"60-6b-44-84-dc-64", "b4-45-52-a2-f1-3c", "26-9a-66-01-33-83"}
+
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Start = time.Date(2025, 11, 9, 0, 0, 0, 0, time.UTC)
+	cfg.Minutes = 120
+	cfg.OutageChance = 0.05
+	cfg.Uploads = 20
+	return cfg
+}
+
+func newFleetServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := memory.NewDeviceRepository()
+	for _, id := range testDevices {
+		if err := repo.Add(context.Background(), domain.NewDeviceStats(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := gin.New()
+	httpadapter.RegisterRoutes(r, services.NewDeviceService(repo))
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNewPlan_IsDeterministicWithOutages(t *testing.T) {
+	a, b := NewPlan(testConfig(), testDevices), NewPlan(testConfig(), testDevices)
+	for i := range a {
+		if len(a[i].Heartbeats) != len(b[i].Heartbeats) || a[i].ExpectedAvgUpload() != b[i].ExpectedAvgUpload() {
+			t.Fatalf("plans differ for %s", a[i].ID)
+		}
+		if len(a[i].Heartbeats) >= 120 || len(a[i].Uploads) != 20 {
+			t.Errorf("%s: expected outages and 20 uploads, got %d heartbeats and %d uploads",
+				a[i].ID, len(a[i].Heartbeats), len(a[i].Uploads))
+		}
+	}
+}
+
+func TestRun_PassesAgainstTheServer(t *testing.T) {
+	srv := newFleetServer(t)
+
+	report := (&Client{BaseURL: srv.URL}).Run(context.Background(), NewPlan(testConfig(), testDevices))
+
+	var out strings.Builder
+	if err := report.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Passed() {
+		t.Fatalf("expected every device to pass:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "PASS: 3/3 devices passed") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
+
+func TestRun_ReportsMismatchesAndRetriesThrottling(t *testing.T) {
+	var throttled atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && throttled.CompareAndSwap(false, true):
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			_, _ = w.Write([]byte(`{"uptime":100,"avg_upload_time":"3m0s"}`))
+		}
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.Minutes, cfg.Uploads = 5, 2
+	report := (&Client{BaseURL: srv.URL, MaxRetries: 1}).Run(context.Background(), NewPlan(cfg, testDevices[:1]))
+
+	res := report.Results[0]
+	if res.Err != nil {
+		t.Fatalf("expected the throttled request to be retried, got %v", res.Err)
+	}
+	if report.Passed() || res.ActualAvgUpload != 3*time.Minute {
+		t.Errorf("expected a mismatch to fail, got %+v", res)
+	}
+}